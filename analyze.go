@@ -0,0 +1,149 @@
+package kibctl
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+// maxPayloadBytes is the default server.maxPayload of kibana, which the json
+// body of the dashboard import must fit in.
+const maxPayloadBytes = 1 << 20
+
+// embeddedImage matches the base64 data urls of the images embedded in
+// markdown panels or image embeddables. Go caps the repeat counts at 1000, so
+// the small icons are left out by embeddedImages instead.
+var embeddedImage = regexp.MustCompile(`data:image/[a-z0-9.+-]+;base64,([A-Za-z0-9+/=\\]+)`)
+
+// minImageData is the size of the base64 data under which an embedded image
+// is an icon not worth externalizing.
+const minImageData = 1024
+
+// embeddedImages returns the data urls of the images embedded in the text,
+// ignoring the small icons.
+func embeddedImages(text string) []string {
+	var images []string
+	for _, match := range embeddedImage.FindAllStringSubmatch(text, -1) {
+		if len(match[1]) >= minImageData {
+			images = append(images, match[0])
+		}
+	}
+	return images
+}
+
+func hasEmbeddedImage(text string) bool {
+	return len(embeddedImages(text)) > 0
+}
+
+type panelSize struct {
+	Dashboard string
+	Panel     string
+	Label     string
+	Size      int
+}
+
+// panelSizes returns the size of every dashboard panel, that is its entry in
+// the panelsJSON along with the object it displays when part of the export.
+func panelSizes(export []byte) []panelSize {
+	objects := exportObjects(export)
+	var sizes []panelSize
+	for _, dashboard := range gjson.GetBytes(export, `objects.#(type=="dashboard")#`).Array() {
+		refs := make(map[string]gjson.Result)
+		for _, ref := range dashboard.Get("references").Array() {
+			refs[ref.Get("name").String()] = ref
+		}
+		labels := panelLabels(dashboard, objects)
+		for i, panel := range gjson.Parse(dashboard.Get("attributes.panelsJSON").String()).Array() {
+			index := panel.Get("panelIndex").String()
+			if index == "" {
+				index = fmt.Sprint(i)
+			}
+			size := len(panel.Raw)
+			objectType, id := panel.Get("type").String(), panel.Get("id").String()
+			if ref, ok := refs[panel.Get("panelRefName").String()]; ok {
+				objectType, id = ref.Get("type").String(), ref.Get("id").String()
+			}
+			if object, ok := objects[objectType+":"+id]; ok {
+				size += len(object.Raw)
+			}
+			sizes = append(sizes, panelSize{Dashboard: dashboard.Get("attributes.title").String(), Panel: index, Label: labels[index], Size: size})
+		}
+	}
+	sort.SliceStable(sizes, func(i, j int) bool { return sizes[i].Size > sizes[j].Size })
+	return sizes
+}
+
+// sizeFindings reports the export larger than the import payload limit, the
+// embedded images and the vega specs larger than the limit, suggesting how to
+// externalize them.
+func sizeFindings(export []byte, maxSize, vegaLimit int) []lintFinding {
+	var findings []lintFinding
+	objects := gjson.GetBytes(export, "objects").Array()
+	if len(export) > maxSize && len(objects) > 0 {
+		findings = append(findings, newFinding(objects[0], severityError, "size",
+			"export of %.1f KB exceeds the %.1f KB import limit, split it with export-all on fewer dashboards or raise server.maxPayload",
+			float64(len(export))/(1<<10), float64(maxSize)/(1<<10)))
+	}
+	for _, object := range objects {
+		attributes := object.Get("attributes")
+		for _, path := range grepAttributes(attributes, hasEmbeddedImage) {
+			size := 0
+			for _, image := range embeddedImages(attributes.Get(path).String()) {
+				size += len(image)
+			}
+			findings = append(findings, newFinding(object, severityWarning, "embedded-image",
+				"%v embeds %.1f KB of base64 images, serve them from a url instead", path, float64(size)/(1<<10)))
+		}
+		visState := attributes.Get("visState").String()
+		if isVega(visState) {
+			if spec := gjson.Get(visState, vegaSpecPath).String(); len(spec) > vegaLimit {
+				findings = append(findings, newFinding(object, severityWarning, "vega-size",
+					"vega spec of %.1f KB, keep it in its own file with export --split --extract-vega and move inline data to a url", float64(len(spec))/(1<<10)))
+			}
+		}
+	}
+	return findings
+}
+
+func analyze(c *cli.Context) error {
+	arg := c.Args().First()
+	if arg == "" {
+		return cli.NewExitError("export file, directory or dashboard name missing", 1)
+	}
+	var export []byte
+	var err error
+	if _, statErr := os.Stat(arg); statErr == nil {
+		export, err = readBundle(arg)
+	} else {
+		if err := checkGlobals(c); err != nil {
+			return err
+		}
+		export, err = newClient().export(arg)
+	}
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+
+	findings := sizeFindings(export, c.Int("max-size-kb")<<10, c.Int("vega-limit-kb")<<10)
+	if !quiet {
+		top := c.Int("top")
+		sizes := panelSizes(export)
+		fmt.Fprintf(os.Stdout, "%d objects, %.1f KB\n", len(gjson.GetBytes(export, "objects").Array()), float64(len(export))/(1<<10))
+		os.Stdout.WriteString("\n" + stdout.header(fmt.Sprintf("%-12v %-8v %-40v %v", "SIZE", "SHARE", "DASHBOARD", "PANEL")) + "\n")
+		for _, s := range sizes[:minInt(top, len(sizes))] {
+			fmt.Fprintf(os.Stdout, "%-12v %-8v %-40v %v\n", fmt.Sprintf("%.1f KB", float64(s.Size)/(1<<10)),
+				fmt.Sprintf("%.1f%%", 100*float64(s.Size)/float64(len(export))), s.Dashboard, s.Label)
+		}
+		if len(findings) > 0 {
+			os.Stdout.WriteString("\n")
+		}
+	}
+	if errorCount := printFindings(findings); errorCount > 0 {
+		return cli.NewExitError(fmt.Sprintf("%d error(s) found", errorCount), 3)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package kibctl
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedImageCompiles(t *testing.T) {
+	if _, err := regexp.Compile(embeddedImage.String()); err != nil {
+		t.Fatalf("embeddedImage does not compile: %v", err)
+	}
+}
+
+func TestEmbeddedImages(t *testing.T) {
+	large := strings.Repeat("QUJD", minImageData/4)
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"none", "# title\nsome text", 0},
+		{"icon", "![icon](data:image/png;base64,QUJDRA==)", 0},
+		{"image", "![chart](data:image/png;base64," + large + ")", 1},
+		{"image and icon", "data:image/svg+xml;base64," + large + " data:image/gif;base64,R0lG", 1},
+		{"two images", "data:image/png;base64," + large + ") (data:image/jpeg;base64," + large, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := len(embeddedImages(test.text)); got != test.want {
+				t.Errorf("embeddedImages() found %d images, want %d", got, test.want)
+			}
+			if got := hasEmbeddedImage(test.text); got != (test.want > 0) {
+				t.Errorf("hasEmbeddedImage() = %v, want %v", got, test.want > 0)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package kibctl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// taggableTypes are the object types kibana lets carry tags.
+var taggableTypes = []string{"dashboard", "visualization", "lens", "search", "map"}
+
+// annotationsAttribute is the attribute the annotations are stored in when
+// not stored as tags.
+const annotationsAttribute = "kibctlAnnotations"
+
+type annotation struct {
+	Key   string
+	Value string
+}
+
+// tag names the tag standing for the annotation.
+func (a annotation) tag() string {
+	return a.Key + ":" + a.Value
+}
+
+// parseAnnotations parses comma separated key=value pairs.
+func parseAnnotations(spec string) ([]annotation, error) {
+	var annotations []annotation
+	for _, pair := range strings.Split(spec, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errors.Errorf("invalid annotation %v, key=value expected.\n", pair)
+		}
+		annotations = append(annotations, annotation{Key: parts[0], Value: parts[1]})
+	}
+	return annotations, nil
+}
+
+// ensureTags returns the ids of the tags by name, creating the missing tags.
+func (c *client) ensureTags(names []string) (map[string]string, error) {
+	tags, err := c.find([]string{"tag"}, nil)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]string)
+	for _, tag := range tags {
+		ids[gjson.GetBytes(tag.Attributes, "name").String()] = tag.ID
+	}
+	for _, name := range names {
+		if _, ok := ids[name]; ok {
+			continue
+		}
+		c.Logger.Printf("creating tag %v\n", name)
+		body, err := c.jsonRequest("POST", "/api/saved_objects/tag", map[string]interface{}{
+			"attributes": map[string]string{"name": name, "description": "created by kibctl", "color": "#6092C0"},
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not create tag %v", name)
+		}
+		ids[name] = gjson.GetBytes(body, "id").String()
+	}
+	return ids, nil
+}
+
+// annotateTags tags every taggable object of the payload with the tags of the
+// annotations.
+func (c *client) annotateTags(payload []byte, annotations []annotation) ([]byte, error) {
+	names := make([]string, len(annotations))
+	for i, a := range annotations {
+		names[i] = a.tag()
+	}
+	ids, err := c.ensureTags(names)
+	if err != nil {
+		return nil, err
+	}
+	for i, object := range gjson.GetBytes(payload, "objects").Array() {
+		if !contains(taggableTypes, object.Get("type").String()) {
+			continue
+		}
+		var tagged []string
+		for _, ref := range object.Get(`references.#(type=="tag")#`).Array() {
+			tagged = append(tagged, ref.Get("id").String())
+		}
+		for _, name := range names {
+			id := ids[name]
+			if contains(tagged, id) {
+				continue
+			}
+			payload, err = sjson.SetBytes(payload, fmt.Sprintf("objects.%d.references.-1", i), reference{Type: "tag", ID: id, Name: "tag-ref-" + id})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return payload, nil
+}
+
+// annotateAttribute stores the annotations in a dedicated attribute of every
+// object of the payload.
+func annotateAttribute(payload []byte, annotations []annotation) ([]byte, error) {
+	values := make(map[string]string)
+	for _, a := range annotations {
+		values[a.Key] = a.Value
+	}
+	var err error
+	for i := range gjson.GetBytes(payload, "objects").Array() {
+		payload, err = sjson.SetBytes(payload, fmt.Sprintf("objects.%d.attributes.%v", i, annotationsAttribute), values)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return payload, nil
+}
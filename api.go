@@ -0,0 +1,105 @@
+package kibctl
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// ExportOptions configures Export, the go counterpart of dashboard export for
+// the tools embedding kibctl instead of running it.
+type ExportOptions struct {
+	// Host is the kibana api endpoint
+	Host string
+	// Username and Password are the basic auth credentials, unused with APIKey
+	Username string
+	Password string
+	// APIKey authenticates the requests instead of the basic auth credentials
+	APIKey string
+	// Flavor is the targeted dashboards product: kibana, the default, or opensearch
+	Flavor string
+	// Compat is the targeted Kibana major version: 6, 7, the default, or auto
+	Compat string
+	// Tenant selects the security tenant on multi-tenant setups
+	Tenant string
+	// Space is the kibana space of the dashboard, the default space when empty
+	Space string
+
+	// Dashboard is the name of the exported dashboard
+	Dashboard string
+	// SkipMissingDeps records the index-patterns missing from kibana in the
+	// missingReferences of the export instead of failing
+	SkipMissingDeps bool
+	// Normalize rewrites the panels of the exported dashboards as dashboard
+	// export --normalize does: sorted by grid position, their coordinates
+	// rounded to integers, so that identical dashboards export identically
+	Normalize bool
+
+	// Stdout receives the export, os.Stdout when nil
+	Stdout io.Writer
+	// Stderr receives the warnings, and the request details with Verbose,
+	// os.Stderr when nil
+	Stderr  io.Writer
+	Verbose bool
+}
+
+// Export writes the export of the dashboard, along with the objects it
+// depends on, to the Stdout of the options. The requests are canceled with
+// the context.
+func Export(ctx context.Context, opts ExportOptions) error {
+	if opts.Host == "" {
+		return errors.New("kibana host not defined.\n")
+	}
+	if opts.APIKey == "" && (opts.Username == "" || opts.Password == "") {
+		return errors.New("kibana credentials not defined, username and password or api key expected.\n")
+	}
+	if opts.Dashboard == "" {
+		return errors.New("dashboard name missing.\n")
+	}
+	if opts.Flavor == "" {
+		opts.Flavor = "kibana"
+	}
+	if opts.Compat == "" {
+		opts.Compat = "7"
+	}
+	if opts.Stdout == nil {
+		opts.Stdout = os.Stdout
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = os.Stderr
+	}
+
+	c := &client{
+		Host:            opts.Host,
+		Username:        opts.Username,
+		Password:        opts.Password,
+		APIKey:          opts.APIKey,
+		Auth:            "basic",
+		Compat:          opts.Compat,
+		Flavor:          opts.Flavor,
+		Tenant:          opts.Tenant,
+		Space:           opts.Space,
+		DepMatch:        "title-fuzzy",
+		SkipMissingDeps: opts.SkipMissingDeps,
+		Stderr:          opts.Stderr,
+		Logger: &cmdLogger{
+			Logger:    log.New(opts.Stderr, "", log.LstdFlags),
+			IsVerbose: opts.Verbose,
+		},
+		ctx: ctx,
+	}
+	export, err := c.export(opts.Dashboard)
+	if err != nil {
+		return err
+	}
+	if opts.Normalize {
+		if export, err = normalizeExport(export); err != nil {
+			return err
+		}
+	}
+	_, err = opts.Stdout.Write(export)
+	return err
+}
@@ -0,0 +1,101 @@
+package kibctl
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestExport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, _ := r.BasicAuth(); user != "elastic" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/s/ops/api/saved_objects/_find":
+			w.Write([]byte(`{"total":1,"saved_objects":[{"id":"d1","type":"dashboard","attributes":{"title":"Nginx"}}]}`))
+		case "/s/ops/api/kibana/dashboards/export":
+			if r.URL.Query().Get("dashboard") != "d1" {
+				t.Errorf("exported dashboard %v, want d1", r.URL.Query().Get("dashboard"))
+			}
+			w.Write([]byte(`{"version":"7.17.0","objects":[{"id":"d1","type":"dashboard","attributes":{"title":"Nginx","panelsJSON":"[]"},"references":[]}]}`))
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	var stdout, stderr bytes.Buffer
+	err := Export(context.Background(), ExportOptions{
+		Host:      server.URL,
+		Username:  "elastic",
+		Password:  "secret",
+		Space:     "ops",
+		Dashboard: "Nginx",
+		Stdout:    &stdout,
+		Stderr:    &stderr,
+	})
+	if err != nil {
+		t.Fatalf("Export() error: %v", err)
+	}
+	if id := gjson.GetBytes(stdout.Bytes(), "objects.0.id").String(); id != "d1" {
+		t.Errorf("Export() wrote %s, want the export of dashboard d1", stdout.String())
+	}
+	if stderr.Len() > 0 {
+		t.Errorf("Export() logged %q without Verbose", stderr.String())
+	}
+}
+
+func TestExportCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request %v sent with a canceled context", r.URL)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := Export(ctx, ExportOptions{Host: server.URL, APIKey: "key", Dashboard: "Nginx", Stdout: &bytes.Buffer{}})
+	if err == nil || !strings.Contains(err.Error(), "canceled") {
+		t.Errorf("Export() error = %v, want a canceled request", err)
+	}
+}
+
+func TestExportOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ExportOptions
+	}{
+		{"host missing", ExportOptions{APIKey: "key", Dashboard: "Nginx"}},
+		{"credentials missing", ExportOptions{Host: "http://kibana:5601", Username: "elastic", Dashboard: "Nginx"}},
+		{"dashboard missing", ExportOptions{Host: "http://kibana:5601", APIKey: "key"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := Export(context.Background(), test.opts); err == nil {
+				t.Error("Export() accepted incomplete options")
+			}
+		})
+	}
+}
+
+func TestSkipMissingWarnsToStderr(t *testing.T) {
+	var stderr bytes.Buffer
+	c := &client{SkipMissingDeps: true, Stderr: &stderr}
+	export, err := c.skipMissing([]byte(`{"objects":[]}`), missingError{dependency{Type: "index-pattern", Title: "logs-*"}})
+	if err != nil {
+		t.Fatalf("skipMissing() error: %v", err)
+	}
+	if title := gjson.GetBytes(export, "missingReferences.0.title").String(); title != "logs-*" {
+		t.Errorf("skipMissing() = %s, want a logs-* placeholder", export)
+	}
+	if !strings.HasPrefix(stderr.String(), "warning: ") {
+		t.Errorf("skipMissing() warned %q, want the warning on the client Stderr", stderr.String())
+	}
+}
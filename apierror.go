@@ -0,0 +1,65 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// apiError is a failed kibana api call, parsed from the json error envelope
+// kibana responds with.
+type apiError struct {
+	// Action describes what failed, e.g. "import dashboard"
+	Action     string
+	StatusCode int             `json:"statusCode"`
+	Err        string          `json:"error"`
+	Message    string          `json:"message"`
+	Attributes json.RawMessage `json:"attributes"`
+	// Guidance hints at the usual cause of the error
+	Guidance string `json:"-"`
+}
+
+func (e *apiError) Error() string {
+	message := fmt.Sprintf("failed to %v. Status:%d %v. %v.\n", e.Action, e.StatusCode, e.Err, strings.TrimSuffix(e.Message, "."))
+	if len(e.Attributes) > 0 && string(e.Attributes) != "null" {
+		message += fmt.Sprintf("Details:%v.\n", string(e.Attributes))
+	}
+	if e.Guidance != "" {
+		message += e.Guidance + ".\n"
+	}
+	return message
+}
+
+// apiError parses the error response of the action, keeping the raw body as
+// message when it is not a kibana error envelope.
+func (c *client) apiError(action string, resp *http.Response, body []byte) error {
+	e := &apiError{}
+	if err := json.Unmarshal(body, e); err != nil || e.Message == "" {
+		e.Message = strings.TrimSpace(string(body))
+	}
+	e.Action = action
+	e.StatusCode = resp.StatusCode
+	if e.Err == "" {
+		e.Err = http.StatusText(resp.StatusCode)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		e.Guidance = "Check the username and password or the api key, or the credentials of the context"
+	case resp.StatusCode == http.StatusForbidden:
+		e.Guidance = fmt.Sprintf("User %v lacks the kibana privileges for this operation in %v, check the feature privileges of its roles", c.Username, c.spaceName())
+	case resp.StatusCode == http.StatusConflict:
+		e.Guidance = "The object changed since it was retrieved, export it again or use --force to overwrite it"
+	case resp.StatusCode == http.StatusNotFound && c.Space != "" && c.Space != "default" && strings.Contains(strings.ToLower(e.Message), "space"):
+		e.Guidance = fmt.Sprintf("Check that the space %v exists, see kibctl audit or the spaces management page", c.Space)
+	}
+	return e
+}
+
+func (c *client) spaceName() string {
+	if c.Space == "" {
+		return "the default space"
+	}
+	return "space " + c.Space
+}
@@ -0,0 +1,170 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+// apiResource is a kind of kibana object along with the operations kibctl
+// can perform on it with the current credentials.
+type apiResource struct {
+	Name      string   `json:"name"`
+	Kind      string   `json:"kind"`
+	Available bool     `json:"available"`
+	Verbs     []string `json:"verbs"`
+}
+
+// writeCapabilities are the ui capabilities granting the creation and update
+// of the saved objects of each type, the saved objects management ones
+// applying to the other types.
+var writeCapabilities = map[string]string{
+	"dashboard":     "dashboard.createNew",
+	"visualization": "visualize.save",
+	"lens":          "visualize.save",
+	"search":        "discover.save",
+	"map":           "maps.save",
+	"index-pattern": "indexPatterns.save",
+	"tag":           "savedObjectsTagging.create",
+}
+
+// apiProbes are the kibana apis kibctl commands use beyond the saved objects,
+// probed with a request reading them, along with the operations kibctl has
+// for them.
+var apiProbes = []struct {
+	Name  string
+	Path  string
+	Verbs []string
+}{
+	{"spaces", "/api/spaces/space", []string{"list", "get"}},
+	{"detection-rules", detectionRulesAPI + "/_find?per_page=1", []string{"list", "export", "import"}},
+	{"exception-lists", exceptionListsAPI + "/_find?per_page=1", []string{"list", "export", "import"}},
+	{"timelines", "/api/timelines?page_size=1", []string{"export", "import"}},
+	{"cases", "/api/cases/_find?perPage=1", []string{"list", "create", "update", "close"}},
+	{"maintenance-windows", maintenanceWindowAPI + "/_find", []string{"list", "create", "delete"}},
+	{"sample-data", sampleDataAPI, []string{"list", "install", "uninstall"}},
+	{"fleet-packages", fleetPackagesAPI + "?prerelease=false", []string{"assets"}},
+}
+
+// capabilities returns the ui capabilities of the user in the space of the
+// client, which kibana derives from the feature privileges of its roles.
+func (c *client) capabilities() (gjson.Result, error) {
+	body, err := c.jsonRequest("POST", "/api/core/capabilities", map[string]interface{}{"applications": []string{}})
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	return gjson.ParseBytes(body), nil
+}
+
+// probe reports whether a GET of the path succeeds.
+func (c *client) probe(path string) (bool, error) {
+	req, err := c.newRequest("GET", path, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, _, err := c.doRequest(req)
+	if err != nil {
+		return false, err
+	}
+	return resp.StatusCode == http.StatusOK, nil
+}
+
+// apiResources probes the saved object types and apis kibctl handles. The
+// types the user may read but the capabilities of which are unknown, e.g.
+// on opensearch dashboards, are assumed writable.
+func (c *client) apiResources() ([]apiResource, error) {
+	caps, err := c.capabilities()
+	if err != nil {
+		c.Logger.Printf("capabilities not available: %v\n", err)
+	}
+	granted := func(capability string) bool {
+		value := caps.Get(capability)
+		return !caps.Exists() || !value.Exists() || value.Bool()
+	}
+
+	var resources []apiResource
+	types := append([]string{}, savedObjectTypes...)
+	for _, extra := range []string{"map", "tag"} {
+		if !contains(types, extra) {
+			types = append(types, extra)
+		}
+	}
+	for _, objectType := range types {
+		readable, err := c.probe("/api/saved_objects/_find?" + url.Values{"type": {objectType}, "per_page": {"1"}}.Encode())
+		if err != nil {
+			return nil, err
+		}
+		r := apiResource{Name: objectType, Kind: "saved-object", Available: readable}
+		if readable {
+			r.Verbs = []string{"get", "list", "export"}
+			capability, ok := writeCapabilities[objectType]
+			if !ok {
+				capability = "savedObjectsManagement.edit"
+			}
+			if granted(capability) {
+				r.Verbs = append(r.Verbs, "import", "update")
+			}
+			if granted("savedObjectsManagement.delete") {
+				r.Verbs = append(r.Verbs, "delete")
+			}
+		}
+		resources = append(resources, r)
+	}
+	for _, api := range apiProbes {
+		available, err := c.probe(api.Path)
+		if err != nil {
+			return nil, err
+		}
+		r := apiResource{Name: api.Name, Kind: "api", Available: available}
+		if available {
+			r.Verbs = api.Verbs
+		}
+		resources = append(resources, r)
+	}
+	return resources, nil
+}
+
+func apiResources(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	client := newClient()
+	resources, err := client.apiResources()
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	available := c.Bool("available")
+	if jsonl() {
+		for _, r := range resources {
+			if available && !r.Available {
+				continue
+			}
+			line, err := json.Marshal(r)
+			if err != nil {
+				return cli.NewExitError(err, 2)
+			}
+			os.Stdout.Write(append(line, '\n'))
+		}
+		return nil
+	}
+	if !quiet {
+		os.Stdout.WriteString(stdout.header(fmt.Sprintf("%-25v %-15v %-10v %v", "NAME", "KIND", "AVAILABLE", "VERBS")) + "\n")
+	}
+	for _, r := range resources {
+		if available && !r.Available {
+			continue
+		}
+		if quiet {
+			printIDs(r.Name)
+			continue
+		}
+		fmt.Fprintf(os.Stdout, "%v %-15v %-10v %v\n", stdout.paint(cyan, fmt.Sprintf("%-25v", r.Name)), r.Kind, r.Available, strings.Join(r.Verbs, ","))
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package kibctl
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"github.com/urfave/cli"
+)
+
+// approver may approve the applies with the token whose sha256 is recorded,
+// so that the approvers file holds no secret.
+type approver struct {
+	Name      string `json:"name"`
+	TokenHash string `json:"tokenHash"`
+}
+
+func loadApprovers(file string) ([]approver, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read approvers file")
+	}
+	var approvers struct {
+		Approvers []approver `json:"approvers"`
+	}
+	if err := json.Unmarshal(content, &approvers); err != nil {
+		return nil, errors.Wrapf(err, "could not parse approvers file %v", file)
+	}
+	if len(approvers.Approvers) == 0 {
+		return nil, errors.Errorf("approvers file %v lists no approver.\n", file)
+	}
+	return approvers.Approvers, nil
+}
+
+// approve returns the approver of the token, who may not be the requester
+// since a change needs a second person to approve it.
+func approve(approvers []approver, token, requester string) (string, bool) {
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(strings.TrimSpace(token))))
+	for _, a := range approvers {
+		if strings.EqualFold(a.TokenHash, hash) && a.Name != requester {
+			return a.Name, true
+		}
+	}
+	return "", false
+}
+
+// waitApproval waits for an approver token typed on the terminal or posted
+// to the listen address, and returns the name of the approver.
+func waitApproval(approvers []approver, requester, listen string, timeout time.Duration) (string, error) {
+	approved := make(chan string, 1)
+	accept := func(token string) bool {
+		name, ok := approve(approvers, token, requester)
+		if ok {
+			select {
+			case approved <- name:
+			default:
+			}
+		}
+		return ok
+	}
+
+	var failed chan error
+	if listen != "" {
+		listener, err := net.Listen("tcp", listen)
+		if err != nil {
+			return "", errors.Wrap(err, "could not listen for the approval")
+		}
+		server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" {
+				http.Error(w, "POST the approval token", http.StatusMethodNotAllowed)
+				return
+			}
+			token, _ := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, 1024))
+			if !accept(string(token)) {
+				http.Error(w, "invalid token", http.StatusForbidden)
+				return
+			}
+			fmt.Fprintln(w, "approved")
+		})}
+		failed = make(chan error, 1)
+		go func() { failed <- server.Serve(listener) }()
+		defer server.Close()
+		fmt.Fprintf(os.Stderr, "waiting for an approver token posted to %v\n", listen)
+	}
+	if isTerminal(os.Stdin) {
+		fmt.Fprintf(os.Stderr, "approver token: ")
+		go func() {
+			scanner := bufio.NewScanner(os.Stdin)
+			for scanner.Scan() {
+				if accept(scanner.Text()) {
+					return
+				}
+				fmt.Fprintf(os.Stderr, "invalid token, approver token: ")
+			}
+		}()
+	}
+
+	select {
+	case name := <-approved:
+		return name, nil
+	case err := <-failed:
+		return "", errors.Wrap(err, "approval listener failed")
+	case <-time.After(timeout):
+		return "", errors.Errorf("no approval within %v.\n", timeout)
+	}
+}
+
+// liveObjects returns the objects of the payload as they currently are on
+// kibana, leaving out the ones which do not exist yet.
+func (c *client) liveObjects(payload []byte) ([]byte, error) {
+	live := []byte(`{"objects":[]}`)
+	for _, object := range gjson.GetBytes(payload, "objects").Array() {
+		current, err := c.getObject(object.Get("type").String(), object.Get("id").String())
+		if err != nil {
+			return nil, err
+		}
+		if current == nil {
+			continue
+		}
+		if live, err = sjson.SetRawBytes(live, "objects.-1", current); err != nil {
+			return nil, err
+		}
+	}
+	return live, nil
+}
+
+func apply(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	path := c.Args().First()
+	if path == "" {
+		return cli.NewExitError("export file or directory missing", 1)
+	}
+	var approvers []approver
+	if c.Bool("require-approval") {
+		file := c.String("approvers-file")
+		if file == "" {
+			return cli.NewExitError("--require-approval requires --approvers-file", 1)
+		}
+		var err error
+		if approvers, err = loadApprovers(file); err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		if c.String("approval-listen") == "" {
+			if err := requireStdin("waiting for the approval token on the terminal"); err != nil {
+				return err
+			}
+		}
+	}
+	payload, err := readBundle(path)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+
+	client := newClient()
+	if !c.Bool("no-validate") {
+		findings, err := client.checkSchemas(payload)
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		if errorCount := printFindings(findings); errorCount > 0 {
+			return cli.NewExitError(fmt.Sprintf("%d schema error(s) found, nothing applied", errorCount), 3)
+		}
+	}
+	// the lock is taken before reading the live objects, so that the diff,
+	// the canary run and the approval are about the objects then imported
+	holder := ""
+	if !c.Bool("no-lock") && !c.Bool("dry-run") {
+		holder = defaultLockHolder()
+		held, err := client.acquireLock(holder, c.Duration("lock-ttl"), c.Duration("lock-timeout"))
+		if err != nil {
+			return cli.NewExitError(errors.Wrap(err, "nothing applied"), 2)
+		}
+		if !held {
+			defer func() {
+				if err := client.releaseLock(holder, false); err != nil && !quiet {
+					fmt.Fprintf(os.Stderr, "warning: %v\n", strings.TrimSpace(err.Error()))
+				}
+			}()
+		}
+	}
+	live, err := client.liveObjects(payload)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	diffs, err := diffExports(live, payload)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if len(diffs) == 0 {
+		if !quiet {
+			fmt.Fprintf(os.Stdout, "no change to apply to %v\n", client.spaceName())
+		}
+		return nil
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stdout, "changes to apply to %v on %v:\n", client.spaceName(), publicHost(client.Host))
+		writeDiffText(os.Stdout, diffs)
+	}
+	if id := c.String("canary-space"); id != "" {
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "importing into canary space %v\n", id)
+		}
+		findings, err := client.canaryImport(payload, id)
+		if err != nil {
+			return cli.NewExitError(errors.Wrap(err, "nothing applied"), 2)
+		}
+		if errorCount := printFindings(findings); errorCount > 0 {
+			return cli.NewExitError(fmt.Sprintf("%d error(s) found in canary space %v, nothing applied", errorCount, id), 3)
+		}
+	}
+	if c.Bool("dry-run") {
+		return nil
+	}
+
+	approvedBy := ""
+	if approvers != nil {
+		requester := os.Getenv("USER")
+		if current, err := user.Current(); err == nil {
+			requester = current.Username
+		}
+		if approvedBy, err = waitApproval(approvers, requester, c.String("approval-listen"), c.Duration("approval-timeout")); err != nil {
+			return cli.NewExitError(errors.Wrap(err, "nothing applied"), 2)
+		}
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "approved by %v\n", approvedBy)
+		}
+		if holder != "" {
+			// extends the lock, which may have expired during the wait
+			blocking, err := client.tryLock(holder, c.Duration("lock-ttl"))
+			if err == nil && blocking != nil {
+				err = errors.Errorf("lock taken by %v while waiting for the approval.\n", blocking)
+			}
+			if err != nil {
+				return cli.NewExitError(errors.Wrap(err, "nothing applied"), 2)
+			}
+		}
+	}
+
+	conf, err := loadConfig()
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	pre := newOperationSummary("apply", "pre", payload, nil)
+	pre.ApprovedBy = approvedBy
+	if err := runHooks(conf.Hooks.PreImport, pre); err != nil {
+		return cli.NewExitError(errors.Wrap(err, "nothing applied"), 2)
+	}
+	emitObjects("apply", "started", payload, nil)
+	if c.Bool("atomic") {
+		err = client.importAtomic(payload, live)
+	} else {
+		err = client._import(payload)
+	}
+	emitObjects("apply", "succeeded", payload, err)
+	post := newOperationSummary("apply", "post", payload, err)
+	post.ApprovedBy = approvedBy
+	runPostHooks(conf.Hooks.PostImport, post)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	printIDs(payloadIDs(payload)...)
+	return nil
+}
@@ -0,0 +1,75 @@
+package kibctl
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitApproval(t *testing.T) {
+	approvers := []approver{{Name: "alice", TokenHash: fmt.Sprintf("%x", sha256.Sum256([]byte("secret")))}}
+
+	// a free port, released for the approval listener
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listen := listener.Addr().String()
+	listener.Close()
+
+	done := make(chan error, 1)
+	var name string
+	go func() {
+		var err error
+		name, err = waitApproval(approvers, "bob", listen, 5*time.Second)
+		done <- err
+	}()
+
+	tests := []struct {
+		token string
+		want  int
+	}{
+		{"wrong", http.StatusForbidden},
+		{"secret", http.StatusOK},
+	}
+	for _, test := range tests {
+		var resp *http.Response
+		for i := 0; i < 50; i++ {
+			if resp, err = http.Post("http://"+listen, "text/plain", strings.NewReader(test.token)); err == nil {
+				break
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		if err != nil {
+			t.Fatalf("posting the token: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.want {
+			t.Errorf("posting %q answered %d, want %d", test.token, resp.StatusCode, test.want)
+		}
+	}
+	if err := <-done; err != nil || name != "alice" {
+		t.Errorf("waitApproval() = %v, %v, want alice", name, err)
+	}
+}
+
+func TestWaitApprovalListenFailure(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	start := time.Now()
+	_, err = waitApproval(nil, "bob", listener.Addr().String(), time.Minute)
+	if err == nil {
+		t.Fatal("waitApproval() listened on an address in use")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("waitApproval() failed after %v instead of right away", time.Since(start))
+	}
+}
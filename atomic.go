@@ -0,0 +1,65 @@
+package kibctl
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// rollback restores the objects of the payload to the live versions captured
+// before the import and deletes the ones the import created. It is best
+// effort: every object is attempted and the failures are reported together.
+func (c *client) rollback(payload, live []byte) error {
+	existed := exportObjects(live)
+	var failed []string
+	for _, object := range gjson.GetBytes(payload, "objects").Array() {
+		objectType, id := object.Get("type").String(), object.Get("id").String()
+		if _, ok := existed[objectType+":"+id]; ok {
+			continue
+		}
+		if err := c.deleteObject(objectType, id); err != nil {
+			failed = append(failed, fmt.Sprintf("%v %v: %v", objectType, id, err))
+		}
+	}
+
+	restore := []byte(`{"objects":[]}`)
+	for _, object := range gjson.GetBytes(live, "objects").Array() {
+		// the version captured is outdated once the import changed the object
+		raw, err := sjson.DeleteBytes([]byte(object.Raw), "version")
+		if err != nil {
+			return err
+		}
+		if restore, err = sjson.SetRawBytes(restore, "objects.-1", raw); err != nil {
+			return err
+		}
+	}
+	if len(gjson.GetBytes(restore, "objects").Array()) > 0 {
+		if err := c._import(restore); err != nil {
+			failed = append(failed, fmt.Sprintf("restoring %d object(s): %v", len(gjson.GetBytes(restore, "objects").Array()), err))
+		}
+	}
+	if len(failed) > 0 {
+		return errors.Errorf("rollback incomplete, check these objects:\n%v\n", strings.Join(failed, "\n"))
+	}
+	return nil
+}
+
+// importAtomic imports the payload and rolls back the objects it changed when
+// the import fails, so that a dashboard set is never left half applied.
+func (c *client) importAtomic(payload, live []byte) error {
+	err := c._import(payload)
+	if err == nil {
+		return nil
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "import failed, rolling back %v\n", c.spaceName())
+	}
+	if rollbackErr := c.rollback(payload, live); rollbackErr != nil {
+		return errors.Errorf("%v%v", err, rollbackErr)
+	}
+	return errors.Wrap(err, "rolled back")
+}
@@ -0,0 +1,88 @@
+package kibctl
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestImportAtomicRollback(t *testing.T) {
+	defer func(q bool) { quiet = q }(quiet)
+	quiet = true
+
+	payload := []byte(`{"objects":[
+		{"id":"d1","type":"dashboard","attributes":{"title":"Nginx v2"}},
+		{"id":"v1","type":"visualization","attributes":{"title":"Requests"}},
+		{"id":"ip1","type":"index-pattern","attributes":{"title":"nginx-*"}}]}`)
+	live := []byte(`{"objects":[
+		{"id":"d1","type":"dashboard","version":"WzEsMV0=","attributes":{"title":"Nginx"}},
+		{"id":"ip1","type":"index-pattern","version":"WzIsMV0=","attributes":{"title":"nginx-*"}}]}`)
+
+	tests := []struct {
+		name         string
+		deleteStatus int
+		want         string
+	}{
+		{"rolled back", http.StatusOK, "rolled back"},
+		{"rollback incomplete", http.StatusInternalServerError, "rollback incomplete"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var deleted []string
+			var imports [][]byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case r.Method == "DELETE":
+					deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/api/saved_objects/"))
+					w.WriteHeader(test.deleteStatus)
+					w.Write([]byte(`{}`))
+				case r.URL.Path == "/api/kibana/dashboards/import":
+					body, _ := ioutil.ReadAll(r.Body)
+					imports = append(imports, body)
+					// the first import fails, the restore succeeds
+					if len(imports) == 1 {
+						w.WriteHeader(http.StatusInternalServerError)
+						w.Write([]byte(`{"statusCode":500,"error":"Internal Server Error"}`))
+						return
+					}
+					w.Write([]byte(`{"objects":[]}`))
+				default:
+					t.Errorf("unexpected request %v %v", r.Method, r.URL)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			err := newTestClient(server).importAtomic(payload, live)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("importAtomic() error = %v, want %v", err, test.want)
+			}
+			if want := []string{"visualization/v1"}; len(deleted) != 1 || deleted[0] != want[0] {
+				t.Errorf("deleted %v, want only the created %v", deleted, want)
+			}
+			if len(imports) != 2 {
+				t.Fatalf("%d imports, want the import and the restore", len(imports))
+			}
+			var restored []string
+			for _, object := range gjson.GetBytes(imports[1], "objects").Array() {
+				if object.Get("version").Exists() {
+					t.Errorf("%v restored with its outdated version", object.Get("id"))
+				}
+				restored = append(restored, object.Get("type").String()+"/"+object.Get("id").String()+" "+object.Get("attributes.title").String())
+			}
+			sort.Strings(restored)
+			if want := []string{"dashboard/d1 Nginx", "index-pattern/ip1 nginx-*"}; strings.Join(restored, ",") != strings.Join(want, ",") {
+				t.Errorf("restored %v, want the modified objects %v", restored, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package kibctl
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// contentHash fingerprints attributes independently of the key ordering.
+func contentHash(attributes json.RawMessage) string {
+	var value interface{}
+	if err := json.Unmarshal(attributes, &value); err != nil {
+		return fmt.Sprintf("%x", sha256.Sum256(attributes))
+	}
+	canonical, _ := json.Marshal(value)
+	return fmt.Sprintf("%x", sha256.Sum256(canonical))
+}
+
+type spaceObject struct {
+	Space string
+	savedObject
+}
+
+type collision struct {
+	Kind       string
+	Type       string
+	Key        string
+	Spaces     []string
+	Suggestion string
+}
+
+// findCollisions groups objects by type and id, then by type and title, and
+// reports the groups whose members do not share the same content.
+func findCollisions(objects []spaceObject) []collision {
+	byID := make(map[string][]spaceObject)
+	byTitle := make(map[string][]spaceObject)
+	for _, o := range objects {
+		byID[o.Type+"\x00"+o.ID] = append(byID[o.Type+"\x00"+o.ID], o)
+		if o.title() != "" {
+			byTitle[o.Type+"\x00"+o.title()] = append(byTitle[o.Type+"\x00"+o.title()], o)
+		}
+	}
+
+	var collisions []collision
+	report := func(groups map[string][]spaceObject, kind, suggestion string) {
+		for key, group := range groups {
+			if len(group) < 2 || !diverges(group) {
+				continue
+			}
+			var spaces []string
+			for _, o := range group {
+				spaces = append(spaces, o.Space)
+			}
+			sort.Strings(spaces)
+			parts := strings.SplitN(key, "\x00", 2)
+			collisions = append(collisions, collision{
+				Kind:       kind,
+				Type:       parts[0],
+				Key:        parts[1],
+				Spaces:     spaces,
+				Suggestion: suggestion,
+			})
+		}
+	}
+	report(byID, "ID", "content diverged after a copy, re-copy from the authoritative space with overwrite or recreate the copies with new ids")
+	report(byTitle, "TITLE", "different objects share a title, rename the copies or consolidate them into a single shared object")
+
+	sort.Slice(collisions, func(i, j int) bool {
+		if collisions[i].Kind != collisions[j].Kind {
+			return collisions[i].Kind < collisions[j].Kind
+		}
+		if collisions[i].Type != collisions[j].Type {
+			return collisions[i].Type < collisions[j].Type
+		}
+		return collisions[i].Key < collisions[j].Key
+	})
+	return collisions
+}
+
+func diverges(group []spaceObject) bool {
+	hash := contentHash(group[0].Attributes)
+	for _, o := range group[1:] {
+		if contentHash(o.Attributes) != hash {
+			return true
+		}
+	}
+	return false
+}
+
+func auditCollisions(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	client := newClient()
+	spaces, err := client.listSpaces()
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+
+	var objects []spaceObject
+	for _, s := range spaces {
+		client.Logger.Printf("scanning space %v\n", s.ID)
+		found, err := client.inSpace(s.ID).findObjects(savedObjectTypes, "")
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		for _, o := range found {
+			objects = append(objects, spaceObject{Space: s.ID, savedObject: o})
+		}
+	}
+
+	collisions := findCollisions(objects)
+	if quiet {
+		for _, col := range collisions {
+			printIDs(col.Key)
+		}
+		return nil
+	}
+	os.Stdout.WriteString(stdout.header(fmt.Sprintf("%-9v %-15v %-40v %v", "COLLISION", "TYPE", "KEY", "SPACES")) + "\n")
+	for _, col := range collisions {
+		kind := stdout.paint(yellow, fmt.Sprintf("%-9v", col.Kind))
+		if col.Kind == "ID" {
+			kind = stdout.paint(red, fmt.Sprintf("%-9v", col.Kind))
+		}
+		os.Stdout.WriteString(fmt.Sprintf("%v %-15v %-40v %v\n", kind, col.Type, col.Key, strings.Join(col.Spaces, ",")))
+		os.Stdout.WriteString(fmt.Sprintf("%-9v %v\n", "", stdout.paint(green, "-> "+col.Suggestion)))
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// auditRecord is a line of the audit log, recording a change applied to a
+// kibana environment.
+type auditRecord struct {
+	Time      time.Time `json:"time"`
+	User      string    `json:"user"`
+	Operation string    `json:"operation"`
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to"`
+	Tag       string    `json:"tag,omitempty"`
+	Objects   []string  `json:"objects"`
+}
+
+func (conf *config) auditLogFile() (string, error) {
+	if conf.AuditLog != "" {
+		return conf.AuditLog, nil
+	}
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "audit.log"), nil
+}
+
+// recordAudit appends the record to the audit log as a json line.
+func (conf *config) recordAudit(record auditRecord) error {
+	file, err := conf.auditLogFile()
+	if err != nil {
+		return err
+	}
+	record.Time = time.Now().UTC()
+	if current, err := user.Current(); err == nil {
+		record.User = current.Username
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return errors.Wrap(err, "could not create audit log directory")
+	}
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrap(err, "could not open audit log")
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return errors.Wrap(err, "could not write audit log")
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package kibctl
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// benchEndpoints are representative kibana apis, from the lightest to the
+// heaviest of the ones kibctl calls.
+var benchEndpoints = []struct {
+	Name string
+	Path string
+}{
+	{"status", "/api/status"},
+	{"find dashboard", "/api/saved_objects/_find?type=dashboard&per_page=1"},
+	{"find index-pattern", "/api/saved_objects/_find?type=index-pattern&per_page=100"},
+	{"find all types", "/api/saved_objects/_find?type=dashboard&type=visualization&type=lens&type=search&per_page=100"},
+}
+
+type benchResult struct {
+	Name      string
+	Latencies []time.Duration
+	Errors    int
+	LastError string
+}
+
+// percentile returns the latency under which the fraction p of the sorted
+// latencies fall.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+// bench sends the requests to the endpoint one after the other, bypassing the
+// cache, and records the latency of the successful ones up to the full read
+// of their body.
+func (c *client) bench(name, path string, requests int) benchResult {
+	result := benchResult{Name: name}
+	for i := 0; i < requests; i++ {
+		req, err := c.newRequest("GET", path, nil)
+		if err != nil {
+			result.Errors++
+			result.LastError = err.Error()
+			continue
+		}
+		start := time.Now()
+		resp, details, err := c.doRequest(req)
+		elapsed := time.Since(start)
+		switch {
+		case err != nil:
+			result.Errors++
+			result.LastError = err.Error()
+		case resp.StatusCode != http.StatusOK:
+			result.Errors++
+			result.LastError = c.apiError("GET "+path, resp, details).Error()
+		default:
+			result.Latencies = append(result.Latencies, elapsed)
+		}
+	}
+	sort.Slice(result.Latencies, func(i, j int) bool { return result.Latencies[i] < result.Latencies[j] })
+	return result
+}
+
+func benchCommand(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	requests := c.Int("requests")
+	if requests <= 0 {
+		return cli.NewExitError("--requests must be positive", 1)
+	}
+	client := newClient()
+	client.CacheTTL = 0
+
+	failed := false
+	if !quiet {
+		os.Stdout.WriteString(stdout.header(fmt.Sprintf("%-20v %-10v %-10v %-10v %-10v %-10v %v", "ENDPOINT", "MIN", "P50", "P90", "P99", "MAX", "ERRORS")) + "\n")
+	}
+	for _, endpoint := range benchEndpoints {
+		client.Logger.Printf("benchmarking %v\n", endpoint.Path)
+		r := client.bench(endpoint.Name, endpoint.Path, requests)
+		if r.Errors > 0 {
+			failed = true
+			fmt.Fprintf(os.Stderr, "%v: %v\n", r.Name, strings.TrimSpace(r.LastError))
+		}
+		if quiet {
+			continue
+		}
+		round := func(d time.Duration) time.Duration { return d.Round(100 * time.Microsecond) }
+		var min, max time.Duration
+		if len(r.Latencies) > 0 {
+			min, max = r.Latencies[0], r.Latencies[len(r.Latencies)-1]
+		}
+		fmt.Fprintf(os.Stdout, "%-20v %-10v %-10v %-10v %-10v %-10v %d/%d\n", r.Name, round(min),
+			round(percentile(r.Latencies, 0.5)), round(percentile(r.Latencies, 0.9)), round(percentile(r.Latencies, 0.99)), round(max), r.Errors, requests)
+	}
+	if failed {
+		return cli.NewExitError("some requests failed", 2)
+	}
+	return nil
+}
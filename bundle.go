@@ -0,0 +1,243 @@
+package kibctl
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// bundleIndex lists the saved objects of a split export.
+type bundleIndex struct {
+	Version string        `json:"version"`
+	Objects []bundleEntry `json:"objects"`
+	// MissingReferences are the placeholders of the dependencies missing at export
+	MissingReferences json.RawMessage `json:"missingReferences,omitempty"`
+}
+
+type bundleEntry struct {
+	Type  string `json:"type"`
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	File  string `json:"file"`
+	// Attachments maps visState paths to the files their content was extracted to
+	Attachments map[string]string `json:"attachments,omitempty"`
+}
+
+const bundleIndexFile = "index.json"
+
+func objectFileName(objectType, id string) string {
+	name := strings.NewReplacer("/", "_", `\`, "_").Replace(id)
+	return filepath.Join(objectType, name+".json")
+}
+
+type splitOptions struct {
+	// ExtractVega writes the vega specs to standalone files
+	ExtractVega bool
+	// ExtractMarkdown writes the markdown panel bodies to standalone files
+	ExtractMarkdown bool
+}
+
+const markdownPath = "params.markdown"
+
+func isMarkdown(visState string) bool {
+	return gjson.Get(visState, "type").String() == "markdown"
+}
+
+// splitBundle writes each saved object of the export to its own file under dir
+// along with an index.json listing the bundle.
+func splitBundle(export []byte, dir string, options splitOptions) error {
+	index := bundleIndex{Version: gjson.GetBytes(export, "version").String()}
+	if missing := gjson.GetBytes(export, "missingReferences"); missing.Exists() {
+		index.MissingReferences = json.RawMessage(missing.Raw)
+	}
+	for _, object := range gjson.GetBytes(export, "objects").Array() {
+		entry := bundleEntry{
+			Type:  object.Get("type").String(),
+			ID:    object.Get("id").String(),
+			Title: object.Get("attributes.title").String(),
+		}
+		entry.File = objectFileName(entry.Type, entry.ID)
+		raw := []byte(object.Raw)
+		visState := object.Get("attributes.visState").String()
+		path, extension := "", ""
+		switch {
+		case options.ExtractVega && isVega(visState):
+			path, extension = vegaSpecPath, ".vega.hjson"
+		case options.ExtractMarkdown && isMarkdown(visState):
+			path, extension = markdownPath, ".md"
+		}
+		if path != "" {
+			var err error
+			raw, err = extractAttachment(raw, entry, path, extension, dir)
+			if err != nil {
+				return err
+			}
+			entry.Attachments = map[string]string{path: attachmentFileName(entry, extension)}
+		}
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, raw, "", "  "); err != nil {
+			return errors.Wrapf(err, "could not format %v %v", entry.Type, entry.ID)
+		}
+		pretty.WriteString("\n")
+		if err := writeBundleFile(dir, entry.File, pretty.Bytes()); err != nil {
+			return err
+		}
+		index.Objects = append(index.Objects, entry)
+	}
+
+	content, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeBundleFile(dir, bundleIndexFile, append(content, '\n'))
+}
+
+func attachmentFileName(entry bundleEntry, extension string) string {
+	return strings.TrimSuffix(entry.File, ".json") + extension
+}
+
+// extractAttachment moves the visState content at path to its own file and
+// blanks it in the object.
+func extractAttachment(object []byte, entry bundleEntry, path, extension, dir string) ([]byte, error) {
+	visState := gjson.GetBytes(object, "attributes.visState").String()
+	content := gjson.Get(visState, path).String()
+	if err := writeBundleFile(dir, attachmentFileName(entry, extension), []byte(content)); err != nil {
+		return nil, err
+	}
+	visState, err := sjson.Set(visState, path, "")
+	if err != nil {
+		return nil, err
+	}
+	return sjson.SetBytes(object, "attributes.visState", visState)
+}
+
+// embedAttachments puts back the content of the extracted files in the object.
+func embedAttachments(object []byte, entry bundleEntry, dir string) ([]byte, error) {
+	if len(entry.Attachments) == 0 {
+		return object, nil
+	}
+	visState := gjson.GetBytes(object, "attributes.visState").String()
+	for path, file := range entry.Attachments {
+		content, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read attachment of %v %v", entry.Type, entry.ID)
+		}
+		if path == vegaSpecPath {
+			if err := validateVegaSpec(string(content)); err != nil {
+				return nil, errors.Wrapf(err, "invalid vega spec %v", file)
+			}
+		}
+		visState, err = sjson.Set(visState, path, string(content))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sjson.SetBytes(object, "attributes.visState", visState)
+}
+
+func writeBundleFile(dir, name string, content []byte) error {
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrapf(err, "could not create directory for %v", path)
+	}
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		return errors.Wrapf(err, "could not write %v", path)
+	}
+	return nil
+}
+
+// joinBundle reassembles the export previously split under dir.
+func joinBundle(dir string) ([]byte, error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, bundleIndexFile))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read bundle index")
+	}
+	var index bundleIndex
+	if err := json.Unmarshal(content, &index); err != nil {
+		return nil, errors.Wrap(err, "could not parse bundle index")
+	}
+
+	export := []byte(`{"objects":[]}`)
+	if index.Version != "" {
+		export, err = sjson.SetBytes(export, "version", index.Version)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(index.MissingReferences) > 0 {
+		export, err = sjson.SetRawBytes(export, "missingReferences", index.MissingReferences)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, entry := range index.Objects {
+		object, err := ioutil.ReadFile(filepath.Join(dir, entry.File))
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read %v %v", entry.Type, entry.ID)
+		}
+		if !gjson.ValidBytes(object) {
+			return nil, errors.Errorf("invalid json in %v.\n", entry.File)
+		}
+		object, err = embedAttachments(bytes.TrimSpace(object), entry, dir)
+		if err != nil {
+			return nil, err
+		}
+		export, err = sjson.SetRawBytes(export, "objects.-1", bytes.TrimSpace(object))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return export, nil
+}
+
+// readBundle reads an export from a json file or a split export directory.
+func readBundle(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return joinBundle(path)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read export file")
+	}
+	return content, nil
+}
+
+// removeBundle deletes the files of the split export previously written under
+// dir, so that objects since deleted on kibana do not linger.
+func removeBundle(dir string) error {
+	content, err := ioutil.ReadFile(filepath.Join(dir, bundleIndexFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "could not read bundle index")
+	}
+	var index bundleIndex
+	if err := json.Unmarshal(content, &index); err != nil {
+		return errors.Wrap(err, "could not parse bundle index")
+	}
+	files := []string{bundleIndexFile}
+	for _, entry := range index.Objects {
+		files = append(files, entry.File)
+		for _, file := range entry.Attachments {
+			files = append(files, file)
+		}
+	}
+	for _, file := range files {
+		if err := os.Remove(filepath.Join(dir, file)); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "could not remove %v", file)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package kibctl
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+type cacheEntry struct {
+	Stored     time.Time   `json:"stored"`
+	StatusCode int         `json:"statusCode"`
+	Status     string      `json:"status"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+func cacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "kibctl"), nil
+}
+
+// cacheable reports whether the response to the request may be served from
+// the cache: only the saved objects searches and the status are.
+func cacheable(req *http.Request) bool {
+	return req.Method == "GET" &&
+		(strings.HasSuffix(req.URL.Path, "/api/saved_objects/_find") || strings.HasSuffix(req.URL.Path, "/api/status"))
+}
+
+// revalidatable reports whether the response to the request may be stored to
+// be revalidated with a conditional request: the potentially large saved
+// objects and dashboard exports are never served without revalidation.
+func revalidatable(req *http.Request) bool {
+	return req.Method == "GET" &&
+		(strings.Contains(req.URL.Path, "/api/saved_objects/") || strings.HasSuffix(req.URL.Path, "/dashboards/export"))
+}
+
+// cacheKey identifies a request by its url, which includes the host, the space
+// and the query, as well as the credentials and tenant the response depends on.
+// The whole Authorization header is hashed, so that a basic auth user with
+// another password does not share the responses. The signed requests are
+// identified by the signing identity, their Authorization header being set
+// after the cache lookup.
+func cacheKey(req *http.Request, signer string) string {
+	key := strings.Join([]string{req.URL.String(), req.Header.Get("Authorization"), signer, req.Header.Get("securitytenant")}, "\x00")
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(key)))
+}
+
+func readCache(key string) (*cacheEntry, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadFile(filepath.Join(dir, key+".json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(content, &entry); err != nil {
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+func writeCache(key string, entry cacheEntry) error {
+	dir, err := cacheDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, key+".json"), content, 0600)
+}
+
+func (entry *cacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:     entry.Status,
+		StatusCode: entry.StatusCode,
+		Header:     entry.Header,
+		Body:       ioutil.NopCloser(bytes.NewReader(entry.Body)),
+		Request:    req,
+	}
+}
+
+func (entry *cacheEntry) validators() bool {
+	return entry.Header.Get("ETag") != "" || entry.Header.Get("Last-Modified") != ""
+}
+
+// cachedDo serves requests from the on-disk cache while their entry is younger
+// than the ttl. Older entries carrying an ETag or a Last-Modified date are
+// revalidated with a conditional request, so that unchanged responses are not
+// downloaded again. Successful responses are stored when they can be reused.
+func (c *client) cachedDo(req *http.Request, ttl time.Duration) (*http.Response, error) {
+	signer, err := c.signingIdentity()
+	if err != nil {
+		return nil, err
+	}
+	key := cacheKey(req, signer)
+	entry, err := readCache(key)
+	if err != nil {
+		c.Logger.Printf("could not read cache: %v\n", err)
+	}
+	if entry != nil && time.Since(entry.Stored) <= ttl {
+		c.Logger.Printf("cache hit %v\n", req.URL)
+		return entry.response(req), nil
+	}
+	if entry != nil {
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if modified := entry.Header.Get("Last-Modified"); modified != "" {
+			req.Header.Set("If-Modified-Since", modified)
+		}
+	}
+
+	resp, err := c.roundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		resp.Body.Close()
+		c.Logger.Printf("not modified %v\n", req.URL)
+		entry.Stored = time.Now()
+		if err := writeCache(key, *entry); err != nil {
+			c.Logger.Printf("could not write cache: %v\n", err)
+		}
+		return entry.response(req), nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	reader := io.Reader(resp.Body)
+	if c.MaxResponseSize > 0 {
+		// one byte past the limit tells a body of exactly the maximum size
+		// from a larger one
+		reader = io.LimitReader(resp.Body, c.MaxResponseSize+1)
+	}
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if c.MaxResponseSize > 0 && int64(len(body)) > c.MaxResponseSize {
+		// too large to be held in memory, streamed uncached
+		c.Logger.Printf("not caching the response of %v past --max-response-mb\n", req.URL.Path)
+		resp.Body = &streamedBody{Reader: io.MultiReader(bytes.NewReader(body), resp.Body), Closer: resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	entry = &cacheEntry{Stored: time.Now(), StatusCode: resp.StatusCode, Status: resp.Status, Header: resp.Header, Body: body}
+	if ttl > 0 || entry.validators() {
+		if err := writeCache(key, *entry); err != nil {
+			c.Logger.Printf("could not write cache: %v\n", err)
+		}
+	}
+	return entry.response(req), nil
+}
+
+// streamedBody is a response body whose head was already read, the rest of it
+// being streamed from the original body.
+type streamedBody struct {
+	io.Reader
+	io.Closer
+}
+
+func cacheClear(c *cli.Context) error {
+	dir, err := cacheDir()
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return cli.NewExitError(errors.Wrap(err, "could not clear cache"), 2)
+	}
+	return nil
+}
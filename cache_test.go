@@ -0,0 +1,111 @@
+package kibctl
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheKey(t *testing.T) {
+	authenticated := func(user, password, tenant string) *http.Request {
+		req, err := http.NewRequest("GET", "http://kibana:5601/s/ops/api/saved_objects/_find?type=dashboard", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user != "" {
+			req.SetBasicAuth(user, password)
+		}
+		if tenant != "" {
+			req.Header.Set("securitytenant", tenant)
+		}
+		return req
+	}
+	request := func(user, tenant string) *http.Request {
+		return authenticated(user, "secret", tenant)
+	}
+	base := cacheKey(request("elastic", ""), "")
+	tests := []struct {
+		name   string
+		req    *http.Request
+		signer string
+		same   bool
+	}{
+		{"same request", request("elastic", ""), "", true},
+		{"other user", request("reader", ""), "", false},
+		{"same user, other password", authenticated("elastic", "other", ""), "", false},
+		{"other tenant", request("elastic", "global"), "", false},
+		{"signed", request("elastic", ""), "sigv4 AKIA1 eu-west-1", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if same := cacheKey(test.req, test.signer) == base; same != test.same {
+				t.Errorf("cacheKey() shared = %v, want %v", same, test.same)
+			}
+		})
+	}
+	if cacheKey(request("", ""), "sigv4 AKIA1 eu-west-1") == cacheKey(request("", ""), "sigv4 AKIA2 eu-west-1") {
+		t.Error("cacheKey() shared between two signing identities")
+	}
+}
+
+func TestCachedDo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kibctl-cache-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("XDG_CACHE_HOME", os.Getenv("XDG_CACHE_HOME"))
+	os.Setenv("XDG_CACHE_HOME", dir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"1"`)
+		w.Write([]byte(strings.TrimPrefix(r.URL.Path, "/api/saved_objects/dashboard/")))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		id     string
+		ttl    time.Duration
+		cached bool
+	}{
+		{"revalidated with a ttl", "small", time.Minute, true},
+		{"not stored without a ttl", "small", 0, false},
+		{"streamed past the maximum size", strings.Repeat("x", 100), time.Minute, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			os.RemoveAll(dir)
+			c := newTestClient(server)
+			c.CacheTTL = test.ttl
+			c.MaxResponseSize = 16
+			req, err := c.newRequest("GET", "/api/saved_objects/dashboard/"+test.id, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := c.send(req)
+			if err != nil {
+				t.Fatalf("send() error: %v", err)
+			}
+			body, err := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatalf("read error: %v", err)
+			}
+			if string(body) != test.id {
+				t.Errorf("send() read %d bytes, want %d", len(body), len(test.id))
+			}
+			entry, err := readCache(cacheKey(req, ""))
+			if err != nil {
+				t.Fatalf("readCache() error: %v", err)
+			}
+			if (entry != nil) != test.cached {
+				t.Errorf("send() cached = %v, want %v", entry != nil, test.cached)
+			}
+		})
+	}
+}
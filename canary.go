@@ -0,0 +1,78 @@
+package kibctl
+
+import (
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+)
+
+// canaryImport imports the payload into a scratch space created for the
+// occasion and checks it there: the lint checks, the references left
+// dangling, the fields of the visualizations and the searches of their data
+// views. The space is deleted afterwards whatever the outcome.
+func (c *client) canaryImport(payload []byte, id string) (findings []lintFinding, err error) {
+	if id == c.Space || (id == "default" && c.Space == "") {
+		return nil, errors.Errorf("canary space %v is the target space.\n", id)
+	}
+	findings = lintExport(payload)
+
+	if err := c.createSpace(kibanaSpace{ID: id, Name: id}); err != nil {
+		return nil, errors.Wrap(err, "could not create the canary space")
+	}
+	defer func() {
+		if deleteErr := c.deleteSpace(id); deleteErr != nil && err == nil {
+			err = errors.Wrapf(deleteErr, "could not delete the canary space %v", id)
+		}
+	}()
+	canary := c.inSpace(id)
+	if err := canary._import(payload); err != nil {
+		return nil, errors.Wrap(err, "import into the canary space failed")
+	}
+
+	references, err := c.danglingReferences(canary, payload)
+	if err != nil {
+		return nil, err
+	}
+	findings = append(findings, references...)
+
+	fields, err := canary.checkFieldCompat(payload, true)
+	if err != nil {
+		return nil, err
+	}
+	findings = append(findings, fields...)
+
+	objects := exportObjects(payload)
+	for _, s := range warmSearches(payload) {
+		if _, err := canary.runWarmSearch(s, "now-15m"); err != nil {
+			findings = append(findings, newFinding(objects["index-pattern:"+s.DataView], severityError, "render",
+				"searching data view %v failed: %v", s.Index, errors.Cause(err)))
+		}
+	}
+	return findings, nil
+}
+
+// danglingReferences reports the references of the objects imported in the
+// canary space which exist neither there nor in the target space, where the
+// dependencies left out of the payload are expected.
+func (c *client) danglingReferences(canary *client, payload []byte) ([]lintFinding, error) {
+	missing, err := canary.missingDependencies(payload)
+	if err != nil || len(missing) == 0 {
+		return nil, err
+	}
+	inTarget, err := c.missingDependencies(payload)
+	if err != nil {
+		return nil, err
+	}
+	dangling := make(map[dependency]struct{})
+	for _, dep := range inTarget {
+		dangling[dep] = struct{}{}
+	}
+	var findings []lintFinding
+	for _, object := range gjson.GetBytes(payload, "objects").Array() {
+		for _, dep := range objectDependencies(object) {
+			if _, ok := dangling[dep]; ok {
+				findings = append(findings, newFinding(object, severityError, "reference", "%v does not exist", dep))
+			}
+		}
+	}
+	return findings, nil
+}
@@ -0,0 +1,65 @@
+package kibctl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCanaryImportCleanup(t *testing.T) {
+	payload := []byte(`{"objects":[{"id":"d1","type":"dashboard","attributes":{"title":"Nginx","panelsJSON":"[]"},"references":[]}]}`)
+	tests := []struct {
+		name         string
+		space        string
+		importStatus int
+		deleteStatus int
+		want         string
+		deleted      bool
+	}{
+		{"passed", "", http.StatusOK, http.StatusNoContent, "", true},
+		{"import failed", "", http.StatusBadRequest, http.StatusNoContent, "import into the canary space failed", true},
+		{"deletion failed", "", http.StatusOK, http.StatusInternalServerError, "could not delete the canary space", true},
+		{"import and deletion failed", "", http.StatusBadRequest, http.StatusInternalServerError, "import into the canary space failed", true},
+		{"target space", "kibctl-canary", http.StatusOK, http.StatusNoContent, "is the target space", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mu sync.Mutex
+			created, deleted := false, false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case r.Method == "POST" && r.URL.Path == "/api/spaces/space":
+					created = true
+					w.Write([]byte(`{}`))
+				case r.Method == "POST" && r.URL.Path == "/s/kibctl-canary/api/kibana/dashboards/import":
+					w.WriteHeader(test.importStatus)
+					w.Write([]byte(`{"objects":[]}`))
+				case r.Method == "DELETE" && r.URL.Path == "/api/spaces/space/kibctl-canary":
+					deleted = true
+					w.WriteHeader(test.deleteStatus)
+				default:
+					t.Errorf("unexpected request %v %v", r.Method, r.URL)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			c := newTestClient(server)
+			c.Space = test.space
+			_, err := c.canaryImport(payload, "kibctl-canary")
+			switch {
+			case test.want == "" && err != nil:
+				t.Errorf("canaryImport() error: %v", err)
+			case test.want != "" && (err == nil || !strings.Contains(err.Error(), test.want)):
+				t.Errorf("canaryImport() error = %v, want %v", err, test.want)
+			}
+			if deleted != test.deleted || created != test.deleted {
+				t.Errorf("canary space created %v and deleted %v, want %v", created, deleted, test.deleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package kibctl
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+// kibanaApplication is the elasticsearch application the kibana privileges
+// are registered under, named after the default kibana index.
+const kibanaApplication = "kibana-.kibana"
+
+// savedObjectActions are the saved object operations each kibctl verb
+// performs.
+var savedObjectActions = map[string][]string{
+	"get":    {"get", "bulk_get"},
+	"list":   {"find"},
+	"export": {"find", "bulk_get"},
+	"import": {"bulk_create", "create"},
+	"create": {"create"},
+	"update": {"update", "bulk_update"},
+	"delete": {"delete"},
+}
+
+// elasticsearch sends the request to elasticsearch through the console proxy
+// of kibana, with the credentials of the user.
+func (c *client) elasticsearch(method, path string, payload interface{}) ([]byte, error) {
+	if c.Flavor == "opensearch" {
+		return nil, errors.Errorf("elasticsearch requests are not supported with opensearch dashboards.\n")
+	}
+	query := url.Values{"path": {path}, "method": {method}}
+	return c.jsonRequest("POST", "/api/console/proxy?"+query.Encode(), payload)
+}
+
+// privilegeActions returns the kibana actions the verb requires on the type.
+// Importing dashboards also writes the objects they display and the data views
+// of these.
+func privilegeActions(verb, objectType string) ([]string, error) {
+	operations, ok := savedObjectActions[verb]
+	if !ok {
+		verbs := make([]string, 0, len(savedObjectActions))
+		for v := range savedObjectActions {
+			verbs = append(verbs, v)
+		}
+		sort.Strings(verbs)
+		return nil, errors.Errorf("unknown verb %v, one of %v expected.\n", verb, strings.Join(verbs, ", "))
+	}
+	types := []string{objectType}
+	if objectType == "dashboard" && verb == "import" {
+		types = savedObjectTypes
+	}
+	var actions []string
+	for _, t := range types {
+		for _, operation := range operations {
+			actions = append(actions, fmt.Sprintf("saved_object:%v/%v", t, operation))
+		}
+	}
+	return actions, nil
+}
+
+// missingPrivileges returns the actions the user lacks in the space of the
+// client, checked with the has privileges api of elasticsearch.
+func (c *client) missingPrivileges(application string, actions []string) ([]string, error) {
+	resource := "space:" + spaceID(c.Space)
+	body, err := c.elasticsearch("POST", "_security/user/_has_privileges", map[string]interface{}{
+		"application": []interface{}{
+			map[string]interface{}{"application": application, "resources": []string{resource}, "privileges": actions},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	granted := gjson.GetBytes(body, "application."+escapeGJSONKey(application)+"."+escapeGJSONKey(resource))
+	if !granted.Exists() {
+		return nil, errors.Errorf("unexpected has privileges response: %v.\n", string(body))
+	}
+	var missing []string
+	for _, action := range actions {
+		if !granted.Get(escapeGJSONKey(action)).Bool() {
+			missing = append(missing, action)
+		}
+	}
+	return missing, nil
+}
+
+// escapeGJSONKey escapes the characters of a key which gjson paths interpret.
+func escapeGJSONKey(key string) string {
+	var b strings.Builder
+	for _, r := range key {
+		if strings.ContainsRune(`.*?|#@\`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func authCanI(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	if c.NArg() != 2 {
+		return cli.NewExitError("verb and saved object type expected, e.g. can-i import dashboard", 1)
+	}
+	actions, err := privilegeActions(c.Args().Get(0), c.Args().Get(1))
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	client := newClient()
+	missing, err := client.missingPrivileges(c.String("application"), actions)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if len(missing) > 0 {
+		if !quiet {
+			fmt.Fprintf(os.Stdout, "no, missing in %v:\n  %v\n", client.spaceName(), strings.Join(missing, "\n  "))
+		}
+		return cli.NewExitError("", 3)
+	}
+	if !quiet {
+		fmt.Fprintln(os.Stdout, "yes")
+	}
+	return nil
+}
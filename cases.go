@@ -0,0 +1,210 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+type kibanaCase struct {
+	ID          string   `json:"id"`
+	Version     string   `json:"version"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Status      string   `json:"status"`
+	Tags        []string `json:"tags"`
+	Owner       string   `json:"owner"`
+}
+
+func (c *client) listCases(status string) ([]kibanaCase, error) {
+	var cases []kibanaCase
+	for page := 1; ; page++ {
+		query := url.Values{}
+		if status != "" {
+			query.Set("status", status)
+		}
+		query.Set("per_page", "100")
+		query.Set("page", fmt.Sprint(page))
+		body, err := c.jsonRequest("GET", "/api/cases/_find?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Total int          `json:"total"`
+			Cases []kibanaCase `json:"cases"`
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, errors.Wrap(err, "could not parse cases")
+		}
+		cases = append(cases, result.Cases...)
+		if len(result.Cases) == 0 || len(cases) >= result.Total {
+			return cases, nil
+		}
+	}
+}
+
+func (c *client) getCase(id string) (kibanaCase, error) {
+	body, err := c.jsonRequest("GET", "/api/cases/"+url.PathEscape(id), nil)
+	if err != nil {
+		return kibanaCase{}, err
+	}
+	var kc kibanaCase
+	if err := json.Unmarshal(body, &kc); err != nil {
+		return kibanaCase{}, errors.Wrap(err, "could not parse case")
+	}
+	return kc, nil
+}
+
+func (c *client) createCase(kc kibanaCase) (kibanaCase, error) {
+	payload := map[string]interface{}{
+		"title":       kc.Title,
+		"description": kc.Description,
+		"tags":        kc.Tags,
+		"owner":       kc.Owner,
+		"connector":   map[string]interface{}{"id": "none", "name": "none", "type": ".none", "fields": nil},
+		"settings":    map[string]interface{}{"syncAlerts": false},
+	}
+	if kc.Tags == nil {
+		payload["tags"] = []string{}
+	}
+	body, err := c.jsonRequest("POST", "/api/cases", payload)
+	if err != nil {
+		return kibanaCase{}, err
+	}
+	var created kibanaCase
+	if err := json.Unmarshal(body, &created); err != nil {
+		return kibanaCase{}, errors.Wrap(err, "could not parse case")
+	}
+	return created, nil
+}
+
+// updateCase applies the changed fields to the case, at its current version.
+func (c *client) updateCase(id string, changes map[string]interface{}) error {
+	current, err := c.getCase(id)
+	if err != nil {
+		return err
+	}
+	changes["id"] = id
+	changes["version"] = current.Version
+	_, err = c.jsonRequest("PATCH", "/api/cases", map[string]interface{}{"cases": []interface{}{changes}})
+	return err
+}
+
+func (c *client) commentCase(kc kibanaCase, comment string) error {
+	payload := map[string]interface{}{"type": "user", "comment": comment, "owner": kc.Owner}
+	_, err := c.jsonRequest("POST", fmt.Sprintf("/api/cases/%v/comments", url.PathEscape(kc.ID)), payload)
+	return err
+}
+
+func caseList(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	cases, err := newClient().listCases(c.String("status"))
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if quiet {
+		for _, kc := range cases {
+			printIDs(kc.ID)
+		}
+		return nil
+	}
+	os.Stdout.WriteString(stdout.header(fmt.Sprintf("%-40v %-12v %v", "ID", "STATUS", "TITLE")) + "\n")
+	for _, kc := range cases {
+		fmt.Fprintf(os.Stdout, "%v %-12v %v\n", stdout.paint(cyan, fmt.Sprintf("%-40v", kc.ID)), kc.Status, kc.Title)
+	}
+	return nil
+}
+
+func caseCreate(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	title := c.Args().First()
+	if title == "" {
+		return cli.NewExitError("case title missing", 1)
+	}
+	client := newClient()
+	var links []string
+	for _, name := range c.StringSlice("dashboard") {
+		found, err := client.findDashboard(name)
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		links = append(links, fmt.Sprintf("[%v](%v)", found.Attributes.Title, client.dashboardURL(found.ID)))
+	}
+
+	description := c.String("description")
+	if description == "" {
+		// kibana requires a description
+		description = title
+	}
+	created, err := client.createCase(kibanaCase{
+		Title:       title,
+		Description: description,
+		Tags:        c.StringSlice("tag"),
+		Owner:       c.String("owner"),
+	})
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if len(links) > 0 {
+		if err := client.commentCase(created, "Dashboards:\n"+strings.Join(links, "\n")); err != nil {
+			return cli.NewExitError(errors.Wrapf(err, "case %v created but dashboard links not attached", created.ID), 2)
+		}
+	}
+	if quiet {
+		printIDs(created.ID)
+		return nil
+	}
+	fmt.Fprintf(os.Stdout, "created case %v\n", created.ID)
+	return nil
+}
+
+func caseUpdate(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	id := c.Args().First()
+	if id == "" {
+		return cli.NewExitError("case id missing", 1)
+	}
+	changes := make(map[string]interface{})
+	for _, field := range []string{"title", "description", "status"} {
+		if c.IsSet(field) {
+			changes[field] = c.String(field)
+		}
+	}
+	if c.IsSet("tag") {
+		changes["tags"] = c.StringSlice("tag")
+	}
+	if len(changes) == 0 {
+		return cli.NewExitError("nothing to update", 1)
+	}
+	if err := newClient().updateCase(id, changes); err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	printIDs(id)
+	return nil
+}
+
+func caseClose(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	id := c.Args().First()
+	if id == "" {
+		return cli.NewExitError("case id missing", 1)
+	}
+	if err := newClient().updateCase(id, map[string]interface{}{"status": "closed"}); err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	printIDs(id)
+	return nil
+}
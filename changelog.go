@@ -0,0 +1,184 @@
+package kibctl
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+// dashboardChange is the changelog entry of a dashboard.
+type dashboardChange struct {
+	Title         string
+	AddedPanels   []string
+	RemovedPanels []string
+	// UpdatedPanels are the panels whose visualization changed
+	UpdatedPanels []string
+	// Updated tells the dashboard itself changed beyond its panels, e.g. its
+	// query or options
+	Updated bool
+}
+
+type changelog struct {
+	Added   []string
+	Removed []string
+	Changed []dashboardChange
+	// Others counts the changes of the objects which are not shown by a
+	// dashboard, by status and type
+	Others map[string]int
+}
+
+// buildChangelog groups the differences of the exports by dashboard, the
+// changed visualizations being reported as updated panels of the dashboards
+// displaying them.
+func buildChangelog(after []byte, diffs []objectDiff) changelog {
+	result := changelog{Others: make(map[string]int)}
+	changed := make(map[string]objectDiff)
+	for _, d := range diffs {
+		changed[d.Type+":"+d.ID] = d
+	}
+
+	shown := make(map[string]bool)
+	entries := make(map[string]*dashboardChange)
+	objects := exportObjects(after)
+	for _, dashboard := range gjson.GetBytes(after, `objects.#(type=="dashboard")#`).Array() {
+		entry := &dashboardChange{Title: dashboard.Get("attributes.title").String()}
+		labels := panelLabels(dashboard, objects)
+		refs := make(map[string]string)
+		for _, ref := range dashboard.Get("references").Array() {
+			refs[ref.Get("name").String()] = ref.Get("type").String() + ":" + ref.Get("id").String()
+		}
+		for i, panel := range gjson.Parse(dashboard.Get("attributes.panelsJSON").String()).Array() {
+			index := panel.Get("panelIndex").String()
+			if index == "" {
+				index = fmt.Sprint(i)
+			}
+			key, ok := refs[panel.Get("panelRefName").String()]
+			if !ok {
+				key = panel.Get("type").String() + ":" + panel.Get("id").String()
+			}
+			shown[key] = true
+			if d, ok := changed[key]; ok && d.Status == diffChanged {
+				entry.UpdatedPanels = append(entry.UpdatedPanels, labels[index])
+			}
+		}
+		entries[dashboard.Get("type").String()+":"+dashboard.Get("id").String()] = entry
+	}
+
+	for _, d := range diffs {
+		key := d.Type + ":" + d.ID
+		switch {
+		case d.Type == "dashboard" && d.Status == diffAdded:
+			result.Added = append(result.Added, d.Title)
+		case d.Type == "dashboard" && d.Status == diffRemoved:
+			result.Removed = append(result.Removed, d.Title)
+		case d.Type == "dashboard":
+			entry := entries[key]
+			entry.AddedPanels, entry.RemovedPanels = d.AddedPanels, d.RemovedPanels
+			entry.Updated = len(d.AddedPanels) == 0 && len(d.RemovedPanels) == 0
+		case !shown[key]:
+			result.Others[d.Status+" "+d.Type]++
+		}
+	}
+	for _, entry := range entries {
+		if entry.Updated || len(entry.AddedPanels)+len(entry.RemovedPanels)+len(entry.UpdatedPanels) > 0 {
+			result.Changed = append(result.Changed, *entry)
+		}
+	}
+	sort.Strings(result.Added)
+	sort.Strings(result.Removed)
+	sort.Slice(result.Changed, func(i, j int) bool { return result.Changed[i].Title < result.Changed[j].Title })
+	return result
+}
+
+func writeChangelog(w io.Writer, result changelog, title string, markdown bool) {
+	heading, item, escape := "", "- ", func(text string) string { return text }
+	if markdown {
+		heading, escape = "### ", markdownEscape
+		if title != "" {
+			fmt.Fprintf(w, "## %v\n\n", escape(title))
+		}
+	} else if title != "" {
+		fmt.Fprintf(w, "%v\n\n", title)
+	}
+	section := func(name string, titles []string) {
+		if len(titles) == 0 {
+			return
+		}
+		fmt.Fprintf(w, "%v%v\n\n", heading, name)
+		for _, t := range titles {
+			fmt.Fprintf(w, "%v%v\n", item, escape(t))
+		}
+		fmt.Fprintln(w)
+	}
+	section("New dashboards", result.Added)
+	if len(result.Changed) > 0 {
+		fmt.Fprintf(w, "%vUpdated dashboards\n\n", heading)
+		for _, d := range result.Changed {
+			fmt.Fprintf(w, "%v%v\n", item, escape(d.Title))
+			for _, panel := range d.AddedPanels {
+				fmt.Fprintf(w, "  %vadded panel %v\n", item, escape(panel))
+			}
+			for _, panel := range d.UpdatedPanels {
+				fmt.Fprintf(w, "  %vupdated panel %v\n", item, escape(panel))
+			}
+			for _, panel := range d.RemovedPanels {
+				fmt.Fprintf(w, "  %vremoved panel %v\n", item, escape(panel))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	section("Removed dashboards", result.Removed)
+	if len(result.Others) > 0 {
+		var others []string
+		for _, key := range sortedCountKeys(result.Others) {
+			parts := strings.SplitN(key, " ", 2)
+			others = append(others, fmt.Sprintf("%d %v %v", result.Others[key], parts[1], parts[0]))
+		}
+		section("Other objects", others)
+	}
+	if len(result.Added)+len(result.Changed)+len(result.Removed)+len(result.Others) == 0 {
+		fmt.Fprintln(w, "No changes.")
+	}
+}
+
+func sortedCountKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func changelogCommand(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return cli.NewExitError("old and new exports expected", 1)
+	}
+	format := c.String("format")
+	if format != "text" && format != "markdown" {
+		return cli.NewExitError(fmt.Sprintf("unsupported changelog format %v", format), 1)
+	}
+	before, err := readBundle(c.Args().Get(0))
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	after, err := readBundle(c.Args().Get(1))
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	diffs, err := diffExports(before, after)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	normalized, err := normalizeExport(after)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	writeChangelog(os.Stdout, buildChangelog(normalized, diffs), c.String("title"), format == "markdown")
+	return nil
+}
@@ -0,0 +1,104 @@
+package kibctl
+
+import (
+	"net/http"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// dependencyOrder sorts the objects so that every object comes after the
+// objects of the payload it refers to, keeping the payload order otherwise.
+// Reference cycles, e.g. dashboards drilling down to each other, are broken
+// at the first object of the cycle.
+func dependencyOrder(objects []gjson.Result) []gjson.Result {
+	index := make(map[string]int)
+	for i, object := range objects {
+		index[object.Get("type").String()+":"+object.Get("id").String()] = i
+	}
+	visited := make([]bool, len(objects))
+	var ordered []gjson.Result
+	var visit func(i int)
+	visit = func(i int) {
+		if visited[i] {
+			return
+		}
+		visited[i] = true
+		for _, dep := range objectDependencies(objects[i]) {
+			if j, ok := index[dep.Type+":"+dep.ID]; ok {
+				visit(j)
+			}
+		}
+		ordered = append(ordered, objects[i])
+	}
+	for i := range objects {
+		visit(i)
+	}
+	return ordered
+}
+
+// chunkObjects groups the objects, in dependency order, into chunks whose
+// encoding stays under the size, a single object larger than the size making
+// a chunk of its own.
+func chunkObjects(objects []gjson.Result, size int) [][]gjson.Result {
+	var chunks [][]gjson.Result
+	var chunk []gjson.Result
+	chunkSize := 0
+	for _, object := range dependencyOrder(objects) {
+		if len(chunk) > 0 && chunkSize+len(object.Raw) > size {
+			chunks = append(chunks, chunk)
+			chunk, chunkSize = nil, 0
+		}
+		chunk = append(chunk, object)
+		chunkSize += len(object.Raw) + 1
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// withObjects returns the payload with its objects replaced.
+func withObjects(payload []byte, objects []gjson.Result) ([]byte, error) {
+	raw := make([]byte, 0, len(payload))
+	raw = append(raw, '[')
+	for i, object := range objects {
+		if i > 0 {
+			raw = append(raw, ',')
+		}
+		raw = append(raw, object.Raw...)
+	}
+	raw = append(raw, ']')
+	return sjson.SetRawBytes(payload, "objects", raw)
+}
+
+// importChunks imports the payload in chunks of the client chunk size, and
+// splits in halves again the chunks kibana rejects as too large.
+func (c *client) importChunks(payload []byte) error {
+	objects := gjson.GetBytes(payload, "objects").Array()
+	chunks := [][]gjson.Result{dependencyOrder(objects)}
+	if c.ChunkSize > 0 && len(payload) > c.ChunkSize {
+		chunks = chunkObjects(objects, c.ChunkSize)
+	}
+	for len(chunks) > 0 {
+		chunk := chunks[0]
+		chunks = chunks[1:]
+		body, err := withObjects(payload, chunk)
+		if err != nil {
+			return err
+		}
+		if len(chunk) < len(objects) {
+			c.Logger.Printf("importing chunk of %d objects, %d bytes\n", len(chunk), len(body))
+		}
+		err = c.importPayload(body)
+		if apiErr, ok := err.(*apiError); ok && apiErr.StatusCode == http.StatusRequestEntityTooLarge && len(chunk) > 1 {
+			c.Logger.Printf("chunk of %d bytes too large, splitting it\n", len(body))
+			chunks = append([][]gjson.Result{chunk[:len(chunk)/2], chunk[len(chunk)/2:]}, chunks...)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,12 +1,18 @@
-package main
+package kibctl
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"os"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/tidwall/gjson"
@@ -22,52 +28,316 @@ type client struct {
 	Host     string
 	Username string
 	Password string
+	// APIKey authenticates the requests instead of the basic auth credentials when set
+	APIKey string
+	// CredentialSource is the vault:// or aws-sm:// secret the credentials
+	// are fetched from when the first request is about to be sent, so that
+	// the commands sending none never reach the secret store
+	CredentialSource string
+	// Auth is the authentication scheme: basic, or sigv4 to sign the requests
+	// for Amazon OpenSearch Service with the ambient aws credentials
+	Auth string
+	// AWSRegion is the region the sigv4 signatures are scoped to
+	AWSRegion string
+	// Compat is the targeted Kibana major version: 6, 7 or auto
+	Compat string
+	// Flavor is the targeted dashboards product: kibana or opensearch
+	Flavor string
+	// Tenant selects the security tenant on multi-tenant setups
+	Tenant string
+	// Space is the kibana space the requests apply to, the default space when empty
+	Space string
+	// CacheTTL enables the on-disk cache of searches and status when positive
+	CacheTTL time.Duration
+	// DepMatch is how the index-patterns referred to by name are matched:
+	// id, title-exact or title-fuzzy
+	DepMatch string
+	// SkipMissingDeps records the index-patterns missing from kibana in the
+	// missingReferences of the exports instead of failing
+	SkipMissingDeps bool
+	// ChunkSize is the size in bytes past which the imports are split into
+	// several requests, imported in dependency order; no limit when 0
+	ChunkSize int
+	// Compress gzips the large import payloads, falling back to plain ones
+	// when the server does not support compressed requests
+	Compress bool
+	// MaxResponseSize is the size in bytes past which a response read in
+	// memory is rejected, unlimited when 0
+	MaxResponseSize int64
+	// ReadOnly refuses the requests which may write, see checkReadOnly
+	ReadOnly bool
+	// Stderr receives the warnings, os.Stderr unless --quiet when nil
+	Stderr io.Writer
 	Logger
+
+	// ctx cancels the requests when set, for the callers of the go api
+	ctx context.Context
+
+	detected         int
+	compressRejected bool
+	awsCredentials   *awsCredentials
+	// credentialsFetched is set once the CredentialSource was read
+	credentialsFetched bool
+}
+
+// warnf prints the warning to the Stderr of the client.
+func (c *client) warnf(format string, v ...interface{}) {
+	switch {
+	case c.Stderr != nil:
+		fmt.Fprintf(c.Stderr, "warning: "+format, v...)
+	case !quiet:
+		fmt.Fprintf(os.Stderr, "warning: "+format, v...)
+	}
+}
+
+// newRequest prepares a request against the api path of the client space with
+// the authentication, tenant and xsrf headers expected by the targeted flavor.
+// Bodies are sent as json unless the caller sets another content type.
+func (c *client) newRequest(method, path string, body io.Reader) (*http.Request, error) {
+	if err := c.checkReadOnly(method, path); err != nil {
+		return nil, err
+	}
+	internal := strings.HasPrefix(path, "/internal/")
+	if c.Space != "" && c.Space != "default" {
+		path = "/s/" + c.Space + path
+	}
+	req, err := http.NewRequest(method, c.Host+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.ctx != nil {
+		req = req.WithContext(c.ctx)
+	}
+	if method != "GET" && method != "HEAD" {
+		if c.Flavor == "opensearch" {
+			req.Header.Set("osd-xsrf", "true")
+		} else {
+			req.Header.Set("kbn-xsrf", "true")
+		}
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if internal {
+		// kibana 8 restricts its internal apis to the requests declaring their origin
+		req.Header.Set("x-elastic-internal-origin", "kibctl")
+	}
+	if c.Tenant != "" {
+		req.Header.Set("securitytenant", c.Tenant)
+	}
+	if err := c.authenticate(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// fetchCredentials reads the credentials of the CredentialSource once, the
+// username of the secret being used when the client has none.
+func (c *client) fetchCredentials() error {
+	if c.CredentialSource == "" || c.credentialsFetched {
+		return nil
+	}
+	creds, err := fetchCredentials(c.CredentialSource)
+	if err != nil {
+		return errors.Wrapf(err, "could not fetch the credentials of %v", c.CredentialSource)
+	}
+	if c.Username == "" {
+		c.Username = creds.Username
+	}
+	c.Password, c.APIKey = creds.Password, creds.APIKey
+	c.credentialsFetched = true
+	return nil
+}
+
+// authenticate sets the credentials of the client on the request, fetching
+// them first from the credential source.
+func (c *client) authenticate(req *http.Request) error {
+	if err := c.fetchCredentials(); err != nil {
+		return err
+	}
+	switch {
+	case c.Auth == "sigv4":
+		// signed when sent, once all the headers are set
+	case c.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+c.APIKey)
+	default:
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	return nil
+}
+
+// do sends the request, through the cache when enabled. The response body is
+// left to the caller to stream, doRequest reading it in memory.
+func (c *client) do(req *http.Request) (*http.Response, error) {
+	return c.send(req)
+}
+
+// jsonRequest sends the json payload, when given, to the api path and returns
+// the response body of a successful request.
+func (c *client) jsonRequest(method, path string, payload interface{}) ([]byte, error) {
+	var body io.Reader
+	if payload != nil {
+		content, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewBuffer(content)
+	}
+	req, err := c.newRequest(method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, details, err := c.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, c.apiError(fmt.Sprintf("%v %v", method, path), resp, details)
+	}
+	return details, nil
+}
+
+// doRequest sends the request and returns the response along with its body.
+// The body is always closed, and drained first so that the connection can be
+// reused by the next request.
+func (c *client) doRequest(req *http.Request) (*http.Response, []byte, error) {
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		io.Copy(ioutil.Discard, io.LimitReader(resp.Body, drainLimit))
+		resp.Body.Close()
+	}()
+	body, err := readBody(req, resp, c.MaxResponseSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, body, nil
+}
+
+// drainLimit bounds the unread bytes discarded before closing a body, past it
+// closing the connection is cheaper than reading on.
+const drainLimit = 64 << 10
+
+func (c *client) send(req *http.Request) (*http.Response, error) {
+	if c.CacheTTL > 0 && cacheable(req) {
+		return c.cachedDo(req, c.CacheTTL)
+	}
+	if c.CacheTTL > 0 && revalidatable(req) {
+		return c.cachedDo(req, 0)
+	}
+	return c.roundTrip(req)
+}
+
+// roundTrip sends the request to kibana, signing it first with sigv4.
+func (c *client) roundTrip(req *http.Request) (*http.Response, error) {
+	if c.Auth == "sigv4" {
+		if err := c.signRequest(req); err != nil {
+			return nil, err
+		}
+	}
+	return http.DefaultClient.Do(req)
+}
+
+// dashboardsAPI returns the base path of the legacy dashboards import/export api.
+func (c *client) dashboardsAPI() string {
+	if c.Flavor == "opensearch" {
+		return "/api/opensearch-dashboards/dashboards"
+	}
+	return "/api/kibana/dashboards"
+}
+
+// dashboardURL returns the link opening the dashboard in the client space.
+func (c *client) dashboardURL(id string) string {
+	prefix := c.Host
+	if c.Space != "" && c.Space != "default" {
+		prefix += "/s/" + c.Space
+	}
+	return prefix + "/app/dashboards#/view/" + url.PathEscape(id)
 }
 
 func (c *client) _import(payload []byte) error {
+	// the placeholders of the missing dependencies are not part of the import api
+	payload, err := sjson.DeleteBytes(payload, "missingReferences")
+	if err != nil {
+		return err
+	}
+	return c.importChunks(payload)
+}
+
+// importPayload sends the payload to the dashboard import api in a single
+// request.
+func (c *client) importPayload(payload []byte) error {
 	c.Logger.Printf("importing dashboard:\n%v\n", string(payload))
-	u := fmt.Sprintf(`%v/api/kibana/dashboards/import?force=true`, c.Host)
-	req, err := http.NewRequest("POST", u, bytes.NewBuffer(payload))
+	body := payload
+	compressed := c.Compress && !c.compressRejected && len(payload) > compressThreshold
+	if compressed {
+		var err error
+		if body, err = gzipPayload(payload); err != nil {
+			return err
+		}
+		c.Logger.Printf("compressed payload of %d bytes to %d bytes\n", len(payload), len(body))
+	}
+	req, err := c.newRequest("POST", c.dashboardsAPI()+"/import?force=true", bytes.NewBuffer(body))
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("kbn-xsrf", "true")
-	req.SetBasicAuth(c.Username, c.Password)
-	resp, err := http.DefaultClient.Do(req)
+	if compressed {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	resp, details, err := c.doRequest(req)
 	if err != nil {
 		return err
 	}
-	details, _ := ioutil.ReadAll(resp.Body)
+	if compressed && resp.StatusCode == http.StatusUnsupportedMediaType {
+		c.Logger.Printf("compressed payloads not supported, sending them uncompressed\n")
+		c.compressRejected = true
+		return c.importPayload(payload)
+	}
 	if resp.StatusCode != http.StatusOK {
-		return errors.Errorf("failed to import dashboard. Status:%v. Response:%v.\n", resp.Status, string(details))
+		return c.apiError("import dashboard", resp, details)
 	}
 	c.Logger.Printf("SUCCESS\n%v\n", string(details))
 	return nil
 }
 
-func (c *client) export(name string) ([]byte, error) {
+// findDashboard returns the single dashboard matching the name.
+func (c *client) findDashboard(name string) (dashboard, error) {
 	c.Logger.Printf("searching dashboards matching name %v\n", name)
 	result, err := c.searchDashboard(fmt.Sprintf(`"%v"`, name))
 	if err != nil {
-		return nil, err
+		return dashboard{}, err
 	}
 	if len(result) == 0 {
-		return nil, errors.Errorf("no dashboard found matching: %v.\n", name)
+		return dashboard{}, errors.Errorf("no dashboard found matching: %v.\n", name)
 	}
 	if len(result) > 1 {
-		return nil, errors.Errorf("more than one dashboard found matching: %v.\n", name)
+		return dashboard{}, errors.Errorf("more than one dashboard found matching: %v.\n", name)
 	}
 	c.Logger.Printf("found dashboard id %v", result[0].ID)
+	return result[0], nil
+}
 
+func (c *client) export(name string) ([]byte, error) {
+	found, err := c.findDashboard(name)
+	if err != nil {
+		return nil, err
+	}
+	return c.exportDashboards(found.ID)
+}
+
+// exportDashboards exports the dashboards as a single bundle including the
+// visualisation and index-pattern dependencies of all of them.
+func (c *client) exportDashboards(ids ...string) ([]byte, error) {
 	c.Logger.Printf("retrieving partial dashboard export from api...\n")
-	dashboard, err := c.getDashboard(result[0].ID)
+	dashboard, err := c.getDashboard(ids...)
 	if err != nil {
 		return nil, err
 	}
 
-	indiceNames, err := c.scanForIndexPatterns(dashboard)
+	indiceNames, indiceIDs, err := c.scanForIndexPatterns(dashboard)
 	if err != nil {
 		return nil, err
 	}
@@ -75,7 +345,10 @@ func (c *client) export(name string) ([]byte, error) {
 	for _, name := range indiceNames {
 		indexPattern, err := c.getIndexPattern(name)
 		if err != nil {
-			return nil, err
+			if dashboard, err = c.skipMissing(dashboard, err); err != nil {
+				return nil, err
+			}
+			continue
 		}
 		c.Logger.Printf("adding index-template %v", name)
 		//element order does not matter
@@ -83,6 +356,47 @@ func (c *client) export(name string) ([]byte, error) {
 
 	}
 
+	for _, object := range gjson.GetBytes(dashboard, "objects").Array() {
+		indiceIDs = append(indiceIDs, controlDataViews(object.Get("attributes"))...)
+	}
+	dashboard, err = c.includeIndexPatterns(dashboard, indiceIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// timelion queries elasticsearch indices which may have no index-pattern
+	for _, val := range gjson.GetBytes(dashboard, "objects.#.attributes.visState").Array() {
+		for _, name := range timelionIndices(val.String()) {
+			patterns, err := c.lookupIndexPatterns(name)
+			if err != nil {
+				return nil, err
+			}
+			var ids []string
+			for _, pattern := range patterns {
+				if pattern.Get("attributes.title").String() == name {
+					ids = append(ids, pattern.Get("id").String())
+				}
+			}
+			if len(ids) != 1 {
+				c.Logger.Printf("skipping timelion index %v without a single matching index-pattern", name)
+				continue
+			}
+			dashboard, err = c.includeIndexPatterns(dashboard, ids)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	major, err := c.major()
+	if err != nil {
+		return nil, err
+	}
+	if major == 6 {
+		c.Logger.Printf("migrating 6.x export\n")
+		return c.migrateLegacyExport(dashboard)
+	}
+
 	return dashboard, nil
 }
 
@@ -95,27 +409,50 @@ type attributes struct {
 	Title string `json:"title"`
 }
 
-func (c *client) searchDashboard(pattern string) ([]dashboard, error) {
-	u := fmt.Sprintf(`%v/api/saved_objects/_find?type=dashboard&per_page=200&search_fields=title&search=%v`, c.Host, pattern)
-	req, err := http.NewRequest("GET", u, nil)
+// listDashboards returns the dashboards whose title matches the glob pattern,
+// all of them when the pattern is empty.
+func (c *client) listDashboards(pattern string) ([]dashboard, error) {
+	if pattern == "" {
+		pattern = "*"
+	}
+	re, err := globRegexp(pattern)
 	if err != nil {
 		return nil, err
 	}
-	req.SetBasicAuth(c.Username, c.Password)
-	resp, err := http.DefaultClient.Do(req)
+	return c.matchDashboards(globSearch(pattern), re)
+}
+
+// matchDashboards returns the dashboards found by the title search whose title
+// matches the regular expression. Kibana cannot search by regular expression so
+// an empty search retrieves every dashboard to match them locally.
+func (c *client) matchDashboards(search string, re *regexp.Regexp) ([]dashboard, error) {
+	objects, err := c.findObjects([]string{"dashboard"}, search)
 	if err != nil {
 		return nil, err
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		details, _ := ioutil.ReadAll(resp.Body)
-		return nil, errors.Errorf("failed to search dashboard name %v. Status:%v. Response:%v.\n", pattern, resp.Status, string(details))
+	var dashboards []dashboard
+	for _, o := range objects {
+		if re.MatchString(o.title()) {
+			dashboards = append(dashboards, dashboard{ID: o.ID, Attributes: attributes{Title: o.title()}})
+		}
 	}
+	return dashboards, nil
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
+func (c *client) searchDashboard(pattern string) ([]dashboard, error) {
+	u := fmt.Sprintf(`/api/saved_objects/_find?type=dashboard&per_page=200&search_fields=title&search=%v`, pattern)
+	req, err := c.newRequest("GET", u, nil)
 	if err != nil {
 		return nil, err
 	}
+	resp, body, err := c.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.apiError(fmt.Sprintf("search dashboard name %v", pattern), resp, body)
+	}
 
 	var dashboards []dashboard
 	for _, value := range gjson.Get(string(body), "saved_objects").Array() {
@@ -130,36 +467,48 @@ func (c *client) searchDashboard(pattern string) ([]dashboard, error) {
 	return dashboards, nil
 }
 
-func (c *client) getDashboard(id string) ([]byte, error) {
-	u := fmt.Sprintf("%v/api/kibana/dashboards/export?dashboard=%v", c.Host, id)
-	req, err := http.NewRequest("GET", u, nil)
+func (c *client) getDashboard(ids ...string) ([]byte, error) {
+	query := url.Values{}
+	for _, id := range ids {
+		query.Add("dashboard", id)
+	}
+	u := fmt.Sprintf("%v/export?%v", c.dashboardsAPI(), query.Encode())
+	req, err := c.newRequest("GET", u, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	req.SetBasicAuth(c.Username, c.Password)
-	resp, err := http.DefaultClient.Do(req)
+	resp, body, err := c.doRequest(req)
 	if err != nil {
 		return nil, err
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		details, _ := ioutil.ReadAll(resp.Body)
-		return nil, errors.Errorf("failed to retrieve dashboard id %v. Status:%v. Response:%v.\n", id, resp.Status, string(details))
+		return nil, c.apiError(fmt.Sprintf("retrieve dashboard id %v", strings.Join(ids, ",")), resp, body)
 	}
 
-	return ioutil.ReadAll(resp.Body)
+	return body, nil
 }
 
-func (c *client) scanForIndexPatterns(dashboard []byte) ([]string, error) {
+// scanForIndexPatterns returns the titles and ids of the index-patterns used by
+// the TSVB visualisations of the export which are not part of it yet.
+func (c *client) scanForIndexPatterns(dashboard []byte) ([]string, []string, error) {
+	included := make(map[string]struct{})
+	for _, val := range gjson.GetBytes(dashboard, `objects.#(type=="index-pattern")#.attributes.title`).Array() {
+		included[val.String()] = struct{}{}
+	}
+
 	names := make(map[string]struct{})
+	var ids []string
 	// scan all visualisations
 	for _, val := range gjson.Get(string(dashboard), "objects.#.attributes.visState").Array() {
-		visualisation := strings.Replace(val.String(), `\"`, `"`, -1)
-		index := gjson.Get(visualisation, "params.index_pattern")
-		if index.Exists() {
-			names[index.String()] = struct{}{}
+		titles, visIDs := tsvbIndexPatterns(val.String())
+		for _, title := range titles {
+			if _, ok := included[title]; !ok {
+				names[title] = struct{}{}
+			}
 		}
+		ids = append(ids, visIDs...)
 	}
 
 	list := make([]string, 0, len(names))
@@ -167,38 +516,61 @@ func (c *client) scanForIndexPatterns(dashboard []byte) ([]string, error) {
 		list = append(list, key)
 	}
 
-	return list, nil
+	return list, ids, nil
 }
 
+// getIndexPattern retrieves the index-pattern a visualisation refers to by
+// name, matched according to the dependency match strategy of the client:
+//   - id: the name is the id of the index-pattern, as for the fleet data views
+//   - title-exact: the title of the index-pattern is the name
+//   - title-fuzzy: the title search of the name finds a single index-pattern,
+//     or several of which a single one has the exact title
 func (c *client) getIndexPattern(name string) ([]byte, error) {
-	u := fmt.Sprintf(`%v/api/saved_objects/_find?type=index-pattern&search_fields=title&search="%v"`, c.Host, name)
-	req, err := http.NewRequest("GET", u, nil)
-	if err != nil {
-		return nil, err
+	if c.DepMatch == "id" {
+		return c.getIndexPatternByID(name)
 	}
-	req.SetBasicAuth(c.Username, c.Password)
-	resp, err := http.DefaultClient.Do(req)
+	patterns, err := c.lookupIndexPatterns(name)
 	if err != nil {
 		return nil, err
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		details, _ := ioutil.ReadAll(resp.Body)
-		return nil, errors.Errorf("failed to retrieve index-pattern title %v. Status:%v. Response: %v.\n", name, resp.Status, string(details))
+	var exact []gjson.Result
+	for _, pattern := range patterns {
+		if pattern.Get("attributes.title").String() == name {
+			exact = append(exact, pattern)
+		}
 	}
-
-	json, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	if c.DepMatch == "title-exact" || len(patterns) > 1 && len(exact) > 0 {
+		patterns = exact
 	}
-
-	patterns := gjson.Get(string(json), "saved_objects").Array()
 	if len(patterns) == 0 {
-		return nil, errors.Errorf("no index-pattern found matching: %v.\n", name)
+		return nil, missingError{dependency{Type: "index-pattern", Title: name}}
 	}
 	if len(patterns) > 1 {
-		return nil, errors.Errorf("More than one index-pattern found matching: %v.\n", name)
+		titles := make([]string, len(patterns))
+		for i, pattern := range patterns {
+			titles[i] = fmt.Sprintf("%v (%v)", pattern.Get("attributes.title").String(), pattern.Get("id").String())
+		}
+		return nil, errors.Errorf("More than one index-pattern found matching: %v: %v.\n", name, strings.Join(titles, ", "))
 	}
 
 	return []byte(patterns[0].String()), nil
 }
+
+// lookupIndexPatterns returns the index-patterns matching the title search.
+func (c *client) lookupIndexPatterns(name string) ([]gjson.Result, error) {
+	u := fmt.Sprintf(`/api/saved_objects/_find?type=index-pattern&search_fields=title&search="%v"`, name)
+	req, err := c.newRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, body, err := c.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.apiError(fmt.Sprintf("retrieve index-pattern title %v", name), resp, body)
+	}
+
+	return gjson.GetBytes(body, "saved_objects").Array(), nil
+}
@@ -0,0 +1,163 @@
+package kibctl
+
+import (
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+)
+
+// newTestClient returns a client of the test server, authenticated with an
+// api key and logging nothing.
+func newTestClient(server *httptest.Server) *client {
+	return &client{
+		Host:     server.URL,
+		APIKey:   "key",
+		Auth:     "basic",
+		Compat:   "7",
+		Flavor:   "kibana",
+		DepMatch: "title-fuzzy",
+		Logger:   &cmdLogger{Logger: log.New(ioutil.Discard, "", 0)},
+	}
+}
+
+// bodyTracker counts the response bodies opened and closed by the requests
+// of the default client.
+type bodyTracker struct {
+	sync.Mutex
+	transport      http.RoundTripper
+	opened, closed int
+}
+
+func (t *bodyTracker) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	t.Lock()
+	t.opened++
+	t.Unlock()
+	resp.Body = &trackedBody{ReadCloser: resp.Body, tracker: t}
+	return resp, nil
+}
+
+type trackedBody struct {
+	io.ReadCloser
+	tracker *bodyTracker
+	once    sync.Once
+}
+
+func (b *trackedBody) Close() error {
+	b.once.Do(func() {
+		b.tracker.Lock()
+		b.tracker.closed++
+		b.tracker.Unlock()
+	})
+	return b.ReadCloser.Close()
+}
+
+func TestDoRequestReleasesConnections(t *testing.T) {
+	var mu sync.Mutex
+	connections := 0
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/saved_objects/dashboard/found":
+			w.Write([]byte(`{"id":"found","type":"dashboard","attributes":{}}`))
+		case "/api/saved_objects/dashboard/missing":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"statusCode":404,"error":"Not Found"}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"statusCode":500,"error":"Internal Server Error"}`))
+		}
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			connections++
+			mu.Unlock()
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	tracker := &bodyTracker{transport: http.DefaultTransport}
+	defer func(transport http.RoundTripper) { http.DefaultClient.Transport = transport }(http.DefaultClient.Transport)
+	http.DefaultClient.Transport = tracker
+
+	c := newTestClient(server)
+	tests := []struct {
+		name    string
+		request func() error
+		wantErr bool
+	}{
+		{"ok", func() error { _, err := c.getObject("dashboard", "found"); return err }, false},
+		{"not found", func() error { _, err := c.getObject("dashboard", "missing"); return err }, false},
+		{"server error", func() error { _, err := c.getObject("dashboard", "broken"); return err }, true},
+		{"delete missing", func() error { return c.deleteObject("dashboard", "missing") }, false},
+		{"update failed", func() error { return c.updateObject("dashboard", "broken", "", "{}", "") }, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.request(); (err != nil) != test.wantErr {
+				t.Errorf("request error = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+
+	tracker.Lock()
+	defer tracker.Unlock()
+	if tracker.opened != len(tests) || tracker.closed != tracker.opened {
+		t.Errorf("closed %d of %d response bodies for %d requests", tracker.closed, tracker.opened, len(tests))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if connections != 1 {
+		t.Errorf("opened %d connections, want a single reused one", connections)
+	}
+}
+
+func TestFetchCredentialsOnFirstRequest(t *testing.T) {
+	var mu sync.Mutex
+	fetched := 0
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fetched++
+		mu.Unlock()
+		w.Write([]byte(`{"data":{"data":{"username":"elastic","password":"secret"}}}`))
+	}))
+	defer vault.Close()
+	defer os.Setenv("VAULT_ADDR", os.Getenv("VAULT_ADDR"))
+	defer os.Setenv("VAULT_TOKEN", os.Getenv("VAULT_TOKEN"))
+	os.Setenv("VAULT_ADDR", vault.URL)
+	os.Setenv("VAULT_TOKEN", "token")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, _ := r.BasicAuth(); user != "elastic" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"id":"d1","type":"dashboard","attributes":{}}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(server)
+	c.APIKey = ""
+	c.CredentialSource = "vault://secret/kibana"
+	if fetched != 0 {
+		t.Fatalf("credentials fetched %d times before any request", fetched)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := c.getObject("dashboard", "d1"); err != nil {
+			t.Fatalf("getObject() error: %v", err)
+		}
+	}
+	if fetched != 1 {
+		t.Errorf("credentials fetched %d times, want once", fetched)
+	}
+}
@@ -0,0 +1,8 @@
+// Command kibctl is a cli tool for kibana.
+package main
+
+import "github.com/lebaptiste/kibctl"
+
+func main() {
+	kibctl.Main()
+}
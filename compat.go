@@ -0,0 +1,198 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// major returns the Kibana major version targeted by the client, probing the
+// status api when the compat mode is auto.
+func (c *client) major() (int, error) {
+	if c.Flavor == "opensearch" {
+		// opensearch dashboards forked from kibana 7.10 and versions independently
+		return 7, nil
+	}
+	switch c.Compat {
+	case "6":
+		return 6, nil
+	case "auto":
+		if c.detected == 0 {
+			version, err := c.version()
+			if err != nil {
+				return 0, errors.Wrap(err, "could not detect kibana version")
+			}
+			c.detected, err = strconv.Atoi(strings.Split(version, ".")[0])
+			if err != nil {
+				return 0, errors.Errorf("unexpected kibana version %v.\n", version)
+			}
+			c.Logger.Printf("detected kibana version %v\n", version)
+		}
+		return c.detected, nil
+	}
+	return 7, nil
+}
+
+func (c *client) version() (string, error) {
+	req, err := c.newRequest("GET", "/api/status", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, body, err := c.doRequest(req)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", c.apiError("retrieve kibana status", resp, body)
+	}
+
+	version := gjson.GetBytes(body, "version.number")
+	if !version.Exists() {
+		return "", errors.Errorf("kibana status does not report a version.\n")
+	}
+	return version.String(), nil
+}
+
+// migrateLegacyExport reshapes a 6.x dashboard export so that it can be
+// imported into a 7.x instance.
+// 6.x objects carry no references array: index-patterns are linked by id from
+// the searchSourceJSON, so any of them missing from the export are fetched
+// individually. The numeric 6.x object versions are dropped since 7.x expects
+// opaque string versions.
+func (c *client) migrateLegacyExport(dashboard []byte) ([]byte, error) {
+	var ids []string
+	for _, val := range gjson.GetBytes(dashboard, "objects.#.attributes.kibanaSavedObjectMeta.searchSourceJSON").Array() {
+		if id := gjson.Get(val.String(), "index").String(); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	dashboard, err := c.includeIndexPatterns(dashboard, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, val := range gjson.GetBytes(dashboard, "objects").Array() {
+		if val.Get("version").Type != gjson.Number {
+			continue
+		}
+		dashboard, err = sjson.DeleteBytes(dashboard, fmt.Sprintf("objects.%d.version", i))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return dashboard, nil
+}
+
+// findLegacyObjects is the title search of kibana 6, whose find api has no
+// default_search_operator: the objects matching any of the terms are found
+// and the ones matching all of them kept.
+func (c *client) findLegacyObjects(types []string, query url.Values) ([]savedObject, error) {
+	search := query.Get("search")
+	query.Del("default_search_operator")
+	objects, err := c.find(types, query)
+	if err != nil {
+		return nil, err
+	}
+	var matching []savedObject
+	for _, o := range objects {
+		if matchesAllTerms(o.title(), search) {
+			matching = append(matching, o)
+		}
+	}
+	return matching, nil
+}
+
+// matchesAllTerms reports whether the title contains every term of the
+// search, the terms ending with * matching as prefixes of the title words.
+func matchesAllTerms(title, search string) bool {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, term := range strings.Fields(strings.ToLower(strings.Trim(search, `"`))) {
+		prefix := strings.HasSuffix(term, "*")
+		term = strings.Trim(term, `*"`)
+		found := term == ""
+		for _, word := range words {
+			found = found || word == term || prefix && strings.HasPrefix(word, term)
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// bulkGetResolved retrieves the saved objects in a single request for the
+// kibana versions without the bulk resolve api, every object found being an
+// exact match.
+func (c *client) bulkGetResolved(deps []dependency) ([]resolvedObject, error) {
+	var body []map[string]string
+	for _, dep := range deps {
+		body = append(body, map[string]string{"type": dep.Type, "id": dep.ID})
+	}
+	response, err := c.jsonRequest("POST", "/api/saved_objects/_bulk_get", body)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		SavedObjects []json.RawMessage `json:"saved_objects"`
+	}
+	if err := json.Unmarshal(response, &result); err != nil {
+		return nil, errors.Wrap(err, "could not parse saved objects")
+	}
+	if len(result.SavedObjects) != len(deps) {
+		return nil, errors.Errorf("expected %v saved objects, got %v.\n", len(deps), len(result.SavedObjects))
+	}
+	resolved := make([]resolvedObject, len(deps))
+	for i, object := range result.SavedObjects {
+		if err := json.Unmarshal(object, &resolved[i].SavedObject); err != nil {
+			return nil, errors.Wrap(err, "could not parse saved objects")
+		}
+		resolved[i].Outcome = "exactMatch"
+	}
+	return resolved, nil
+}
+
+// getIndexPatternByID retrieves the index-pattern, which may carry a new id when
+// the requested one is a legacy url alias.
+func (c *client) getIndexPatternByID(id string) ([]byte, error) {
+	indexPattern, outcome, err := c.resolveObject("index-pattern", id)
+	if err != nil {
+		return nil, err
+	}
+	if indexPattern == nil {
+		return nil, missingError{dependency{Type: "index-pattern", ID: id}}
+	}
+	if outcome == "aliasMatch" {
+		c.Logger.Printf("index-pattern id %v resolved to %v through its alias", id, gjson.GetBytes(indexPattern, "id").String())
+	}
+	return indexPattern, nil
+}
+
+// supportsManaged reports whether the kibana instance keeps the managed flag
+// of the imported objects, which it does from 8.10 on.
+func (c *client) supportsManaged() (bool, string, error) {
+	if c.Flavor == "opensearch" {
+		return false, "opensearch dashboards", nil
+	}
+	version, err := c.version()
+	if err != nil {
+		return false, "", err
+	}
+	parts := strings.SplitN(version, ".", 3)
+	major, _ := strconv.Atoi(parts[0])
+	minor := 0
+	if len(parts) > 1 {
+		minor, _ = strconv.Atoi(parts[1])
+	}
+	return major > 8 || major == 8 && minor >= 10, "kibana " + version, nil
+}
@@ -0,0 +1,96 @@
+package kibctl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMatchesAllTerms(t *testing.T) {
+	tests := []struct {
+		title  string
+		search string
+		want   bool
+	}{
+		{"Nginx Overview", "nginx", true},
+		{"Nginx Overview", "overview nginx", true},
+		{"Nginx Overview", "nginx errors", false},
+		{"Nginx Overview", "ngi*", true},
+		{"Nginx Overview", "ngi", false},
+		{"[Logs] Web Traffic", `"web traffic"`, true},
+		{"Web-Traffic", "traffic", true},
+		{"Anything", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.title+"/"+test.search, func(t *testing.T) {
+			if got := matchesAllTerms(test.title, test.search); got != test.want {
+				t.Errorf("matchesAllTerms(%q, %q) = %v, want %v", test.title, test.search, got, test.want)
+			}
+		})
+	}
+}
+
+func TestFindObjectsCompat(t *testing.T) {
+	tests := []struct {
+		compat string
+		want   []string
+	}{
+		{"7", []string{"d1", "d2"}},
+		{"6", []string{"d1"}},
+	}
+	for _, test := range tests {
+		t.Run(test.compat, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if operator := r.URL.Query().Get("default_search_operator"); (operator != "") != (test.compat != "6") {
+					t.Errorf("compat %v searched with default_search_operator %q", test.compat, operator)
+				}
+				w.Write([]byte(`{"total":2,"saved_objects":[{"type":"dashboard","id":"d1","attributes":{"title":"Nginx Errors"}},{"type":"dashboard","id":"d2","attributes":{"title":"Nginx Traffic"}}]}`))
+			}))
+			defer server.Close()
+			c := newTestClient(server)
+			c.Compat = test.compat
+
+			objects, err := c.findObjects([]string{"dashboard"}, "nginx errors")
+			if err != nil {
+				t.Fatalf("findObjects() error: %v", err)
+			}
+			var ids []string
+			for _, o := range objects {
+				ids = append(ids, o.ID)
+			}
+			if len(ids) != len(test.want) || ids[0] != test.want[0] {
+				t.Errorf("findObjects() = %v, want %v", ids, test.want)
+			}
+		})
+	}
+}
+
+func TestBulkResolveFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/saved_objects/_bulk_get":
+			w.Write([]byte(`{"saved_objects":[{"type":"index-pattern","id":"logs","attributes":{}},{"type":"index-pattern","id":"gone","error":{"statusCode":404,"message":"Not found"}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"statusCode":404,"error":"Not Found"}`))
+		}
+	}))
+	defer server.Close()
+
+	resolved, err := newTestClient(server).bulkResolve([]dependency{{Type: "index-pattern", ID: "logs"}, {Type: "index-pattern", ID: "gone"}})
+	if err != nil {
+		t.Fatalf("bulkResolve() error: %v", err)
+	}
+	tests := []struct {
+		id    string
+		found bool
+	}{
+		{"logs", true},
+		{"gone", false},
+	}
+	for i, test := range tests {
+		if r := resolved[i]; r.SavedObject.ID != test.id || r.found() != test.found || r.Outcome != "exactMatch" {
+			t.Errorf("resolved %d = %+v, want %v found %v", i, r, test.id, test.found)
+		}
+	}
+}
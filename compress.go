@@ -0,0 +1,22 @@
+package kibctl
+
+import (
+	"bytes"
+	"compress/gzip"
+)
+
+// compressThreshold is the size in bytes above which the import payloads are
+// gzipped when compression is enabled, smaller ones not being worth it.
+const compressThreshold = 64 << 10
+
+func gzipPayload(payload []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(payload); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
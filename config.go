@@ -0,0 +1,231 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+// config is the kibctl configuration file, defining named connection contexts.
+type config struct {
+	Contexts map[string]kibanaContext `json:"contexts"`
+	// AuditLog is the file the promotions are recorded to, ~/.kibctl/audit.log when empty
+	AuditLog string `json:"auditLog"`
+	// Types are the saved object types of third-party plugins handled by the
+	// generic commands along with the built-in ones
+	Types []string `json:"types"`
+	// Policy is the naming policy checked by lint and import --enforce-policy
+	Policy *namingPolicy `json:"policy"`
+	// Aliases are user-defined commands standing for kibctl arguments, e.g.
+	// "prod-list": "--context prod dashboard list"
+	Aliases map[string]string `json:"aliases"`
+	// Hooks are the commands and webhooks notified of the imports and applies
+	Hooks hooks `json:"hooks"`
+}
+
+// kibanaContext holds the connection settings of a kibana environment, empty
+// values fall back to the global flag defaults.
+type kibanaContext struct {
+	Host     string `json:"host"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	APIKey   string `json:"apiKey"`
+	// CredentialSource fetches the password or api key at runtime instead of
+	// storing it in the config: vault://PATH or aws-sm://NAME
+	CredentialSource string `json:"credentialSource"`
+	// Auth and AWSRegion select the sigv4 signing of Amazon OpenSearch Service
+	Auth      string `json:"auth"`
+	AWSRegion string `json:"awsRegion"`
+	Compat    string `json:"compat"`
+	Flavor    string `json:"flavor"`
+	Tenant    string `json:"tenant"`
+	Space     string `json:"space"`
+	// Output is the default output format of the context: text or jsonl
+	Output string `json:"output"`
+	// Defaults are the values of the command flags not given explicitly, by
+	// command path and flag name, e.g. {"dashboard data": {"parallel": "8"}}
+	Defaults map[string]map[string]string `json:"defaults"`
+}
+
+// commandDefaults are the command flag defaults of the selected context, by
+// command path.
+var commandDefaults map[string]map[string]string
+
+// credentialSource is the credential source of the selected context, fetched
+// by the client when it sends its first request.
+var credentialSource string
+
+func configDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "could not locate home directory")
+	}
+	return filepath.Join(home, ".kibctl"), nil
+}
+
+// configFile returns the path of the configuration file, overridden by the
+// KIBCTL_CONFIG environment variable.
+func configFile() (string, error) {
+	if file := os.Getenv("KIBCTL_CONFIG"); file != "" {
+		return file, nil
+	}
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.json"), nil
+}
+
+// loadConfig reads the configuration file, an empty configuration when it
+// does not exist.
+func loadConfig() (*config, error) {
+	file, err := configFile()
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return &config{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read config file")
+	}
+	var conf config
+	if err := json.Unmarshal(content, &conf); err != nil {
+		return nil, errors.Wrapf(err, "could not parse config file %v", file)
+	}
+	return &conf, nil
+}
+
+func (conf *config) context(name string) (kibanaContext, error) {
+	ctx, ok := conf.Contexts[name]
+	if !ok {
+		return kibanaContext{}, errors.Errorf("unknown context %v.\n", name)
+	}
+	return ctx, nil
+}
+
+// applyContext fills the connection globals which were not given as flag or
+// environment variable from the selected context.
+func applyContext(c *cli.Context, conf *config) error {
+	if contextName == "" {
+		return nil
+	}
+	ctx, err := conf.context(contextName)
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	// the secrets are only fetched when no credentials are given explicitly,
+	// by the client once a request is about to be sent
+	if !c.IsSet("password") && !c.IsSet("api-key") && !c.IsSet("password-file") && !c.IsSet("api-key-file") && !c.Bool("password-stdin") {
+		credentialSource = ctx.CredentialSource
+	}
+	settings := map[string]struct {
+		flag  *string
+		value string
+	}{
+		"host":       {&host, ctx.Host},
+		"username":   {&username, ctx.Username},
+		"password":   {&password, ctx.Password},
+		"api-key":    {&apiKey, ctx.APIKey},
+		"auth":       {&auth, ctx.Auth},
+		"aws-region": {&awsRegion, ctx.AWSRegion},
+		"compat":     {&compat, ctx.Compat},
+		"flavor":     {&flavor, ctx.Flavor},
+		"tenant":     {&tenant, ctx.Tenant},
+		"space":      {&space, ctx.Space},
+		"output":     {&output, ctx.Output},
+	}
+	for name, setting := range settings {
+		// flags given explicitly take precedence over the context
+		if setting.value != "" && !c.IsSet(name) {
+			*setting.flag = setting.value
+		}
+	}
+	commandDefaults = ctx.Defaults
+	return nil
+}
+
+// withCommandDefaults applies the flag defaults of the context to every
+// command before it runs, ahead of the command's own Before.
+func withCommandDefaults(commands []cli.Command, parent string) []cli.Command {
+	for i, command := range commands {
+		name := strings.TrimSpace(parent + " " + command.Name)
+		if len(command.Subcommands) > 0 {
+			commands[i].Subcommands = withCommandDefaults(command.Subcommands, name)
+			continue
+		}
+		before := command.Before
+		commands[i].Before = func(c *cli.Context) error {
+			if err := applyCommandDefaults(c, name); err != nil {
+				return err
+			}
+			if before != nil {
+				return before(c)
+			}
+			return nil
+		}
+	}
+	return commands
+}
+
+// applyCommandDefaults sets the flags of the command not given explicitly to
+// the defaults of the context for the command path.
+func applyCommandDefaults(c *cli.Context, command string) error {
+	defaults := commandDefaults[command]
+	for _, name := range c.FlagNames() {
+		value, ok := defaults[name]
+		if !ok || c.IsSet(name) {
+			continue
+		}
+		if err := c.Set(name, value); err != nil {
+			return cli.NewExitError(fmt.Sprintf("invalid default %v=%v of command %v in context %v: %v", name, value, command, contextName, err), 1)
+		}
+	}
+	return nil
+}
+
+// expandAlias returns the arguments with the user-defined alias the command
+// line runs replaced by its arguments, keeping the global flags given before
+// it and the arguments given after it. The args are the ones left after the
+// global flags, starting with the alias name.
+func expandAlias(conf *config, args []string) ([]string, bool) {
+	expansion, ok := conf.Aliases[args[0]]
+	if !ok {
+		return nil, false
+	}
+	globals := os.Args[:len(os.Args)-len(args)]
+	expanded := append(append([]string{}, globals...), strings.Fields(expansion)...)
+	return append(expanded, args[1:]...), true
+}
+
+// contextClient returns a client connected to the named context.
+func contextClient(conf *config, name string) (*client, error) {
+	ctx, err := conf.context(name)
+	if err != nil {
+		return nil, err
+	}
+	client := newClient()
+	client.Host, client.Username, client.Password = ctx.Host, ctx.Username, ctx.Password
+	client.APIKey, client.CredentialSource = ctx.APIKey, ctx.CredentialSource
+	if ctx.Auth != "" {
+		client.Auth, client.AWSRegion = ctx.Auth, ctx.AWSRegion
+	}
+	client.Tenant, client.Space = ctx.Tenant, ctx.Space
+	if ctx.Compat != "" {
+		client.Compat = ctx.Compat
+	}
+	if ctx.Flavor != "" {
+		client.Flavor = ctx.Flavor
+	}
+	if client.Host == "" {
+		return nil, errors.Errorf("context %v defines no host.\n", name)
+	}
+	return client, nil
+}
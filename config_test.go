@@ -0,0 +1,48 @@
+package kibctl
+
+import (
+	"testing"
+
+	"github.com/urfave/cli"
+)
+
+func TestWithCommandDefaultsChainsBefore(t *testing.T) {
+	var ran []string
+	before := func(name string) cli.BeforeFunc {
+		return func(c *cli.Context) error {
+			ran = append(ran, name)
+			return nil
+		}
+	}
+	commands := withCommandDefaults([]cli.Command{
+		{Name: "dashboard", Subcommands: []cli.Command{
+			{Name: "data", Before: before("dashboard data")},
+			{Name: "export"},
+		}},
+		{Name: "foreach", Before: before("foreach")},
+	}, "")
+
+	tests := []struct {
+		name    string
+		command cli.Command
+		want    string
+	}{
+		{"nested command", commands[0].Subcommands[0], "dashboard data"},
+		{"command without before", commands[0].Subcommands[1], ""},
+		{"top level command", commands[1], "foreach"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ran = nil
+			if test.command.Before == nil {
+				t.Fatal("command defaults not applied before the command")
+			}
+			if err := test.command.Before(&cli.Context{}); err != nil {
+				t.Fatalf("Before() error: %v", err)
+			}
+			if got := len(ran) > 0; got != (test.want != "") || (got && ran[0] != test.want) {
+				t.Errorf("Before() ran %v, want %q", ran, test.want)
+			}
+		})
+	}
+}
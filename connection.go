@@ -0,0 +1,71 @@
+package kibctl
+
+import (
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// offlineCommands work on local files only and take no connection flags.
+var offlineCommands = []string{"scrub", "normalize", "cache clear", "diff", "changelog", "lint", "plugin list", "promote", "foreach", "i18n extract", "i18n apply"}
+
+// connectionFlags may be given after the subcommand to override the global
+// connection settings for that command only, e.g. to export from one cluster
+// and import into another in a single pipeline.
+func connectionFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{Name: "host", Usage: "Kibana api endpoint, overriding the global one"},
+		cli.StringFlag{Name: "username", Usage: "Basic auth username, overriding the global one"},
+		cli.StringFlag{Name: "password", Usage: "Basic auth password, overriding the global one"},
+		cli.StringFlag{Name: "password-file", Usage: "read the basic auth password from `FILE`"},
+		cli.BoolFlag{Name: "password-stdin", Usage: "read the basic auth password from the first line of stdin"},
+		cli.StringFlag{Name: "api-key", Usage: "Kibana api key, overriding the global credentials"},
+		cli.StringFlag{Name: "api-key-file", Usage: "read the kibana api key from `FILE`"},
+		cli.StringFlag{Name: "auth", Usage: "authentication scheme: basic or sigv4"},
+		cli.StringFlag{Name: "aws-region", Usage: "aws region of the OpenSearch Service domain, with --auth sigv4"},
+		cli.StringFlag{Name: "tenant", Usage: "security tenant, overriding the global one"},
+		cli.StringFlag{Name: "space", Usage: "Kibana space, overriding the global one"},
+	}
+}
+
+// withConnectionFlags adds the connection flags to the commands talking to
+// kibana.
+func withConnectionFlags(commands []cli.Command, parent string) []cli.Command {
+	for i, command := range commands {
+		name := strings.TrimSpace(parent + " " + command.Name)
+		if len(command.Subcommands) > 0 {
+			commands[i].Subcommands = withConnectionFlags(command.Subcommands, name)
+			continue
+		}
+		if !contains(offlineCommands, name) {
+			commands[i].Flags = append(command.Flags, connectionFlags()...)
+		}
+	}
+	return commands
+}
+
+// applyCommandFlags overrides the global connection settings with the ones
+// given to the subcommand.
+func applyCommandFlags(c *cli.Context) error {
+	settings := map[string]*string{
+		"host":       &host,
+		"username":   &username,
+		"password":   &password,
+		"api-key":    &apiKey,
+		"auth":       &auth,
+		"aws-region": &awsRegion,
+		"tenant":     &tenant,
+		"space":      &space,
+	}
+	for name, flag := range settings {
+		if c.IsSet(name) {
+			*flag = c.String(name)
+		}
+	}
+	basicAuth := c.IsSet("username") || c.IsSet("password") || c.IsSet("password-file") || c.Bool("password-stdin")
+	if basicAuth && !c.IsSet("api-key") && !c.IsSet("api-key-file") {
+		// basic credentials given to the command replace a global api key
+		apiKey = ""
+	}
+	return applyCredentials(c)
+}
@@ -0,0 +1,59 @@
+package kibctl
+
+import (
+	"fmt"
+	"os"
+)
+
+// console colors the output of the commands when it goes to a terminal,
+// unless disabled with --no-color or the NO_COLOR environment variable.
+type console struct {
+	colored bool
+}
+
+type color string
+
+const (
+	bold   color = "1"
+	red    color = "31"
+	green  color = "32"
+	yellow color = "33"
+	cyan   color = "36"
+)
+
+var stdout = console{}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func newConsole(file *os.File, noColor bool) console {
+	_, noColorEnv := os.LookupEnv("NO_COLOR")
+	return console{colored: !noColor && !noColorEnv && isTerminal(file)}
+}
+
+// paint wraps the text in the color escape sequence. Pad the text before
+// painting it as the sequences would otherwise count in the column widths.
+func (c console) paint(col color, text string) string {
+	if !c.colored || text == "" {
+		return text
+	}
+	return "\x1b[" + string(col) + "m" + text + "\x1b[0m"
+}
+
+func (c console) header(text string) string {
+	return c.paint(bold, text)
+}
+
+// severity pads and colors a lint severity.
+func (c console) severity(severity string) string {
+	text := fmt.Sprintf("%-8v", severity)
+	switch severity {
+	case severityError:
+		return c.paint(red, text)
+	case severityWarning:
+		return c.paint(yellow, text)
+	}
+	return text
+}
@@ -0,0 +1,66 @@
+package kibctl
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+// readSecret reads a secret from the file, trimming the trailing newline left
+// by editors and by kubernetes secret mounts.
+func readSecret(file string) (string, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not read secret file %v", file)
+	}
+	secret := strings.TrimRight(string(content), "\r\n")
+	if secret == "" {
+		return "", errors.Errorf("secret file %v is empty.\n", file)
+	}
+	return secret, nil
+}
+
+// readSecretLine reads a secret from the first line of the reader.
+func readSecretLine(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", errors.Wrap(err, "could not read secret from stdin")
+	}
+	secret := strings.TrimRight(line, "\r\n")
+	if secret == "" {
+		return "", errors.New("no secret given on stdin.\n")
+	}
+	return secret, nil
+}
+
+// applyCredentials reads the password or api key from the secret files or
+// stdin, taking precedence over the context like any explicit flag, so that
+// the secrets never show in the process listing.
+func applyCredentials(c *cli.Context) error {
+	if c.Bool("password-stdin") && c.IsSet("password-file") {
+		return cli.NewExitError("--password-stdin and --password-file are mutually exclusive", 1)
+	}
+	var err error
+	if c.Bool("password-stdin") {
+		if err := requireStdin("reading the password from the terminal"); err != nil {
+			return err
+		}
+		password, err = readSecretLine(os.Stdin)
+	} else if file := c.String("password-file"); file != "" {
+		password, err = readSecret(file)
+	}
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	if file := c.String("api-key-file"); file != "" {
+		if apiKey, err = readSecret(file); err != nil {
+			return cli.NewExitError(err, 1)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,556 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+// panelSearch is a search of the data displayed by a dashboard panel, one
+// per data view the panel uses.
+type panelSearch struct {
+	DataView string                 `json:"dataView"`
+	Index    string                 `json:"index"`
+	Body     map[string]interface{} `json:"request"`
+	Response json.RawMessage        `json:"response,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+// panelData is the data snapshot of a dashboard panel. The searches are the
+// ones of the panel: the aggregations of the visualization, the documents of
+// the saved search, along with the query and filters of the panel, of its
+// saved search and of the dashboard. Skipped lists what kibctl could not
+// reproduce, e.g. the kuery queries or the lens formulas.
+type panelData struct {
+	Panel    string          `json:"panel"`
+	Label    string          `json:"label"`
+	Type     string          `json:"type"`
+	ID       string          `json:"id"`
+	Query    json.RawMessage `json:"query,omitempty"`
+	Filters  json.RawMessage `json:"filters,omitempty"`
+	Searches []*panelSearch  `json:"searches"`
+	Skipped  []string        `json:"skipped,omitempty"`
+	File     string          `json:"-"`
+}
+
+type dataManifest struct {
+	Dashboard string    `json:"dashboard"`
+	Title     string    `json:"title"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Taken     time.Time `json:"taken"`
+	Panels    []string  `json:"panels"`
+}
+
+// savedSearchDocuments is the number of documents retrieved for the saved
+// search panels, kibana paging the rest.
+const savedSearchDocuments = 50
+
+// objectDataView returns the data view the object searches, if any.
+func objectDataView(object gjson.Result) string {
+	if id := object.Get(`references.#(name=="kibanaSavedObjectMeta.searchSourceJSON.index").id`).String(); id != "" {
+		return id
+	}
+	return object.Get(`references.#(type=="index-pattern").id`).String()
+}
+
+// searchSource returns the parsed search source of the object.
+func searchSource(object gjson.Result) gjson.Result {
+	return gjson.Parse(object.Get("attributes.kibanaSavedObjectMeta.searchSourceJSON").String())
+}
+
+// searchFilters are the clauses of the query and filters of the search
+// sources of a panel.
+type searchFilters struct {
+	Filter  []interface{}
+	MustNot []interface{}
+	Skipped []string
+}
+
+// add translates the query and the enabled filters of the search source.
+// The lucene queries are run as query strings; the kuery ones, which only
+// kibana parses, are skipped.
+func (f *searchFilters) add(source gjson.Result) {
+	query := source.Get("query.query")
+	switch {
+	case query.IsObject():
+		// 6.x query dsl
+		f.Filter = append(f.Filter, json.RawMessage(query.Raw))
+	case strings.TrimSpace(query.String()) == "":
+	case source.Get("query.language").String() == "lucene":
+		f.Filter = append(f.Filter, map[string]interface{}{"query_string": map[string]string{"query": query.String()}})
+	default:
+		f.Skipped = append(f.Skipped, fmt.Sprintf("%v query %q", source.Get("query.language").String(), query.String()))
+	}
+	list := source.Get("filter")
+	if !list.Exists() {
+		// lens state
+		list = source.Get("filters")
+	}
+	for _, filter := range list.Array() {
+		if filter.Get("meta.disabled").Bool() {
+			continue
+		}
+		clause := filter.Get("query")
+		if !clause.Exists() {
+			// legacy filters holding the query dsl along with their meta
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(filter.Raw), &fields); err != nil {
+				continue
+			}
+			delete(fields, "meta")
+			delete(fields, "$state")
+			raw, err := json.Marshal(fields)
+			if err != nil || len(fields) == 0 {
+				continue
+			}
+			clause = gjson.ParseBytes(raw)
+		}
+		if filter.Get("meta.negate").Bool() {
+			f.MustNot = append(f.MustNot, json.RawMessage(clause.Raw))
+		} else {
+			f.Filter = append(f.Filter, json.RawMessage(clause.Raw))
+		}
+	}
+}
+
+// apply restricts the query of the search body to the filters.
+func (f *searchFilters) apply(body map[string]interface{}) {
+	if len(f.Filter)+len(f.MustNot) == 0 {
+		return
+	}
+	query := map[string]interface{}{"filter": append([]interface{}{body["query"]}, f.Filter...)}
+	if len(f.MustNot) > 0 {
+		query["must_not"] = f.MustNot
+	}
+	body["query"] = map[string]interface{}{"bool": query}
+}
+
+// metricAggs are the elasticsearch aggregations of the visualization metrics
+// computed on a single field.
+var metricAggs = map[string]string{
+	"avg":         "avg",
+	"sum":         "sum",
+	"min":         "min",
+	"max":         "max",
+	"cardinality": "cardinality",
+	"value_count": "value_count",
+	"std_dev":     "extended_stats",
+}
+
+// calendarUnits are the date histogram intervals kibana saves as a bare unit.
+var calendarUnits = []string{"m", "h", "d", "w", "M", "q", "y"}
+
+// metricAgg translates a metric of a visualization, false when it has no
+// aggregation or cannot be translated.
+func metricAgg(agg gjson.Result) (map[string]interface{}, bool) {
+	aggType, field := agg.Get("type").String(), agg.Get("params.field").String()
+	if field == "" {
+		return nil, false
+	}
+	params := map[string]interface{}{"field": field}
+	switch aggType {
+	case "median":
+		params["percents"] = []float64{50}
+		return map[string]interface{}{"percentiles": params}, true
+	case "percentiles":
+		if percents := agg.Get("params.percents"); percents.IsArray() {
+			params["percents"] = json.RawMessage(percents.Raw)
+		}
+		return map[string]interface{}{"percentiles": params}, true
+	case "top_hits":
+		size := agg.Get("params.size").Int()
+		if size < 1 {
+			size = 1
+		}
+		return map[string]interface{}{"top_hits": map[string]interface{}{"size": size, "_source": []string{field}}}, true
+	}
+	if name, ok := metricAggs[aggType]; ok {
+		return map[string]interface{}{name: params}, true
+	}
+	return nil, false
+}
+
+// singleValueMetric returns the metric aggregation of the visualization the
+// buckets may be ordered by, false when it has several values.
+func singleValueMetric(metrics map[string]interface{}, id string) (interface{}, bool) {
+	metric, ok := metrics[id].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	for name := range metric {
+		if name == "percentiles" || name == "extended_stats" || name == "top_hits" {
+			return nil, false
+		}
+	}
+	return metric, true
+}
+
+// bucketAgg translates a bucket aggregation of a visualization, false when
+// it cannot be translated. The terms are ordered by count unless ordered by
+// key or by one of the single value metrics.
+func bucketAgg(agg gjson.Result, metrics map[string]interface{}) (map[string]interface{}, bool) {
+	field := agg.Get("params.field").String()
+	params := map[string]interface{}{"field": field}
+	switch aggType := agg.Get("type").String(); aggType {
+	case "terms", "significant_terms":
+		size := agg.Get("params.size").Int()
+		if size < 1 {
+			size = 5
+		}
+		params["size"] = size
+		if aggType == "terms" {
+			order := strings.ToLower(agg.Get("params.order.value").String())
+			if order == "" {
+				order = agg.Get("params.order").String()
+			}
+			if order != "asc" {
+				order = "desc"
+			}
+			orderBy := agg.Get("params.orderBy").String()
+			if _, ok := singleValueMetric(metrics, orderBy); ok {
+				params["order"] = map[string]string{orderBy: order}
+			} else if orderBy == "_key" || orderBy == "alphabetical" {
+				params["order"] = map[string]string{"_key": order}
+			} else {
+				params["order"] = map[string]string{"_count": order}
+			}
+		}
+		return map[string]interface{}{aggType: params}, field != ""
+	case "date_histogram":
+		interval := agg.Get("params.interval").String()
+		if interval == "" || interval == "auto" {
+			params["buckets"] = 50
+			return map[string]interface{}{"auto_date_histogram": params}, field != ""
+		}
+		if contains(calendarUnits, interval) {
+			params["calendar_interval"] = "1" + interval
+		} else {
+			params["fixed_interval"] = interval
+		}
+		return map[string]interface{}{"date_histogram": params}, field != ""
+	case "histogram":
+		interval := agg.Get("params.interval")
+		if interval.Type != gjson.Number || interval.Num <= 0 {
+			return nil, false
+		}
+		params["interval"] = interval.Num
+		return map[string]interface{}{"histogram": params}, field != ""
+	case "range", "date_range", "ip_range":
+		ranges := agg.Get("params.ranges")
+		if aggType == "ip_range" {
+			ranges = agg.Get("params.ranges.fromTo")
+		}
+		if !ranges.IsArray() {
+			return nil, false
+		}
+		params["ranges"] = json.RawMessage(ranges.Raw)
+		return map[string]interface{}{aggType: params}, field != ""
+	case "filters":
+		filters := make(map[string]interface{})
+		for i, filter := range agg.Get("params.filters").Array() {
+			query := filter.Get("input.query").String()
+			key := filter.Get("label").String()
+			if key == "" {
+				key = fmt.Sprint(i)
+			}
+			switch {
+			case strings.TrimSpace(query) == "":
+				filters[key] = map[string]interface{}{"match_all": map[string]interface{}{}}
+			case filter.Get("input.language").String() == "lucene":
+				filters[key] = map[string]interface{}{"query_string": map[string]string{"query": query}}
+			default:
+				return nil, false
+			}
+		}
+		return map[string]interface{}{"filters": map[string]interface{}{"filters": filters}}, true
+	}
+	return nil, false
+}
+
+// visAggs translates the enabled aggregations of the visualization: the
+// buckets nested in their order, the metrics computed in the innermost one.
+// The terms ordered by a metric get the metric at their level too. It also
+// returns the aggregations it could not translate.
+func visAggs(visState gjson.Result) (map[string]interface{}, []string) {
+	var buckets []gjson.Result
+	metrics := make(map[string]interface{})
+	var skipped []string
+	for _, agg := range visState.Get("aggs").Array() {
+		if agg.Get("enabled").Exists() && !agg.Get("enabled").Bool() {
+			continue
+		}
+		aggType := agg.Get("type").String()
+		if agg.Get("schema").String() != "metric" && agg.Get("schema").String() != "radius" {
+			buckets = append(buckets, agg)
+			continue
+		}
+		if aggType == "count" {
+			continue
+		}
+		if metric, ok := metricAgg(agg); ok {
+			metrics[agg.Get("id").String()] = metric
+		} else {
+			skipped = append(skipped, fmt.Sprintf("%v aggregation %v", aggType, agg.Get("id").String()))
+		}
+	}
+
+	children := metrics
+	for i := len(buckets) - 1; i >= 0; i-- {
+		agg := buckets[i]
+		bucket, ok := bucketAgg(agg, metrics)
+		if !ok {
+			skipped = append(skipped, fmt.Sprintf("%v aggregation %v", agg.Get("type").String(), agg.Get("id").String()))
+			continue
+		}
+		level := make(map[string]interface{})
+		for id, child := range children {
+			level[id] = child
+		}
+		if metric, ok := singleValueMetric(metrics, agg.Get("params.orderBy").String()); ok && agg.Get("type").String() == "terms" {
+			level[agg.Get("params.orderBy").String()] = metric
+		}
+		if len(level) > 0 {
+			bucket["aggs"] = level
+		}
+		children = map[string]interface{}{agg.Get("id").String(): bucket}
+	}
+	return children, skipped
+}
+
+// panelDataSearches builds the searches of every panel of the dashboard
+// displaying data, over the time range up to now.
+func panelDataSearches(export []byte, dashboard gjson.Result, from string) []*panelData {
+	objects := exportObjects(export)
+	labels := panelLabels(dashboard, objects)
+	refs := make(map[string]gjson.Result)
+	for _, ref := range dashboard.Get("references").Array() {
+		refs[ref.Get("name").String()] = ref
+	}
+
+	var panels []*panelData
+	for i, panel := range gjson.Parse(dashboard.Get("attributes.panelsJSON").String()).Array() {
+		index := panel.Get("panelIndex").String()
+		if index == "" {
+			index = fmt.Sprint(i)
+		}
+		objectType, id := panel.Get("type").String(), panel.Get("id").String()
+		if ref, ok := refs[panel.Get("panelRefName").String()]; ok {
+			objectType, id = ref.Get("type").String(), ref.Get("id").String()
+		}
+		object, ok := objects[objectType+":"+id]
+		if !ok {
+			continue
+		}
+		source := searchSource(object)
+		data := &panelData{Panel: index, Label: labels[index], Type: objectType, ID: id}
+		if query := source.Get("query"); query.Exists() {
+			data.Query = json.RawMessage(query.Raw)
+		}
+		if filters := source.Get("filter"); filters.Exists() && len(filters.Array()) > 0 {
+			data.Filters = json.RawMessage(filters.Raw)
+		}
+
+		// the dashboard query and filters apply to every panel
+		filters := &searchFilters{}
+		filters.add(searchSource(dashboard))
+		filters.add(source)
+		view := objectDataView(object)
+		if name := object.Get("attributes.savedSearchRefName").String(); name != "" {
+			ref := object.Get(fmt.Sprintf(`references.#(name==%q)`, name))
+			if savedSearch, ok := objects["search:"+ref.Get("id").String()]; ok {
+				filters.add(searchSource(savedSearch))
+				view = objectDataView(savedSearch)
+			}
+		}
+
+		searches := make(map[string]map[string]interface{})
+		var views []string
+		addSearch := func(view string, aggs map[string]interface{}) {
+			if view == "" {
+				return
+			}
+			if _, ok := searches[view]; !ok {
+				searches[view] = make(map[string]interface{})
+				views = append(views, view)
+			}
+			for name, agg := range aggs {
+				searches[view][name] = agg
+			}
+		}
+		switch objectType {
+		case "visualization":
+			aggs, skipped := visAggs(gjson.Parse(object.Get("attributes.visState").String()))
+			addSearch(view, aggs)
+			data.Skipped = append(data.Skipped, skipped...)
+		case "search":
+			addSearch(view, nil)
+		default:
+			// lens and the other panels are approximated by an aggregation of
+			// every field they use
+			if objectType == "lens" {
+				filters.add(object.Get("attributes.state"))
+			}
+			addSearch(view, nil)
+			aggs := make(map[string]map[string]interface{})
+			for _, use := range fieldUses(object) {
+				if use.DataView == "" {
+					continue
+				}
+				if aggs[use.DataView] == nil {
+					aggs[use.DataView] = make(map[string]interface{})
+				}
+				aggs[use.DataView][fmt.Sprintf("%v-%d", use.Field, len(aggs[use.DataView]))] = useAgg(use)
+			}
+			for dataView, fieldAggs := range aggs {
+				addSearch(dataView, fieldAggs)
+			}
+			if len(aggs) > 0 {
+				data.Skipped = append(data.Skipped, fmt.Sprintf("%v aggregations, approximated from the fields the panel uses", objectType))
+			}
+		}
+		data.Skipped = append(data.Skipped, filters.Skipped...)
+
+		for _, view := range views {
+			dataView, ok := objects["index-pattern:"+view]
+			if !ok {
+				continue
+			}
+			timeField := dataView.Get("attributes.timeFieldName").String()
+			body := searchBody(timeField, from, searches[view])
+			if objectType == "search" {
+				body["size"] = savedSearchDocuments
+				if timeField != "" {
+					body["sort"] = []interface{}{map[string]string{timeField: "desc"}}
+				}
+				var columns []string
+				for _, column := range object.Get("attributes.columns").Array() {
+					if column.String() != "_source" {
+						columns = append(columns, column.String())
+					}
+				}
+				if len(columns) > 0 {
+					body["_source"] = columns
+				}
+			}
+			filters.apply(body)
+			data.Searches = append(data.Searches, &panelSearch{DataView: view, Index: dataView.Get("attributes.title").String(), Body: body})
+		}
+		if len(data.Searches) > 0 {
+			data.File = objectFileName("panels", index)
+			panels = append(panels, data)
+		}
+	}
+	return panels
+}
+
+// runPanelSearches executes the searches of the panels with the given
+// concurrency, recording the response or the error of each of them.
+func (c *client) runPanelSearches(panels []*panelData, parallel int) int {
+	searches := make(chan *panelSearch)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := 0
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for s := range searches {
+				response, err := c.search(s.Index, s.Body)
+				if err != nil {
+					s.Error = strings.TrimSpace(err.Error())
+					mu.Lock()
+					failed++
+					mu.Unlock()
+					continue
+				}
+				s.Response = json.RawMessage(response.Raw)
+			}
+		}()
+	}
+	for _, panel := range panels {
+		for _, s := range panel.Searches {
+			searches <- s
+		}
+	}
+	close(searches)
+	wg.Wait()
+	return failed
+}
+
+func dashboardData(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	name := c.Args().First()
+	if name == "" {
+		return cli.NewExitError("dashboard name missing", 1)
+	}
+	from := c.String("time-range")
+	if !strings.HasPrefix(from, "now-") {
+		return cli.NewExitError(fmt.Sprintf("invalid --time-range %v, e.g. now-7d expected", from), 1)
+	}
+	dir := c.String("out")
+	if dir == "" {
+		return cli.NewExitError("--out missing", 1)
+	}
+	parallel := c.Int("parallel")
+	if parallel < 1 {
+		return cli.NewExitError(fmt.Sprintf("invalid --parallel %d", parallel), 1)
+	}
+
+	client := newClient()
+	found, err := client.findDashboard(name)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	export, err := client.exportDashboards(found.ID)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	dashboard := gjson.GetBytes(export, fmt.Sprintf(`objects.#(id==%q)`, found.ID))
+	panels := panelDataSearches(export, dashboard, from)
+	if len(panels) == 0 {
+		return cli.NewExitError(fmt.Sprintf("dashboard %v has no panel searching a data view", name), 2)
+	}
+	manifest := dataManifest{Dashboard: found.ID, Title: dashboard.Get("attributes.title").String(), From: from, To: "now", Taken: time.Now().UTC()}
+	failed := client.runPanelSearches(panels, parallel)
+
+	for _, panel := range panels {
+		content, err := json.MarshalIndent(panel, "", "  ")
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		if err := writeBundleFile(dir, panel.File, append(content, '\n')); err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		manifest.Panels = append(manifest.Panels, panel.File)
+		if !quiet {
+			status := stdout.paint(green, "ok")
+			for _, s := range panel.Searches {
+				if s.Error != "" {
+					status = stdout.paint(red, "failed")
+				}
+			}
+			fmt.Fprintf(os.Stdout, "%v %v\n", stdout.paint(cyan, fmt.Sprintf("%-50v", panel.Label)), status)
+		}
+	}
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if err := writeBundleFile(dir, "manifest.json", append(content, '\n')); err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if failed > 0 {
+		return cli.NewExitError(errors.Errorf("%d search(es) failed, see the error of their panel file", failed), 2)
+	}
+	return nil
+}
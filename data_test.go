@@ -0,0 +1,159 @@
+package kibctl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestVisAggs(t *testing.T) {
+	tests := []struct {
+		name    string
+		aggs    string
+		want    string
+		skipped string
+	}{
+		{
+			name: "count",
+			aggs: `[{"id":"1","enabled":true,"type":"count","schema":"metric","params":{}}]`,
+			want: `{}`,
+		},
+		{
+			name: "metrics",
+			aggs: `[{"id":"1","type":"avg","schema":"metric","params":{"field":"bytes"}},{"id":"2","type":"median","schema":"metric","params":{"field":"bytes"}},{"id":"3","enabled":false,"type":"max","schema":"metric","params":{"field":"bytes"}}]`,
+			want: `{"1":{"avg":{"field":"bytes"}},"2":{"percentiles":{"field":"bytes","percents":[50]}}}`,
+		},
+		{
+			name: "nested buckets",
+			aggs: `[{"id":"1","type":"sum","schema":"metric","params":{"field":"bytes"}},{"id":"2","type":"date_histogram","schema":"segment","params":{"field":"@timestamp","interval":"d"}},{"id":"3","type":"terms","schema":"group","params":{"field":"host","size":3,"order":"desc","orderBy":"1"}}]`,
+			want: `{"2":{"aggs":{"3":{"aggs":{"1":{"sum":{"field":"bytes"}}},"terms":{"field":"host","order":{"1":"desc"},"size":3}}},"date_histogram":{"calendar_interval":"1d","field":"@timestamp"}}}`,
+		},
+		{
+			name: "terms ordered by count",
+			aggs: `[{"id":"1","type":"count","schema":"metric","params":{}},{"id":"2","type":"terms","schema":"segment","params":{"field":"status","orderBy":"1","order":"asc"}}]`,
+			want: `{"2":{"terms":{"field":"status","order":{"_count":"asc"},"size":5}}}`,
+		},
+		{
+			name: "auto interval",
+			aggs: `[{"id":"2","type":"date_histogram","schema":"segment","params":{"field":"@timestamp","interval":"auto"}}]`,
+			want: `{"2":{"auto_date_histogram":{"buckets":50,"field":"@timestamp"}}}`,
+		},
+		{
+			name: "lucene filters",
+			aggs: `[{"id":"2","type":"filters","schema":"group","params":{"filters":[{"input":{"query":"status:500","language":"lucene"},"label":"errors"},{"input":{"query":"","language":"lucene"}}]}}]`,
+			want: `{"2":{"filters":{"filters":{"1":{"match_all":{}},"errors":{"query_string":{"query":"status:500"}}}}}}`,
+		},
+		{
+			name:    "untranslated",
+			aggs:    `[{"id":"1","type":"geo_centroid","schema":"metric","params":{"field":"location"}},{"id":"2","type":"filters","schema":"group","params":{"filters":[{"input":{"query":"status:500","language":"kuery"}}]}}]`,
+			want:    `{}`,
+			skipped: "geo_centroid aggregation 1, filters aggregation 2",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			aggs, skipped := visAggs(gjson.Parse(`{"aggs":` + test.aggs + `}`))
+			got, err := encodeJSON(aggs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("visAggs() = %v, want %v", got, test.want)
+			}
+			if strings.Join(skipped, ", ") != test.skipped {
+				t.Errorf("visAggs() skipped %v, want %v", skipped, test.skipped)
+			}
+		})
+	}
+}
+
+func TestSearchFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		want    string
+		skipped string
+	}{
+		{
+			name:   "empty",
+			source: `{"query":{"query":"","language":"kuery"},"filter":[]}`,
+			want:   `{"range":{"@timestamp":{"gte":"now-1d","lte":"now"}}}`,
+		},
+		{
+			name:   "lucene query",
+			source: `{"query":{"query":"status:500","language":"lucene"}}`,
+			want:   `{"bool":{"filter":[{"range":{"@timestamp":{"gte":"now-1d","lte":"now"}}},{"query_string":{"query":"status:500"}}]}}`,
+		},
+		{
+			name:    "kuery query",
+			source:  `{"query":{"query":"status: 500","language":"kuery"}}`,
+			want:    `{"range":{"@timestamp":{"gte":"now-1d","lte":"now"}}}`,
+			skipped: `kuery query "status: 500"`,
+		},
+		{
+			name:   "filters",
+			source: `{"filter":[{"meta":{"negate":false},"query":{"match_phrase":{"host":"web-1"}}},{"meta":{"negate":true},"$state":{"store":"appState"},"exists":{"field":"error"}},{"meta":{"disabled":true},"query":{"match_phrase":{"host":"web-2"}}}]}`,
+			want:   `{"bool":{"filter":[{"range":{"@timestamp":{"gte":"now-1d","lte":"now"}}},{"match_phrase":{"host":"web-1"}}],"must_not":[{"exists":{"field":"error"}}]}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filters := &searchFilters{}
+			filters.add(gjson.Parse(test.source))
+			body := searchBody("@timestamp", "now-1d", nil)
+			filters.apply(body)
+			got, err := encodeJSON(body["query"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("query = %v, want %v", got, test.want)
+			}
+			if strings.Join(filters.Skipped, ", ") != test.skipped {
+				t.Errorf("skipped %v, want %v", filters.Skipped, test.skipped)
+			}
+		})
+	}
+}
+
+func TestPanelDataSearches(t *testing.T) {
+	export := []byte(`{"objects":[
+		{"type":"index-pattern","id":"logs","attributes":{"title":"logs-*","timeFieldName":"@timestamp"}},
+		{"type":"search","id":"errors","attributes":{"title":"Errors","columns":["host","message"],"kibanaSavedObjectMeta":{"searchSourceJSON":"{\"query\":{\"query\":\"level:error\",\"language\":\"lucene\"},\"filter\":[]}"}},"references":[{"name":"kibanaSavedObjectMeta.searchSourceJSON.index","type":"index-pattern","id":"logs"}]},
+		{"type":"visualization","id":"by-host","attributes":{"title":"Errors by host","savedSearchRefName":"search_0","visState":"{\"type\":\"pie\",\"aggs\":[{\"id\":\"1\",\"type\":\"count\",\"schema\":\"metric\"},{\"id\":\"2\",\"type\":\"terms\",\"schema\":\"segment\",\"params\":{\"field\":\"host\",\"size\":5}}]}","kibanaSavedObjectMeta":{"searchSourceJSON":"{}"}},"references":[{"name":"search_0","type":"search","id":"errors"}]},
+		{"type":"visualization","id":"notes","attributes":{"title":"Notes","visState":"{\"type\":\"markdown\",\"aggs\":[]}"},"references":[]}
+	]}`)
+	dashboard := gjson.Parse(`{"type":"dashboard","id":"d1","attributes":{"title":"Errors","panelsJSON":"[{\"panelIndex\":\"1\",\"panelRefName\":\"panel_0\"},{\"panelIndex\":\"2\",\"panelRefName\":\"panel_1\"},{\"panelIndex\":\"3\",\"panelRefName\":\"panel_2\"}]","kibanaSavedObjectMeta":{"searchSourceJSON":"{\"query\":{\"query\":\"env:prod\",\"language\":\"kuery\"},\"filter\":[]}"}},"references":[{"name":"panel_0","type":"visualization","id":"by-host"},{"name":"panel_1","type":"search","id":"errors"},{"name":"panel_2","type":"visualization","id":"notes"}]}`)
+
+	panels := panelDataSearches(export, dashboard, "now-1d")
+	tests := []struct {
+		panel   string
+		want    string
+		skipped string
+	}{
+		{"1", `{"aggs":{"2":{"terms":{"field":"host","order":{"_count":"desc"},"size":5}}},"query":{"bool":{"filter":[{"range":{"@timestamp":{"gte":"now-1d","lte":"now"}}},{"query_string":{"query":"level:error"}}]}},"size":0,"track_total_hits":true}`, `kuery query "env:prod"`},
+		{"2", `{"_source":["host","message"],"query":{"bool":{"filter":[{"range":{"@timestamp":{"gte":"now-1d","lte":"now"}}},{"query_string":{"query":"level:error"}}]}},"size":50,"sort":[{"@timestamp":"desc"}],"track_total_hits":true}`, `kuery query "env:prod"`},
+	}
+	if len(panels) != len(tests) {
+		t.Fatalf("panelDataSearches() returned %d panels, want %d", len(panels), len(tests))
+	}
+	for i, test := range tests {
+		t.Run(test.panel, func(t *testing.T) {
+			panel := panels[i]
+			if panel.Panel != test.panel || len(panel.Searches) != 1 || panel.Searches[0].Index != "logs-*" {
+				t.Fatalf("panel %v searched %+v, want a single search of logs-*", panel.Panel, panel.Searches)
+			}
+			got, err := encodeJSON(panel.Searches[0].Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("search = %v, want %v", got, test.want)
+			}
+			if strings.Join(panel.Skipped, ", ") != test.skipped {
+				t.Errorf("skipped %v, want %v", panel.Skipped, test.skipped)
+			}
+		})
+	}
+}
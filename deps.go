@@ -0,0 +1,253 @@
+package kibctl
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// dependency is a link from a saved object to another one, identified by id
+// or, for TSVB index patterns, only by title.
+type dependency struct {
+	Type  string
+	ID    string
+	Title string
+}
+
+// objectDependencies lists the objects a saved object refers to, through its
+// references array as well as the pre 7.0 id links embedded in its attributes.
+func objectDependencies(object gjson.Result) []dependency {
+	var deps []dependency
+	for _, ref := range object.Get("references").Array() {
+		deps = append(deps, dependency{Type: ref.Get("type").String(), ID: ref.Get("id").String()})
+	}
+
+	attributes := object.Get("attributes")
+	for _, panel := range gjson.Parse(attributes.Get("panelsJSON").String()).Array() {
+		if panel.Get("id").Exists() && panel.Get("type").Exists() {
+			deps = append(deps, dependency{Type: panel.Get("type").String(), ID: panel.Get("id").String()})
+		}
+	}
+	if index := gjson.Get(attributes.Get("kibanaSavedObjectMeta.searchSourceJSON").String(), "index"); index.Exists() {
+		deps = append(deps, dependency{Type: "index-pattern", ID: index.String()})
+	}
+	if search := attributes.Get("savedSearchId"); search.Exists() {
+		deps = append(deps, dependency{Type: "search", ID: search.String()})
+	}
+	titles, ids := tsvbIndexPatterns(attributes.Get("visState").String())
+	for _, title := range append(titles, timelionIndices(attributes.Get("visState").String())...) {
+		deps = append(deps, dependency{Type: "index-pattern", Title: title})
+	}
+	for _, id := range ids {
+		deps = append(deps, dependency{Type: "index-pattern", ID: id})
+	}
+	for _, id := range controlDataViews(attributes) {
+		deps = append(deps, dependency{Type: "index-pattern", ID: id})
+	}
+	for _, d := range dashboardDrilldowns(object) {
+		if d.Dashboard != "" {
+			deps = append(deps, dependency{Type: "dashboard", ID: d.Dashboard})
+		}
+	}
+
+	return uniqueDependencies(deps)
+}
+
+// tsvbIndexPatterns returns the index patterns of a TSVB visualisation, by
+// title for the string form and by id for the kibana data view form, including
+// the series overrides and the annotations.
+func tsvbIndexPatterns(visState string) ([]string, []string) {
+	candidates := []gjson.Result{gjson.Get(visState, "params.index_pattern")}
+	for _, series := range gjson.Get(visState, "params.series").Array() {
+		if series.Get("override_index_pattern").Bool() {
+			candidates = append(candidates, series.Get("series_index_pattern"))
+		}
+	}
+	candidates = append(candidates, gjson.Get(visState, "params.annotations.#.index_pattern").Array()...)
+
+	var titles, ids []string
+	for _, candidate := range candidates {
+		switch {
+		case candidate.Type == gjson.String && candidate.String() != "":
+			titles = append(titles, candidate.String())
+		case candidate.IsObject() && candidate.Get("id").String() != "":
+			ids = append(ids, candidate.Get("id").String())
+		}
+	}
+	return titles, ids
+}
+
+var timelionFunction = regexp.MustCompile(`\.(?:es|elasticsearch)\(([^)]*)\)`)
+var timelionIndex = regexp.MustCompile(`index\s*=\s*(?:"([^"]*)"|'([^']*)'|([^,\s)]+))`)
+
+// timelionIndices returns the indices queried by the expression of a timelion
+// visualisation.
+func timelionIndices(visState string) []string {
+	if gjson.Get(visState, "type").String() != "timelion" {
+		return nil
+	}
+	var indices []string
+	for _, function := range timelionFunction.FindAllStringSubmatch(gjson.Get(visState, "params.expression").String(), -1) {
+		for _, match := range timelionIndex.FindAllStringSubmatch(function[1], -1) {
+			indices = append(indices, match[1]+match[2]+match[3])
+		}
+	}
+	return indices
+}
+
+// controlDataViews returns the ids of the data views used by the controls of a
+// dashboard control group or of a legacy input control visualization.
+func controlDataViews(attributes gjson.Result) []string {
+	var ids []string
+	gjson.Parse(attributes.Get("controlGroupInput.panelsJSON").String()).ForEach(func(_, control gjson.Result) bool {
+		if id := control.Get("explicitInput.dataViewId").String(); id != "" {
+			ids = append(ids, id)
+		}
+		return true
+	})
+	for _, control := range gjson.Get(attributes.Get("visState").String(), "params.controls").Array() {
+		if id := control.Get("indexPattern").String(); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// includeIndexPatterns adds to the export the index-patterns with the given ids
+// which are not part of it yet.
+func (c *client) includeIndexPatterns(export []byte, ids []string) ([]byte, error) {
+	included := make(map[string]struct{})
+	for _, val := range gjson.GetBytes(export, `objects.#(type=="index-pattern")#.id`).Array() {
+		included[val.String()] = struct{}{}
+	}
+
+	for _, id := range ids {
+		if _, ok := included[id]; ok {
+			continue
+		}
+		indexPattern, err := c.getIndexPatternByID(id)
+		if err != nil {
+			if export, err = c.skipMissing(export, err); err != nil {
+				return nil, err
+			}
+			included[id] = struct{}{}
+			continue
+		}
+		c.Logger.Printf("adding index-pattern id %v", id)
+		export, err = sjson.SetRawBytes(export, "objects.-1", indexPattern)
+		if err != nil {
+			return nil, err
+		}
+		included[id] = struct{}{}
+		if resolved := gjson.GetBytes(indexPattern, "id").String(); resolved != id {
+			export, err = rewriteReferences(export, "index-pattern", id, resolved)
+			if err != nil {
+				return nil, err
+			}
+			included[resolved] = struct{}{}
+		}
+	}
+	return export, nil
+}
+
+func uniqueDependencies(deps []dependency) []dependency {
+	seen := make(map[dependency]struct{})
+	unique := deps[:0]
+	for _, dep := range deps {
+		if _, ok := seen[dep]; ok {
+			continue
+		}
+		seen[dep] = struct{}{}
+		unique = append(unique, dep)
+	}
+	return unique
+}
+
+// missingDependencies returns the dependencies of the payload objects which
+// are neither part of the payload nor present on kibana.
+func (c *client) missingDependencies(payload []byte) ([]dependency, error) {
+	included := make(map[dependency]struct{})
+	var deps []dependency
+	for _, object := range gjson.GetBytes(payload, "objects").Array() {
+		included[dependency{Type: object.Get("type").String(), ID: object.Get("id").String()}] = struct{}{}
+		if object.Get("type").String() == "index-pattern" {
+			included[dependency{Type: "index-pattern", Title: object.Get("attributes.title").String()}] = struct{}{}
+		}
+		deps = append(deps, objectDependencies(object)...)
+	}
+
+	var byID, missing []dependency
+	for _, dep := range uniqueDependencies(deps) {
+		if _, ok := included[dep]; ok {
+			continue
+		}
+		if dep.ID != "" {
+			byID = append(byID, dep)
+			continue
+		}
+		patterns, err := c.lookupIndexPatterns(dep.Title)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, pattern := range patterns {
+			found = found || pattern.Get("attributes.title").String() == dep.Title
+		}
+		if !found {
+			missing = append(missing, dep)
+		}
+	}
+
+	if len(byID) == 0 {
+		return missing, nil
+	}
+	resolved, err := c.bulkResolve(byID)
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range resolved {
+		if !r.found() {
+			missing = append(missing, byID[i])
+		}
+	}
+	return missing, nil
+}
+
+func (d dependency) String() string {
+	if d.ID == "" {
+		return fmt.Sprintf("%v titled %v", d.Type, d.Title)
+	}
+	return fmt.Sprintf("%v %v", d.Type, d.ID)
+}
+
+// missingError reports a dependency which does not exist on kibana.
+type missingError struct {
+	dependency
+}
+
+func (e missingError) Error() string {
+	if e.ID == "" {
+		return fmt.Sprintf("no %v found matching: %v.\n", e.Type, e.Title)
+	}
+	return fmt.Sprintf("no %v found with id: %v.\n", e.Type, e.ID)
+}
+
+// skipMissing records the missing dependency of the error in the
+// missingReferences of the export instead of failing, when the client skips
+// the missing dependencies. Other errors are returned unchanged.
+func (c *client) skipMissing(export []byte, err error) ([]byte, error) {
+	missing, ok := err.(missingError)
+	if !ok || !c.SkipMissingDeps {
+		return nil, err
+	}
+	c.warnf("%v missing, exported as a placeholder reference\n", missing.dependency)
+	placeholder := map[string]string{"type": missing.Type}
+	if missing.ID != "" {
+		placeholder["id"] = missing.ID
+	} else {
+		placeholder["title"] = missing.Title
+	}
+	return sjson.SetBytes(export, "missingReferences.-1", placeholder)
+}
@@ -0,0 +1,297 @@
+package kibctl
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+const (
+	diffAdded   = "added"
+	diffRemoved = "removed"
+	diffChanged = "changed"
+)
+
+// objectDiff describes how a saved object differs between two exports.
+type objectDiff struct {
+	Status string
+	Type   string
+	ID     string
+	Title  string
+	// AddedPanels and RemovedPanels are the labels of the dashboard panels
+	AddedPanels   []string
+	RemovedPanels []string
+	Queries       []queryChange
+	// Attributes are the names of the changed attributes
+	Attributes []string
+}
+
+type queryChange struct {
+	Old string
+	New string
+}
+
+// diffExports compares the objects of two exports, both normalized first so
+// that panel order and rounding do not show as changes.
+func diffExports(before, after []byte) ([]objectDiff, error) {
+	before, err := normalizeExport(before)
+	if err != nil {
+		return nil, err
+	}
+	after, err = normalizeExport(after)
+	if err != nil {
+		return nil, err
+	}
+
+	beforeObjects := exportObjects(before)
+	afterObjects := exportObjects(after)
+	var diffs []objectDiff
+	for _, object := range gjson.GetBytes(after, "objects").Array() {
+		key := object.Get("type").String() + ":" + object.Get("id").String()
+		previous, ok := beforeObjects[key]
+		if !ok {
+			diffs = append(diffs, newObjectDiff(diffAdded, object))
+			continue
+		}
+		if d, changed := diffObject(previous, object, beforeObjects, afterObjects); changed {
+			diffs = append(diffs, d)
+		}
+	}
+	for _, object := range gjson.GetBytes(before, "objects").Array() {
+		key := object.Get("type").String() + ":" + object.Get("id").String()
+		if _, ok := afterObjects[key]; !ok {
+			diffs = append(diffs, newObjectDiff(diffRemoved, object))
+		}
+	}
+	return diffs, nil
+}
+
+func exportObjects(export []byte) map[string]gjson.Result {
+	objects := make(map[string]gjson.Result)
+	for _, object := range gjson.GetBytes(export, "objects").Array() {
+		objects[object.Get("type").String()+":"+object.Get("id").String()] = object
+	}
+	return objects
+}
+
+func newObjectDiff(status string, object gjson.Result) objectDiff {
+	return objectDiff{
+		Status: status,
+		Type:   object.Get("type").String(),
+		ID:     object.Get("id").String(),
+		Title:  object.Get("attributes.title").String(),
+	}
+}
+
+func diffObject(before, after gjson.Result, beforeObjects, afterObjects map[string]gjson.Result) (objectDiff, bool) {
+	d := newObjectDiff(diffChanged, after)
+
+	names := make(map[string]struct{})
+	for _, attributes := range []gjson.Result{before.Get("attributes"), after.Get("attributes")} {
+		attributes.ForEach(func(key, _ gjson.Result) bool {
+			names[key.String()] = struct{}{}
+			return true
+		})
+	}
+	for name := range names {
+		path := escapePath(name)
+		if before.Get("attributes."+path).Raw != after.Get("attributes."+path).Raw {
+			d.Attributes = append(d.Attributes, name)
+		}
+	}
+	sort.Strings(d.Attributes)
+	if before.Get("references").Raw != after.Get("references").Raw {
+		d.Attributes = append(d.Attributes, "references")
+	}
+
+	beforePanels := panelLabels(before, beforeObjects)
+	afterPanels := panelLabels(after, afterObjects)
+	for _, index := range sortedKeys(afterPanels) {
+		if _, ok := beforePanels[index]; !ok {
+			d.AddedPanels = append(d.AddedPanels, afterPanels[index])
+		}
+	}
+	for _, index := range sortedKeys(beforePanels) {
+		if _, ok := afterPanels[index]; !ok {
+			d.RemovedPanels = append(d.RemovedPanels, beforePanels[index])
+		}
+	}
+
+	beforeQuery := objectQuery(before)
+	afterQuery := objectQuery(after)
+	if beforeQuery != afterQuery {
+		d.Queries = append(d.Queries, queryChange{Old: beforeQuery, New: afterQuery})
+	}
+
+	return d, len(d.Attributes) > 0
+}
+
+func objectQuery(object gjson.Result) string {
+	source := object.Get("attributes.kibanaSavedObjectMeta.searchSourceJSON").String()
+	return gjson.Get(source, "query.query").String()
+}
+
+// panelLabels returns the panels of a dashboard by panel index, labelled by
+// their custom title or else the title of the object they display.
+func panelLabels(object gjson.Result, objects map[string]gjson.Result) map[string]string {
+	refs := make(map[string]gjson.Result)
+	for _, ref := range object.Get("references").Array() {
+		refs[ref.Get("name").String()] = ref
+	}
+	labels := make(map[string]string)
+	for i, panel := range gjson.Parse(object.Get("attributes.panelsJSON").String()).Array() {
+		index := panel.Get("panelIndex").String()
+		if index == "" {
+			index = fmt.Sprint(i)
+		}
+		label := panel.Get("embeddableConfig.title").String()
+		if label == "" {
+			label = panel.Get("title").String()
+		}
+		if label == "" {
+			objectType, id := panel.Get("type").String(), panel.Get("id").String()
+			if ref, ok := refs[panel.Get("panelRefName").String()]; ok {
+				objectType, id = ref.Get("type").String(), ref.Get("id").String()
+			}
+			label = objects[objectType+":"+id].Get("attributes.title").String()
+			if label == "" && id != "" {
+				label = objectType + " " + id
+			}
+		}
+		if label == "" {
+			label = "panel " + index
+		}
+		labels[index] = label
+	}
+	return labels
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func diffSummary(diffs []objectDiff) string {
+	counts := make(map[string]int)
+	for _, d := range diffs {
+		counts[d.Status]++
+	}
+	return fmt.Sprintf("%d added, %d changed, %d removed", counts[diffAdded], counts[diffChanged], counts[diffRemoved])
+}
+
+func writeDiffText(w io.Writer, diffs []objectDiff) {
+	signs := map[string]string{diffAdded: "+", diffRemoved: "-", diffChanged: "~"}
+	colors := map[string]color{diffAdded: green, diffRemoved: red, diffChanged: yellow}
+	for _, d := range diffs {
+		fmt.Fprintf(w, "%v %v %v (%v)\n", stdout.paint(colors[d.Status], signs[d.Status]), d.Type, d.ID, d.Title)
+		for _, panel := range d.AddedPanels {
+			fmt.Fprintf(w, "    + panel %v\n", panel)
+		}
+		for _, panel := range d.RemovedPanels {
+			fmt.Fprintf(w, "    - panel %v\n", panel)
+		}
+		for _, q := range d.Queries {
+			fmt.Fprintf(w, "    query: %q -> %q\n", q.Old, q.New)
+		}
+		if len(d.Attributes) > 0 {
+			fmt.Fprintf(w, "    changed: %v\n", strings.Join(d.Attributes, ", "))
+		}
+	}
+	fmt.Fprintf(w, "%v\n", diffSummary(diffs))
+}
+
+// writeDiffMarkdown renders the diff as a pull request comment, the details
+// of each changed object being collapsible.
+func writeDiffMarkdown(w io.Writer, diffs []objectDiff) {
+	fmt.Fprintf(w, "### Kibana objects: %v\n\n", diffSummary(diffs))
+	if len(diffs) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "| | Type | Title | ID |\n|---|---|---|---|\n")
+	for _, d := range diffs {
+		fmt.Fprintf(w, "| %v | %v | %v | `%v` |\n", d.Status, d.Type, markdownEscape(d.Title), d.ID)
+	}
+	for _, d := range diffs {
+		if d.Status != diffChanged {
+			continue
+		}
+		fmt.Fprintf(w, "\n<details>\n<summary><b>%v</b> (%v <code>%v</code>)</summary>\n\n", markdownEscape(d.Title), d.Type, d.ID)
+		for _, panel := range d.AddedPanels {
+			fmt.Fprintf(w, "- :heavy_plus_sign: panel %v\n", markdownEscape(panel))
+		}
+		for _, panel := range d.RemovedPanels {
+			fmt.Fprintf(w, "- :heavy_minus_sign: panel %v\n", markdownEscape(panel))
+		}
+		for _, q := range d.Queries {
+			fmt.Fprintf(w, "- query changed\n  ```diff\n  - %v\n  + %v\n  ```\n", q.Old, q.New)
+		}
+		if len(d.Attributes) > 0 {
+			fmt.Fprintf(w, "- changed attributes: `%v`\n", strings.Join(d.Attributes, "`, `"))
+		}
+		fmt.Fprintf(w, "\n</details>\n")
+	}
+}
+
+func markdownEscape(text string) string {
+	return strings.NewReplacer("|", `\|`, "<", "&lt;", ">", "&gt;", "*", `\*`, "_", `\_`, "`", "\\`").Replace(text)
+}
+
+// diffCases reports every changed object as a failed test case.
+func diffCases(diffs []objectDiff) []junitCase {
+	var cases []junitCase
+	for _, d := range diffs {
+		message := fmt.Sprintf("%v %v", d.Type, d.Status)
+		if len(d.Attributes) > 0 {
+			message += ": " + strings.Join(d.Attributes, ", ")
+		}
+		cases = append(cases, junitCase{
+			ClassName: d.Type,
+			Name:      fmt.Sprintf("%v (%v)", d.ID, d.Title),
+			Failures:  []junitFailure{{Type: d.Status, Message: message, Text: message}},
+		})
+	}
+	return cases
+}
+
+func diff(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return cli.NewExitError("two exports to compare expected", 1)
+	}
+	format := c.String("format")
+	if format != "text" && format != "markdown" {
+		return cli.NewExitError(fmt.Sprintf("unsupported diff format %v", format), 1)
+	}
+	before, err := readBundle(c.Args().Get(0))
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	after, err := readBundle(c.Args().Get(1))
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	diffs, err := diffExports(before, after)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+
+	if report := c.String("report"); report != "" {
+		if err := writeJUnit(report, "kibctl diff", diffCases(diffs)); err != nil {
+			return cli.NewExitError(err, 2)
+		}
+	}
+	if format == "markdown" {
+		writeDiffMarkdown(os.Stdout, diffs)
+		return nil
+	}
+	writeDiffText(os.Stdout, diffs)
+	return nil
+}
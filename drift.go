@@ -0,0 +1,72 @@
+package kibctl
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/urfave/cli"
+)
+
+// driftLabels words the diff between the repository and kibana as drift.
+var driftLabels = map[string]string{
+	diffAdded:   "missing in repository",
+	diffRemoved: "missing on kibana",
+	diffChanged: "modified on kibana",
+}
+
+func writeDriftText(w io.Writer, diffs []objectDiff) {
+	for _, d := range diffs {
+		fmt.Fprintf(w, "%v %v %v (%v)\n", stdout.paint(yellow, fmt.Sprintf("%-22v", driftLabels[d.Status])), d.Type, d.ID, d.Title)
+		for _, panel := range d.AddedPanels {
+			fmt.Fprintf(w, "    + panel %v\n", panel)
+		}
+		for _, panel := range d.RemovedPanels {
+			fmt.Fprintf(w, "    - panel %v\n", panel)
+		}
+		for _, q := range d.Queries {
+			fmt.Fprintf(w, "    query: %q -> %q\n", q.Old, q.New)
+		}
+	}
+}
+
+func drift(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	repo := c.String("repo")
+	if repo == "" {
+		return cli.NewExitError("repository missing", 1)
+	}
+	format := c.String("format")
+	if format != "text" && format != "markdown" {
+		return cli.NewExitError(fmt.Sprintf("unsupported drift format %v", format), 1)
+	}
+	committed, err := readCommittedBundle(repo, c.String("revision"), c.String("path"))
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	live, err := newClient().exportMatching(c.Args().First())
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	diffs, err := diffExports(committed, live)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+
+	if report := c.String("report"); report != "" {
+		if err := writeJUnit(report, "kibctl drift", diffCases(diffs)); err != nil {
+			return cli.NewExitError(err, 2)
+		}
+	}
+	if format == "markdown" {
+		writeDiffMarkdown(os.Stdout, diffs)
+	} else if !quiet {
+		writeDriftText(os.Stdout, diffs)
+	}
+	if len(diffs) > 0 {
+		return cli.NewExitError(fmt.Sprintf("drift found: %v", diffSummary(diffs)), 3)
+	}
+	return nil
+}
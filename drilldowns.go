@@ -0,0 +1,90 @@
+package kibctl
+
+import (
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	dashboardDrilldown = "DASHBOARD_TO_DASHBOARD_DRILLDOWN"
+	urlDrilldown       = "URL_DRILLDOWN"
+)
+
+// drilldown is a dashboard to dashboard or url drilldown of a dashboard panel.
+type drilldown struct {
+	Panel   string
+	Name    string
+	Factory string
+	// Dashboard is the id of the target dashboard of a dashboard drilldown
+	Dashboard string
+	// URL is the template of an url drilldown
+	URL string
+}
+
+// dashboardDrilldowns returns the drilldowns defined in the enhancements of the
+// dashboard panels. The target dashboards are read from the drilldown config
+// up to 7.x, and from the references from 7.11 on.
+func dashboardDrilldowns(object gjson.Result) []drilldown {
+	refs := make(map[string]string)
+	for _, ref := range object.Get("references").Array() {
+		refs[ref.Get("name").String()] = ref.Get("id").String()
+	}
+	var drilldowns []drilldown
+	for _, panel := range gjson.Parse(object.Get("attributes.panelsJSON").String()).Array() {
+		index := panel.Get("panelIndex").String()
+		for _, event := range panel.Get("embeddableConfig.enhancements.dynamicActions.events").Array() {
+			d := drilldown{
+				Panel:   index,
+				Name:    event.Get("action.name").String(),
+				Factory: event.Get("action.factoryId").String(),
+			}
+			switch d.Factory {
+			case dashboardDrilldown:
+				d.Dashboard = event.Get("action.config.dashboardId").String()
+				if d.Dashboard == "" {
+					suffix := "drilldown:" + dashboardDrilldown + ":" + event.Get("eventId").String() + ":dashboardId"
+					for name, id := range refs {
+						if strings.HasSuffix(name, suffix) {
+							d.Dashboard = id
+						}
+					}
+				}
+			case urlDrilldown:
+				d.URL = event.Get("action.config.url.template").String()
+			default:
+				continue
+			}
+			drilldowns = append(drilldowns, d)
+		}
+	}
+	return drilldowns
+}
+
+// drilldownCheck reports the drilldowns without target, and the dashboard
+// drilldowns whose target is not part of the export.
+func drilldownCheck(export []byte) lintCheck {
+	dashboards := make(map[string]struct{})
+	for _, id := range gjson.GetBytes(export, `objects.#(type=="dashboard")#.id`).Array() {
+		dashboards[id.String()] = struct{}{}
+	}
+	return func(object gjson.Result) []lintFinding {
+		if object.Get("type").String() != "dashboard" {
+			return nil
+		}
+		var findings []lintFinding
+		for _, d := range dashboardDrilldowns(object) {
+			switch {
+			case d.Factory == dashboardDrilldown && d.Dashboard == "":
+				findings = append(findings, newFinding(object, severityError, "drilldown", "drilldown %q of panel %v has no target dashboard", d.Name, d.Panel))
+			case d.Factory == dashboardDrilldown:
+				if _, ok := dashboards[d.Dashboard]; !ok {
+					findings = append(findings, newFinding(object, severityWarning, "drilldown", "drilldown %q of panel %v targets dashboard %v, which is not part of the export", d.Name, d.Panel, d.Dashboard))
+				}
+			case strings.TrimSpace(d.URL) == "":
+				findings = append(findings, newFinding(object, severityError, "drilldown", "url drilldown %q of panel %v has no url", d.Name, d.Panel))
+			}
+		}
+		return findings
+	}
+}
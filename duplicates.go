@@ -0,0 +1,162 @@
+package kibctl
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+// panelFingerprint is what identifies a dashboard panel independently of the
+// dashboard it belongs to: the object it displays, its position and its
+// configuration.
+type panelFingerprint struct {
+	Type   string `json:"type"`
+	Object string `json:"object"`
+	X      int64  `json:"x"`
+	Y      int64  `json:"y"`
+	W      int64  `json:"w"`
+	H      int64  `json:"h"`
+	// EmbeddableConfig is the hash of the panel configuration
+	EmbeddableConfig string `json:"embeddableConfig,omitempty"`
+}
+
+// dashboardFingerprint hashes the normalized panels of the dashboard along
+// with its query and filters, ignoring its id, title and description so that
+// clones hash alike. It returns an empty hash for dashboards without panels.
+func dashboardFingerprint(o savedObject) (string, int, error) {
+	panelsJSON := gjson.GetBytes(o.Attributes, "panelsJSON").String()
+	if panelsJSON == "" || panelsJSON == "[]" {
+		return "", 0, nil
+	}
+	normalized, err := normalizePanels(panelsJSON)
+	if err != nil {
+		return "", 0, err
+	}
+	refs := make(map[string]reference)
+	for _, ref := range o.References {
+		refs[ref.Name] = ref
+	}
+
+	var panels []panelFingerprint
+	for _, panel := range gjson.Parse(normalized).Array() {
+		objectType, id := panel.Get("type").String(), panel.Get("id").String()
+		if ref, ok := refs[panel.Get("panelRefName").String()]; ok {
+			objectType, id = ref.Type, ref.ID
+		}
+		fp := panelFingerprint{
+			Type:   objectType,
+			Object: id,
+			X:      panel.Get("gridData.x").Int(),
+			Y:      panel.Get("gridData.y").Int(),
+			W:      panel.Get("gridData.w").Int(),
+			H:      panel.Get("gridData.h").Int(),
+		}
+		if config := panel.Get("embeddableConfig"); config.Exists() {
+			fp.EmbeddableConfig = contentHash(json.RawMessage(config.Raw))
+		}
+		panels = append(panels, fp)
+	}
+	source := gjson.GetBytes(o.Attributes, "kibanaSavedObjectMeta.searchSourceJSON").String()
+	content, err := json.Marshal(struct {
+		Panels  []panelFingerprint `json:"panels"`
+		Query   string             `json:"query"`
+		Filters string             `json:"filters"`
+	}{panels, gjson.Get(source, "query").Raw, gjson.Get(source, "filter").Raw})
+	if err != nil {
+		return "", 0, err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(content)), len(panels), nil
+}
+
+type duplicateGroup struct {
+	Panels     int
+	Dashboards []spaceObject
+}
+
+// findDuplicates groups the dashboards sharing the same fingerprint, within
+// each space unless across spaces is requested.
+func findDuplicates(dashboards []spaceObject, acrossSpaces bool) ([]duplicateGroup, error) {
+	groups := make(map[string]*duplicateGroup)
+	var keys []string
+	for _, d := range dashboards {
+		hash, panels, err := dashboardFingerprint(d.savedObject)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not fingerprint dashboard %v", d.ID)
+		}
+		if hash == "" {
+			continue
+		}
+		key := hash
+		if !acrossSpaces {
+			key = d.Space + "\x00" + hash
+		}
+		group, ok := groups[key]
+		if !ok {
+			group = &duplicateGroup{Panels: panels}
+			groups[key] = group
+			keys = append(keys, key)
+		}
+		group.Dashboards = append(group.Dashboards, d)
+	}
+
+	var duplicates []duplicateGroup
+	for _, key := range keys {
+		if group := groups[key]; len(group.Dashboards) > 1 {
+			duplicates = append(duplicates, *group)
+		}
+	}
+	sort.SliceStable(duplicates, func(i, j int) bool {
+		return len(duplicates[i].Dashboards) > len(duplicates[j].Dashboards)
+	})
+	return duplicates, nil
+}
+
+func auditDuplicates(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	client := newClient()
+	spaces, err := client.listSpaces()
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	var dashboards []spaceObject
+	for _, s := range spaces {
+		client.Logger.Printf("scanning space %v\n", s.ID)
+		found, err := client.inSpace(s.ID).findObjects([]string{"dashboard"}, "")
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		for _, o := range found {
+			dashboards = append(dashboards, spaceObject{Space: s.ID, savedObject: o})
+		}
+	}
+
+	duplicates, err := findDuplicates(dashboards, c.Bool("across-spaces"))
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if quiet {
+		for _, group := range duplicates {
+			for _, d := range group.Dashboards {
+				printIDs(d.ID)
+			}
+		}
+		return nil
+	}
+	for i, group := range duplicates {
+		os.Stdout.WriteString(stdout.header(fmt.Sprintf("GROUP %d: %d identical dashboards, %d panels", i+1, len(group.Dashboards), group.Panels)) + "\n")
+		for _, d := range group.Dashboards {
+			os.Stdout.WriteString(fmt.Sprintf("  %-20v %-40v %v\n", d.Space, d.ID, d.title()))
+		}
+		os.Stdout.WriteString(fmt.Sprintf("  %v\n", stdout.paint(green, "-> keep one dashboard and share it, or link the copies to it")))
+	}
+	os.Stdout.WriteString(fmt.Sprintf("%d group(s) of duplicates among %d dashboards\n", len(duplicates), len(dashboards)))
+	return nil
+}
@@ -0,0 +1,133 @@
+package kibctl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+func editor() []string {
+	for _, env := range []string{"KIBCTL_EDITOR", "EDITOR"} {
+		if value := strings.Fields(os.Getenv(env)); len(value) > 0 {
+			return value
+		}
+	}
+	return []string{"vi"}
+}
+
+func runEditor(file string) error {
+	command := editor()
+	cmd := exec.Command(command[0], append(command[1:], file)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// validateEdit checks the edited export can be imported.
+func validateEdit(export []byte) error {
+	if !gjson.ValidBytes(export) {
+		return errors.New("invalid json")
+	}
+	if !gjson.GetBytes(export, "objects").IsArray() {
+		return errors.New("objects array missing")
+	}
+	for _, f := range lintExport(export) {
+		if f.Severity == severityError {
+			return errors.Errorf("%v %v [%v] %v", f.Type, f.ID, f.Check, f.Message)
+		}
+	}
+	return nil
+}
+
+// editExport opens the export in the editor until it is saved valid, and
+// returns the edited export or nil when the edit was cancelled.
+func editExport(export []byte) ([]byte, error) {
+	file, err := ioutil.TempFile("", "kibctl-edit-*.json")
+	if err != nil {
+		return nil, err
+	}
+	path := file.Name()
+	file.Close()
+
+	original := export
+	previous := export
+	for {
+		if err := ioutil.WriteFile(path, previous, 0600); err != nil {
+			return nil, err
+		}
+		if err := runEditor(path); err != nil {
+			return nil, errors.Wrapf(err, "editor failed, edits kept in %v", path)
+		}
+		edited, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if bytes.Equal(bytes.TrimSpace(edited), bytes.TrimSpace(original)) {
+			os.Remove(path)
+			return nil, nil
+		}
+		err = validateEdit(edited)
+		if err == nil {
+			os.Remove(path)
+			return edited, nil
+		}
+		if bytes.Equal(edited, previous) {
+			return nil, errors.Wrapf(err, "edit aborted, edits kept in %v", path)
+		}
+		fmt.Fprintf(os.Stderr, "error: %v\nreopening the editor, save without changes to abort\n", err)
+		previous = edited
+	}
+}
+
+func editDashboard(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	name := c.Args().First()
+	if name == "" {
+		return cli.NewExitError("dashboard name missing", 1)
+	}
+	if err := requireInput("editing the dashboard"); err != nil {
+		return err
+	}
+	client := newClient()
+	dashboard, err := client.export(name)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, dashboard, "", "  "); err != nil {
+		return cli.NewExitError(err, 2)
+	}
+
+	edited, err := editExport(pretty.Bytes())
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if edited == nil {
+		if !quiet {
+			fmt.Fprintln(os.Stderr, "edit cancelled, no changes made")
+		}
+		return nil
+	}
+	if !c.Bool("force") {
+		if err := client.checkVersions(dashboard); err != nil {
+			return cli.NewExitError(err, 2)
+		}
+	}
+	if err := client._import(edited); err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	printIDs(payloadIDs(edited)...)
+	return nil
+}
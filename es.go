@@ -0,0 +1,96 @@
+package kibctl
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+var consoleMethods = []string{"GET", "HEAD", "POST", "PUT", "DELETE"}
+
+// consoleProxy sends the request to elasticsearch through the console proxy
+// of kibana and returns the response of elasticsearch as is, whatever its
+// status and content type.
+func (c *client) consoleProxy(method, path string, body []byte) (*http.Response, []byte, error) {
+	query := url.Values{"path": {path}, "method": {method}}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := c.newRequest("POST", "/api/console/proxy?"+query.Encode(), reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Del("Accept")
+	if strings.Contains(path, "_bulk") || strings.Contains(path, "_msearch") {
+		req.Header.Set("Content-Type", "application/x-ndjson")
+	}
+	return c.doRequest(req)
+}
+
+// esRequestBody returns the body given with --data, read from the file
+// following an @, or from stdin with @-.
+func esRequestBody(c *cli.Context) ([]byte, error) {
+	data := c.String("data")
+	switch {
+	case data == "":
+		return nil, nil
+	case data == "@-":
+		if c.GlobalBool("password-stdin") || c.Bool("password-stdin") {
+			return nil, errors.New("stdin holds the password with --password-stdin, give the body as a file.\n")
+		}
+		body, err := ioutil.ReadAll(os.Stdin)
+		return body, errors.Wrap(err, "could not read the request body")
+	case strings.HasPrefix(data, "@"):
+		body, err := ioutil.ReadFile(data[1:])
+		return body, errors.Wrap(err, "could not read the request body")
+	}
+	return []byte(data), nil
+}
+
+func esCommand(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	path := c.Args().First()
+	if path == "" {
+		return cli.NewExitError("elasticsearch path missing, e.g. /_cat/indices?v", 1)
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	method := strings.ToUpper(c.String("request"))
+	if !contains(consoleMethods, method) {
+		return cli.NewExitError(fmt.Sprintf("invalid method %v, one of %v expected", method, strings.Join(consoleMethods, ", ")), 1)
+	}
+	if c.String("data") == "@-" {
+		if err := requireStdin("reading the request body from the terminal"); err != nil {
+			return err
+		}
+	}
+	body, err := esRequestBody(c)
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	resp, content, err := newClient().consoleProxy(method, path, body)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	os.Stdout.Write(content)
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		os.Stdout.WriteString("\n")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return cli.NewExitError(fmt.Sprintf("%v %v responded %v", method, path, resp.Status), 2)
+	}
+	return nil
+}
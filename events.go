@@ -0,0 +1,60 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// event reports the progress of a command on a saved object, printed as a
+// json line with --output jsonl.
+type event struct {
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	// Event is started, succeeded, failed or skipped
+	Event string `json:"event"`
+	Type  string `json:"type"`
+	ID    string `json:"id"`
+	Title string `json:"title,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+func jsonl() bool {
+	return output == "jsonl"
+}
+
+func emit(e event) {
+	if !jsonl() {
+		return
+	}
+	e.Time = time.Now().UTC()
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	os.Stdout.Write(append(line, '\n'))
+}
+
+// emitObjects reports the event for every object of the payload, failed with
+// the error when given.
+func emitObjects(command, name string, payload []byte, err error) {
+	for _, object := range gjson.GetBytes(payload, "objects").Array() {
+		emitObject(command, name, object, err)
+	}
+}
+
+func emitObject(command, name string, object gjson.Result, err error) {
+	e := event{
+		Command: command,
+		Event:   name,
+		Type:    object.Get("type").String(),
+		ID:      object.Get("id").String(),
+		Title:   object.Get("attributes.title").String(),
+	}
+	if err != nil {
+		e.Event, e.Error = "failed", err.Error()
+	}
+	emit(e)
+}
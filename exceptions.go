@@ -0,0 +1,221 @@
+package kibctl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+const exceptionListsAPI = "/api/exception_lists"
+
+type exceptionList struct {
+	ID            string `json:"id"`
+	ListID        string `json:"list_id"`
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	NamespaceType string `json:"namespace_type"`
+}
+
+func (c *client) listExceptionLists() ([]exceptionList, error) {
+	var lists []exceptionList
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("namespace_type", "single,agnostic")
+		query.Set("per_page", "100")
+		query.Set("page", fmt.Sprint(page))
+		body, err := c.jsonRequest("GET", exceptionListsAPI+"/_find?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Total int             `json:"total"`
+			Data  []exceptionList `json:"data"`
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, errors.Wrap(err, "could not parse exception lists")
+		}
+		lists = append(lists, result.Data...)
+		if len(result.Data) == 0 || len(lists) >= result.Total {
+			return lists, nil
+		}
+	}
+}
+
+// findExceptionList returns the exception list with the list id.
+func (c *client) findExceptionList(listID string) (exceptionList, error) {
+	lists, err := c.listExceptionLists()
+	if err != nil {
+		return exceptionList{}, err
+	}
+	for _, list := range lists {
+		if list.ListID == listID {
+			return list, nil
+		}
+	}
+	return exceptionList{}, errors.Errorf("no exception list found with list id: %v.\n", listID)
+}
+
+// exportExceptionList exports the exception list and its items as ndjson.
+func (c *client) exportExceptionList(list exceptionList) ([]byte, error) {
+	query := url.Values{}
+	query.Set("id", list.ID)
+	query.Set("list_id", list.ListID)
+	query.Set("namespace_type", list.NamespaceType)
+	export, err := c.jsonRequest("POST", exceptionListsAPI+"/_export?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// drop the export details summary
+	var content bytes.Buffer
+	for _, line := range ndjsonLines(export) {
+		content.WriteString(line.Raw + "\n")
+	}
+	return content.Bytes(), nil
+}
+
+func (c *client) importExceptionLists(content []byte) (importResult, error) {
+	body, err := c.uploadNDJSON(exceptionListsAPI+"/_import?overwrite=true", "exceptions.ndjson", content)
+	if err != nil {
+		return importResult{}, err
+	}
+	var result importResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		return importResult{}, errors.Wrap(err, "could not parse import result")
+	}
+	return result, nil
+}
+
+// isExceptionLine tells whether an ndjson line of a rules export is an
+// exception list or item rather than a rule.
+func isExceptionLine(line gjson.Result) bool {
+	return !line.Get("rule_id").Exists() && (line.Get("list_id").Exists() || line.Get("item_id").Exists())
+}
+
+// linkExceptionLists appends to the rules export the exception lists the rules
+// refer to which are not part of it, as exported by kibana versions which do
+// not include them.
+func (c *client) linkExceptionLists(export []byte) ([]byte, error) {
+	lines := ndjsonLines(export)
+	included := make(map[string]struct{})
+	for _, line := range lines {
+		if isExceptionLine(line) && !line.Get("item_id").Exists() {
+			included[line.Get("list_id").String()] = struct{}{}
+		}
+	}
+	var linked bytes.Buffer
+	for _, line := range lines {
+		linked.WriteString(line.Raw + "\n")
+	}
+	for _, line := range lines {
+		for _, ref := range line.Get("exceptions_list").Array() {
+			listID := ref.Get("list_id").String()
+			if _, ok := included[listID]; ok {
+				continue
+			}
+			c.Logger.Printf("adding exception list %v of rule %v", listID, line.Get("rule_id").String())
+			list, err := c.exportExceptionList(exceptionList{
+				ID:            ref.Get("id").String(),
+				ListID:        listID,
+				NamespaceType: ref.Get("namespace_type").String(),
+			})
+			if err != nil {
+				return nil, err
+			}
+			linked.Write(list)
+			included[listID] = struct{}{}
+		}
+	}
+	return linked.Bytes(), nil
+}
+
+// stripExceptionLists removes the exception lists and items from a rules export.
+func stripExceptionLists(export []byte) []byte {
+	var stripped bytes.Buffer
+	for _, line := range ndjsonLines(export) {
+		if !isExceptionLine(line) {
+			stripped.WriteString(line.Raw + "\n")
+		}
+	}
+	return stripped.Bytes()
+}
+
+func exceptionListList(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	lists, err := newClient().listExceptionLists()
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if quiet {
+		for _, list := range lists {
+			printIDs(list.ListID)
+		}
+		return nil
+	}
+	os.Stdout.WriteString(stdout.header(fmt.Sprintf("%-40v %-20v %-10v %v", "LIST ID", "TYPE", "NAMESPACE", "NAME")) + "\n")
+	for _, list := range lists {
+		fmt.Fprintf(os.Stdout, "%v %-20v %-10v %v\n", stdout.paint(cyan, fmt.Sprintf("%-40v", list.ListID)), list.Type, list.NamespaceType, list.Name)
+	}
+	return nil
+}
+
+func exceptionListExport(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	if c.NArg() == 0 {
+		return cli.NewExitError("exception list id missing", 1)
+	}
+	client := newClient()
+	for _, listID := range c.Args() {
+		list, err := client.findExceptionList(listID)
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		export, err := client.exportExceptionList(list)
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		os.Stdout.Write(export)
+	}
+	return nil
+}
+
+func exceptionListImport(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	file := c.Args().First()
+	if file == "" {
+		return cli.NewExitError("exception lists ndjson file missing", 1)
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return cli.NewExitError(errors.Wrap(err, "could not read exception lists file"), 2)
+	}
+	result, err := newClient().importExceptionLists(content)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if err := result.err(); err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if quiet {
+		for _, line := range ndjsonLines(content) {
+			if !line.Get("item_id").Exists() {
+				printIDs(line.Get("list_id").String())
+			}
+		}
+		return nil
+	}
+	fmt.Fprintf(os.Stdout, "imported %d exception list object(s)\n", result.SuccessCount)
+	return nil
+}
@@ -0,0 +1,217 @@
+package kibctl
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+// fieldUse is a field used by a visualization, along with the aggregation or
+// operation using it.
+type fieldUse struct {
+	DataView  string
+	Field     string
+	Operation string
+}
+
+// numberOperations and dateOperations are the aggregations and lens
+// operations which require a numeric or a date field.
+var numberOperations = []string{"avg", "sum", "min", "max", "median", "percentiles", "percentile_ranks", "std_dev", "histogram", "range",
+	"average", "percentile", "percentile_rank", "standard_deviation", "cumulative_sum"}
+var dateOperations = []string{"date_histogram", "date_range"}
+
+// fieldUses lists the fields of the data views used by the aggregations of a
+// visualization, the columns of a lens layer or of a saved search.
+func fieldUses(object gjson.Result) []fieldUse {
+	refs := make(map[string]string)
+	for _, ref := range object.Get("references").Array() {
+		refs[ref.Get("name").String()] = ref.Get("id").String()
+	}
+	attributes := object.Get("attributes")
+	dataView := refs["kibanaSavedObjectMeta.searchSourceJSON.index"]
+	if dataView == "" {
+		dataView = gjson.Get(attributes.Get("kibanaSavedObjectMeta.searchSourceJSON").String(), "index").String()
+	}
+
+	var uses []fieldUse
+	switch object.Get("type").String() {
+	case "visualization":
+		for _, agg := range gjson.Get(attributes.Get("visState").String(), "aggs").Array() {
+			if field := agg.Get("params.field").String(); field != "" {
+				uses = append(uses, fieldUse{DataView: dataView, Field: field, Operation: agg.Get("type").String()})
+			}
+		}
+	case "lens":
+		for _, datasource := range []string{"formBased", "indexpattern"} {
+			attributes.Get("state.datasourceStates." + datasource + ".layers").ForEach(func(layerID, layer gjson.Result) bool {
+				layerView := refs["indexpattern-datasource-layer-"+layerID.String()]
+				layer.Get("columns").ForEach(func(_, column gjson.Result) bool {
+					field := column.Get("sourceField").String()
+					if field != "" && field != "___records___" {
+						uses = append(uses, fieldUse{DataView: layerView, Field: field, Operation: column.Get("operationType").String()})
+					}
+					return true
+				})
+				return true
+			})
+		}
+	case "search":
+		for _, column := range attributes.Get("columns").Array() {
+			if column.String() != "_source" {
+				uses = append(uses, fieldUse{DataView: dataView, Field: column.String()})
+			}
+		}
+	}
+	return uses
+}
+
+// dataViewFields returns the types of the fields of the data view by name,
+// the mapped fields being read from the cluster when live, else from the
+// field list saved with the data view. The runtime and scripted fields of the
+// data view are always included.
+func (c *client) dataViewFields(dataView gjson.Result, live bool) (map[string]string, error) {
+	fields := make(map[string]string)
+	attributes := dataView.Get("attributes")
+	for _, field := range gjson.Parse(attributes.Get("fields").String()).Array() {
+		if !live || field.Get("scripted").Bool() {
+			fields[field.Get("name").String()] = field.Get("type").String()
+		}
+	}
+	gjson.Parse(attributes.Get("runtimeFieldMap").String()).ForEach(func(name, field gjson.Result) bool {
+		fields[name.String()] = runtimeFieldType(field.Get("type").String())
+		return true
+	})
+	if !live {
+		return fields, nil
+	}
+	mapped, err := c.fieldsForWildcard(attributes.Get("title").String())
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range mapped {
+		fields[field.Get("name").String()] = field.Get("type").String()
+	}
+	return fields, nil
+}
+
+// runtimeFieldType converts the elasticsearch type of a runtime field to the
+// kibana field type.
+func runtimeFieldType(esType string) string {
+	switch esType {
+	case "long", "double":
+		return "number"
+	case "keyword":
+		return "string"
+	}
+	return esType
+}
+
+// fieldsForWildcard returns the fields mapped by the indices of the pattern,
+// with their name, type and whether they are aggregatable, through the data
+// views api of kibana 8 or else the legacy index patterns api.
+func (c *client) fieldsForWildcard(pattern string) ([]gjson.Result, error) {
+	query := url.Values{"pattern": {pattern}}.Encode()
+	var body []byte
+	for _, path := range []string{"/internal/data_views/_fields_for_wildcard?", "/api/index_patterns/_fields_for_wildcard?"} {
+		req, err := c.newRequest("GET", path+query, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, details, err := c.doRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusNotFound && strings.HasPrefix(path, "/internal/") {
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, c.apiError(fmt.Sprintf("retrieve the fields of %v", pattern), resp, details)
+		}
+		body = details
+		break
+	}
+	return gjson.GetBytes(body, "fields").Array(), nil
+}
+
+// checkFieldCompat reports the fields used by the visualizations of the export
+// which do not exist in their data view, or whose type does not suit the
+// aggregation using them.
+func (c *client) checkFieldCompat(export []byte, live bool) ([]lintFinding, error) {
+	dataViews := make(map[string]gjson.Result)
+	for _, object := range gjson.GetBytes(export, `objects.#(type=="index-pattern")#`).Array() {
+		dataViews[object.Get("id").String()] = object
+	}
+	fieldsByView := make(map[string]map[string]string)
+
+	var findings []lintFinding
+	for _, object := range gjson.GetBytes(export, "objects").Array() {
+		for _, use := range fieldUses(object) {
+			if use.DataView == "" {
+				continue
+			}
+			fields, ok := fieldsByView[use.DataView]
+			if !ok {
+				dataView, included := dataViews[use.DataView]
+				if !included {
+					if !live {
+						continue
+					}
+					raw, err := c.getIndexPatternByID(use.DataView)
+					if err != nil {
+						return nil, errors.Wrapf(err, "could not retrieve the data view of %v %v", object.Get("type").String(), object.Get("id").String())
+					}
+					dataView = gjson.ParseBytes(raw)
+				}
+				var err error
+				if fields, err = c.dataViewFields(dataView, live); err != nil {
+					return nil, err
+				}
+				fieldsByView[use.DataView] = fields
+			}
+			if len(fields) == 0 {
+				// data views saved without their field list can only be checked live
+				continue
+			}
+			fieldType, exists := fields[use.Field]
+			switch {
+			case !exists:
+				findings = append(findings, newFinding(object, severityError, "field-missing", "field %v does not exist in data view %v", use.Field, use.DataView))
+			case contains(numberOperations, use.Operation) && fieldType != "number":
+				findings = append(findings, newFinding(object, severityWarning, "field-type", "%v of %v field %v, a number field is expected", use.Operation, fieldType, use.Field))
+			case contains(dateOperations, use.Operation) && fieldType != "date" && fieldType != "date_nanos":
+				findings = append(findings, newFinding(object, severityWarning, "field-type", "%v of %v field %v, a date field is expected", use.Operation, fieldType, use.Field))
+			}
+		}
+	}
+	return findings, nil
+}
+
+func checkCompat(c *cli.Context) error {
+	path := c.Args().First()
+	if path == "" {
+		return cli.NewExitError("export file or directory missing", 1)
+	}
+	live := c.Bool("against-cluster")
+	if live {
+		if err := checkGlobals(c); err != nil {
+			return err
+		}
+	}
+	export, err := readBundle(path)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	findings, err := newClient().checkFieldCompat(export, live)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if errorCount := printFindings(findings); errorCount > 0 {
+		return cli.NewExitError(fmt.Sprintf("%d error(s) found", errorCount), 3)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// fieldSettings models the per field settings of an index-pattern which Kibana
+// stores as json encoded strings: the formatters of fieldFormatMap and the
+// custom labels and popularity counts of fieldAttrs.
+type fieldSettings struct {
+	Formats map[string]json.RawMessage
+	Attrs   map[string]map[string]json.RawMessage
+}
+
+func parseFieldSettings(attributes gjson.Result) (fieldSettings, error) {
+	settings := fieldSettings{
+		Formats: make(map[string]json.RawMessage),
+		Attrs:   make(map[string]map[string]json.RawMessage),
+	}
+	if err := decodeEncodedAttribute(attributes.Get("fieldFormatMap"), &settings.Formats); err != nil {
+		return settings, errors.Wrap(err, "invalid fieldFormatMap")
+	}
+	if err := decodeEncodedAttribute(attributes.Get("fieldAttrs"), &settings.Attrs); err != nil {
+		return settings, errors.Wrap(err, "invalid fieldAttrs")
+	}
+	return settings, nil
+}
+
+// decodeEncodedAttribute accepts both the json string stored by Kibana and a
+// plain json object, as written when editing an export by hand.
+func decodeEncodedAttribute(value gjson.Result, target interface{}) error {
+	switch {
+	case !value.Exists() || value.Type == gjson.Null:
+		return nil
+	case value.Type == gjson.String:
+		if value.String() == "" {
+			return nil
+		}
+		return json.Unmarshal([]byte(value.String()), target)
+	default:
+		return json.Unmarshal([]byte(value.Raw), target)
+	}
+}
+
+// merge overlays the incoming settings on the existing ones: formatters are
+// replaced per field and attributes per key, so that settings only present on
+// the target, such as popularity counts, survive the import.
+func (existing fieldSettings) merge(incoming fieldSettings) fieldSettings {
+	for field, format := range incoming.Formats {
+		existing.Formats[field] = format
+	}
+	for field, attrs := range incoming.Attrs {
+		if existing.Attrs[field] == nil {
+			existing.Attrs[field] = make(map[string]json.RawMessage)
+		}
+		for key, value := range attrs {
+			existing.Attrs[field][key] = value
+		}
+	}
+	return existing
+}
+
+// set stores the settings back as json encoded strings in the attributes at path.
+func (settings fieldSettings) set(payload []byte, path string) ([]byte, error) {
+	formats, err := json.Marshal(settings.Formats)
+	if err != nil {
+		return nil, err
+	}
+	payload, err = sjson.SetBytes(payload, path+".fieldFormatMap", string(formats))
+	if err != nil {
+		return nil, err
+	}
+	if len(settings.Attrs) == 0 && !gjson.GetBytes(payload, path+".fieldAttrs").Exists() {
+		return payload, nil
+	}
+	attrs, err := json.Marshal(settings.Attrs)
+	if err != nil {
+		return nil, err
+	}
+	return sjson.SetBytes(payload, path+".fieldAttrs", string(attrs))
+}
+
+// mergeFieldSettings merges the field settings of the index-patterns being
+// imported with the ones of the index-patterns already present on the target.
+func (c *client) mergeFieldSettings(payload []byte) ([]byte, error) {
+	for i, object := range gjson.GetBytes(payload, "objects").Array() {
+		if object.Get("type").String() != "index-pattern" {
+			continue
+		}
+		id := object.Get("id").String()
+		incoming, err := parseFieldSettings(object.Get("attributes"))
+		if err != nil {
+			return nil, errors.Wrapf(err, "index-pattern %v", id)
+		}
+
+		current, err := c.getObject("index-pattern", id)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil {
+			existing, err := parseFieldSettings(gjson.GetBytes(current, "attributes"))
+			if err != nil {
+				return nil, errors.Wrapf(err, "existing index-pattern %v", id)
+			}
+			c.Logger.Printf("merging field settings of index-pattern %v\n", id)
+			incoming = existing.merge(incoming)
+		}
+
+		payload, err = incoming.set(payload, fmt.Sprintf("objects.%d.attributes", i))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return payload, nil
+}
@@ -0,0 +1,112 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+// foundObject is a saved object matching a search, along with the space it
+// lives in and whether it matched by id or by title.
+type foundObject struct {
+	Space   string `json:"space"`
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	MatchBy string `json:"matchBy"`
+}
+
+// findByIDOrTitle returns the objects of the types whose id is the query or
+// whose title contains it, ignoring the case.
+func (c *client) findByIDOrTitle(types []string, query string) ([]foundObject, error) {
+	var found []foundObject
+	for _, t := range types {
+		object, err := c.getObject(t, query)
+		if err != nil {
+			return nil, err
+		}
+		if object != nil {
+			found = append(found, foundObject{Space: c.Space, Type: t, ID: query, Title: gjson.GetBytes(object, "attributes.title").String(), MatchBy: "id"})
+		}
+	}
+
+	objects, err := c.findObjects(types, query)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range objects {
+		if o.ID == query || !strings.Contains(strings.ToLower(o.title()), strings.ToLower(query)) {
+			continue
+		}
+		found = append(found, foundObject{Space: c.Space, Type: o.Type, ID: o.ID, Title: o.title(), MatchBy: "title"})
+	}
+	return found, nil
+}
+
+func findCommand(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	query := c.Args().First()
+	if query == "" {
+		return cli.NewExitError("id or title missing", 1)
+	}
+	types := savedObjectTypes
+	if list := c.String("type"); list != "" {
+		types = strings.Split(list, ",")
+	}
+
+	client := newClient()
+	spaces := []string{client.Space}
+	if c.Bool("all-spaces") {
+		all, err := client.listSpaces()
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		spaces = nil
+		for _, s := range all {
+			spaces = append(spaces, s.ID)
+		}
+	}
+	var found []foundObject
+	for _, space := range spaces {
+		client.Logger.Printf("searching space %v\n", space)
+		matches, err := client.inSpace(space).findByIDOrTitle(types, query)
+		if err != nil {
+			return cli.NewExitError(errors.Wrapf(err, "could not search space %v", space), 2)
+		}
+		found = append(found, matches...)
+	}
+
+	if jsonl() {
+		for _, f := range found {
+			line, err := json.Marshal(f)
+			if err != nil {
+				return cli.NewExitError(err, 2)
+			}
+			os.Stdout.Write(append(line, '\n'))
+		}
+	} else if quiet {
+		for _, f := range found {
+			printIDs(f.ID)
+		}
+	} else {
+		os.Stdout.WriteString(stdout.header(fmt.Sprintf("%-20v %-15v %-40v %v", "SPACE", "TYPE", "ID", "TITLE")) + "\n")
+		for _, f := range found {
+			space := f.Space
+			if space == "" {
+				space = "default"
+			}
+			fmt.Fprintf(os.Stdout, "%-20v %-15v %v %v\n", space, f.Type, stdout.paint(cyan, fmt.Sprintf("%-40v", f.ID)), f.Title)
+		}
+	}
+	if len(found) == 0 {
+		return cli.NewExitError(fmt.Sprintf("no object found matching %v", query), 2)
+	}
+	return nil
+}
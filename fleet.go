@@ -0,0 +1,117 @@
+package kibctl
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+const fleetPackagesAPI = "/api/fleet/epm/packages"
+
+// fleetPackage is a fleet integration package along with the kibana assets it
+// installed.
+type fleetPackage struct {
+	Name    string
+	Version string
+	Status  string
+	Assets  []dependency
+}
+
+// getPackage retrieves the fleet package. The installation details moved from
+// the package saved object to installationInfo in 8.12.
+func (c *client) getPackage(name string) (fleetPackage, error) {
+	body, err := c.jsonRequest("GET", fleetPackagesAPI+"/"+url.PathEscape(name), nil)
+	if err != nil {
+		return fleetPackage{}, err
+	}
+	item := gjson.GetBytes(body, "item")
+	if !item.Exists() {
+		// fleet up to 7.x returns the package at the top level
+		item = gjson.GetBytes(body, "response")
+	}
+	pkg := fleetPackage{Name: name, Status: item.Get("status").String()}
+	installation := item.Get("installationInfo")
+	if !installation.Exists() {
+		installation = item.Get("savedObject.attributes")
+	}
+	pkg.Version = installation.Get("version").String()
+	if pkg.Version == "" {
+		pkg.Version = item.Get("version").String()
+	}
+	for _, asset := range installation.Get("installed_kibana").Array() {
+		pkg.Assets = append(pkg.Assets, dependency{Type: asset.Get("type").String(), ID: asset.Get("id").String()})
+	}
+	sort.SliceStable(pkg.Assets, func(i, j int) bool {
+		if pkg.Assets[i].Type != pkg.Assets[j].Type {
+			return pkg.Assets[i].Type < pkg.Assets[j].Type
+		}
+		return pkg.Assets[i].ID < pkg.Assets[j].ID
+	})
+	return pkg, nil
+}
+
+// exportPackageDashboards exports the dashboards installed by the package with
+// their dependencies, annotated with the package name and version they were
+// forked from.
+func (c *client) exportPackageDashboards(pkg fleetPackage) ([]byte, error) {
+	var ids []string
+	for _, asset := range pkg.Assets {
+		if asset.Type == "dashboard" {
+			ids = append(ids, asset.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, errors.Errorf("package %v installed no dashboard.\n", pkg.Name)
+	}
+	export, err := c.exportDashboards(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return annotateAttribute(export, []annotation{
+		{Key: "fleet-package", Value: pkg.Name},
+		{Key: "fleet-package-version", Value: pkg.Version},
+	})
+}
+
+func fleetPackageAssets(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	name := c.Args().First()
+	if name == "" {
+		return cli.NewExitError("package name missing", 1)
+	}
+	client := newClient()
+	pkg, err := client.getPackage(name)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if pkg.Status != "" && pkg.Status != "installed" {
+		return cli.NewExitError(fmt.Sprintf("package %v is %v", name, pkg.Status), 2)
+	}
+
+	if c.Bool("export") {
+		export, err := client.exportPackageDashboards(pkg)
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		return writeExport(c, export)
+	}
+	if quiet {
+		for _, asset := range pkg.Assets {
+			printIDs(asset.ID)
+		}
+		return nil
+	}
+	fmt.Fprintf(os.Stdout, "package %v %v\n", pkg.Name, pkg.Version)
+	os.Stdout.WriteString(stdout.header(fmt.Sprintf("%-25v %v", "TYPE", "ID")) + "\n")
+	for _, asset := range pkg.Assets {
+		fmt.Fprintf(os.Stdout, "%-25v %v\n", asset.Type, stdout.paint(cyan, asset.ID))
+	}
+	return nil
+}
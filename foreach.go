@@ -0,0 +1,165 @@
+package kibctl
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/tidwall/sjson"
+	"github.com/urfave/cli"
+)
+
+// contextRun is the outcome of a command run against a context.
+type contextRun struct {
+	Context string
+	Output  bytes.Buffer
+	// Errors is the diagnostic output, kept apart from the events with jsonl output
+	Errors bytes.Buffer
+	Code   int
+	Err    error
+}
+
+// contextArgs returns the arguments running kibctl again with the command
+// against the context, along with the global flags of the safety and output
+// settings which the runs share.
+func contextArgs(name string, typeExtras, args []string) []string {
+	globals := []string{"--context", name, "--output", output}
+	for _, flag := range []struct {
+		name string
+		set  bool
+	}{
+		{"--verbose", verbose},
+		{"--quiet", quiet},
+		{"--read-only", readOnly},
+		{"--no-input", noInput},
+		{"--no-color", noColor},
+	} {
+		if flag.set {
+			globals = append(globals, flag.name)
+		}
+	}
+	globals = append(globals, "--max-response-mb", strconv.Itoa(maxResponseMB))
+	if cacheTTL > 0 {
+		globals = append(globals, "--cache-ttl", cacheTTL.String())
+	}
+	for _, extra := range typeExtras {
+		globals = append(globals, "--type-extra", extra)
+	}
+	return append(globals, args...)
+}
+
+// runInContext runs kibctl again with the arguments of contextArgs against the
+// context, the output of the run being collected.
+func runInContext(executable, name string, args []string, run *contextRun) {
+	run.Context = name
+	cmd := exec.Command(executable, args...)
+	cmd.Stdout = &run.Output
+	cmd.Stderr = &run.Output
+	if jsonl() {
+		cmd.Stderr = &run.Errors
+	}
+	if err := cmd.Run(); err != nil {
+		run.Err = err
+		run.Code = 2
+		if exit, ok := err.(*exec.ExitError); ok {
+			run.Code = exit.ExitCode()
+		}
+	}
+}
+
+// writeContextRun prints the output of the run under a header naming its
+// context, or with jsonl output adds the context to every event.
+func writeContextRun(run *contextRun) {
+	if jsonl() {
+		scanner := bufio.NewScanner(&run.Output)
+		scanner.Buffer(make([]byte, 64<<10), 64<<20)
+		for scanner.Scan() {
+			line, err := sjson.SetBytes(scanner.Bytes(), "context", run.Context)
+			if err != nil {
+				line = scanner.Bytes()
+			}
+			os.Stdout.Write(append(line, '\n'))
+		}
+		os.Stderr.Write(run.Errors.Bytes())
+		return
+	}
+	if !quiet {
+		os.Stdout.WriteString(stdout.header(fmt.Sprintf("== %v ==", run.Context)) + "\n")
+	}
+	os.Stdout.Write(run.Output.Bytes())
+}
+
+func foreach(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return cli.NewExitError("command to run missing, e.g. kibctl foreach --contexts dev,prod -- dashboard list", 1)
+	}
+	conf, err := loadConfig()
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	var names []string
+	if list := c.String("contexts"); list != "" {
+		names = strings.Split(list, ",")
+	} else {
+		for name := range conf.Contexts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	if len(names) == 0 {
+		return cli.NewExitError("no contexts to run the command against", 1)
+	}
+	for _, name := range names {
+		if _, err := conf.context(name); err != nil {
+			return cli.NewExitError(err, 1)
+		}
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+
+	typeExtras := c.GlobalStringSlice("type-extra")
+	runs := make([]contextRun, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		if !c.Bool("parallel") {
+			runInContext(executable, name, contextArgs(name, typeExtras, c.Args()), &runs[i])
+			writeContextRun(&runs[i])
+			continue
+		}
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			runInContext(executable, name, contextArgs(name, typeExtras, c.Args()), &runs[i])
+		}(i, name)
+	}
+	if c.Bool("parallel") {
+		wg.Wait()
+		for i := range runs {
+			writeContextRun(&runs[i])
+		}
+	}
+
+	// the failures are summarized with the highest exit code of the runs
+	code := 0
+	var failed []string
+	for _, run := range runs {
+		if run.Err != nil {
+			failed = append(failed, fmt.Sprintf("%v (exit %d)", run.Context, run.Code))
+			if run.Code > code {
+				code = run.Code
+			}
+		}
+	}
+	if code > 0 {
+		return cli.NewExitError(fmt.Sprintf("command failed in %d of %d contexts: %v", len(failed), len(runs), strings.Join(failed, ", ")), code)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package kibctl
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestContextArgs(t *testing.T) {
+	defer func(o string, v, q, r, i, n bool, mb int, ttl time.Duration) {
+		output, verbose, quiet, readOnly, noInput, noColor, maxResponseMB, cacheTTL = o, v, q, r, i, n, mb, ttl
+	}(output, verbose, quiet, readOnly, noInput, noColor, maxResponseMB, cacheTTL)
+
+	tests := []struct {
+		name  string
+		setup func()
+		want  []string
+	}{
+		{
+			"defaults",
+			func() {},
+			[]string{"--context", "prod", "--output", "text", "--max-response-mb", "64", "dashboard", "list"},
+		},
+		{
+			"safety and output flags",
+			func() { verbose, quiet, readOnly, noInput, noColor = true, true, true, true, true },
+			[]string{"--context", "prod", "--output", "text", "--verbose", "--quiet", "--read-only", "--no-input", "--no-color", "--max-response-mb", "64", "dashboard", "list"},
+		},
+		{
+			"cache and limits",
+			func() { maxResponseMB, cacheTTL = 0, 5*time.Minute },
+			[]string{"--context", "prod", "--output", "text", "--max-response-mb", "0", "--cache-ttl", "5m0s", "dashboard", "list"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output, verbose, quiet, readOnly, noInput, noColor, maxResponseMB, cacheTTL = "text", false, false, false, false, false, 64, 0
+			test.setup()
+			if got := contextArgs("prod", nil, []string{"dashboard", "list"}); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("contextArgs() = %v, want %v", got, test.want)
+			}
+		})
+	}
+	got := contextArgs("prod", []string{"canvas-workpad"}, nil)
+	if want := []string{"--type-extra", "canvas-workpad"}; !reflect.DeepEqual(got[len(got)-2:], want) {
+		t.Errorf("contextArgs() = %v, want the extra types passed on", got)
+	}
+}
@@ -0,0 +1,221 @@
+package kibctl
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+// dashboardTemplates are the preferred fields of the top terms panels by
+// template, each group contributing its first field found in the data view.
+// The generic template uses the first aggregatable keyword fields instead.
+var dashboardTemplates = map[string][][]string{
+	"generic": nil,
+	"weblogs": {
+		{"url.path", "url.original", "request", "request.keyword"},
+		{"http.response.status_code", "response", "response.keyword"},
+		{"source.ip", "client.ip", "clientip"},
+		{"user_agent.name", "agent", "agent.keyword"},
+		{"http.request.method", "method", "method.keyword"},
+		{"host.name", "host", "host.keyword"},
+	},
+}
+
+const maxTermsPanels = 6
+
+type generatedPanel struct {
+	Title    string
+	VisState map[string]interface{}
+	W, H     int
+}
+
+// termsFields picks the fields of the top terms panels of the template among
+// the aggregatable fields of the data view.
+func termsFields(fields []gjson.Result, template string) []string {
+	aggregatable := make(map[string]string)
+	var keywords []string
+	for _, field := range fields {
+		name := field.Get("name").String()
+		if !field.Get("aggregatable").Bool() || strings.HasPrefix(name, "_") {
+			continue
+		}
+		aggregatable[name] = field.Get("type").String()
+		if field.Get("type").String() == "string" {
+			keywords = append(keywords, name)
+		}
+	}
+
+	var picked []string
+	for _, group := range dashboardTemplates[template] {
+		for _, name := range group {
+			if _, ok := aggregatable[name]; ok {
+				picked = append(picked, name)
+				break
+			}
+		}
+	}
+	if len(dashboardTemplates[template]) == 0 {
+		picked = keywords
+	}
+	if len(picked) > maxTermsPanels {
+		picked = picked[:maxTermsPanels]
+	}
+	return picked
+}
+
+func countAgg() map[string]interface{} {
+	return map[string]interface{}{"id": "1", "enabled": true, "type": "count", "schema": "metric", "params": map[string]interface{}{}}
+}
+
+// generatePanels returns the panels of the starter dashboard: the event count
+// and histogram over the time field when the data view has one, then the top
+// terms of the fields.
+func generatePanels(timeField string, terms []string) []generatedPanel {
+	panels := []generatedPanel{{
+		Title:    "Events",
+		VisState: map[string]interface{}{"type": "metric", "params": map[string]interface{}{}, "aggs": []interface{}{countAgg()}},
+		W:        12, H: 8,
+	}}
+	if timeField != "" {
+		panels = append(panels, generatedPanel{
+			Title: "Events over time",
+			VisState: map[string]interface{}{"type": "histogram", "params": map[string]interface{}{}, "aggs": []interface{}{
+				countAgg(),
+				map[string]interface{}{"id": "2", "enabled": true, "type": "date_histogram", "schema": "segment",
+					"params": map[string]interface{}{"field": timeField, "interval": "auto", "min_doc_count": 1, "extended_bounds": map[string]interface{}{}}},
+			}},
+			W: 36, H: 8,
+		})
+	}
+	for _, field := range terms {
+		panels = append(panels, generatedPanel{
+			Title: "Top " + field,
+			VisState: map[string]interface{}{"type": "table", "params": map[string]interface{}{"perPage": 10}, "aggs": []interface{}{
+				countAgg(),
+				map[string]interface{}{"id": "2", "enabled": true, "type": "terms", "schema": "bucket",
+					"params": map[string]interface{}{"field": field, "size": 10, "order": "desc", "orderBy": "1"}},
+			}},
+			W: 24, H: 15,
+		})
+	}
+	return panels
+}
+
+// generateDashboard builds the export of a dashboard with a visualization per
+// panel on the data view. The ids derive from the data view and the title so
+// that generating the dashboard again updates it.
+func generateDashboard(dataViewID, title string, panels []generatedPanel) ([]byte, error) {
+	prefix := fmt.Sprintf("kibctl-%x", sha256.Sum256([]byte(dataViewID+"\x00"+title)))[:19]
+	searchSource, _ := json.Marshal(map[string]interface{}{
+		"query":        map[string]string{"query": "", "language": "kuery"},
+		"filter":       []interface{}{},
+		"indexRefName": "kibanaSavedObjectMeta.searchSourceJSON.index",
+	})
+
+	var objects []interface{}
+	var panelsJSON []interface{}
+	var references []reference
+	x, y, rowHeight := 0, 0, 0
+	for i, panel := range panels {
+		id := fmt.Sprintf("%v-%d", prefix, i)
+		panel.VisState["title"] = panel.Title
+		visState, err := json.Marshal(panel.VisState)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, map[string]interface{}{
+			"type": "visualization",
+			"id":   id,
+			"attributes": map[string]interface{}{
+				"title":                 panel.Title,
+				"visState":              string(visState),
+				"uiStateJSON":           "{}",
+				"description":           "",
+				"kibanaSavedObjectMeta": map[string]string{"searchSourceJSON": string(searchSource)},
+			},
+			"references": []reference{{Type: "index-pattern", ID: dataViewID, Name: "kibanaSavedObjectMeta.searchSourceJSON.index"}},
+		})
+
+		// panels fill rows of 48 columns
+		if x+panel.W > 48 {
+			x, y, rowHeight = 0, y+rowHeight, 0
+		}
+		index := fmt.Sprint(i + 1)
+		panelsJSON = append(panelsJSON, map[string]interface{}{
+			"panelIndex":       index,
+			"gridData":         map[string]interface{}{"x": x, "y": y, "w": panel.W, "h": panel.H, "i": index},
+			"embeddableConfig": map[string]interface{}{},
+			"panelRefName":     fmt.Sprintf("panel_%d", i),
+			"version":          "7.10.0",
+		})
+		references = append(references, reference{Type: "visualization", ID: id, Name: fmt.Sprintf("panel_%d", i)})
+		x += panel.W
+		if panel.H > rowHeight {
+			rowHeight = panel.H
+		}
+	}
+
+	encodedPanels, err := json.Marshal(panelsJSON)
+	if err != nil {
+		return nil, err
+	}
+	dashboardSource, _ := json.Marshal(map[string]interface{}{
+		"query":  map[string]string{"query": "", "language": "kuery"},
+		"filter": []interface{}{},
+	})
+	objects = append(objects, map[string]interface{}{
+		"type": "dashboard",
+		"id":   prefix,
+		"attributes": map[string]interface{}{
+			"title":                 title,
+			"description":           "generated by kibctl",
+			"panelsJSON":            string(encodedPanels),
+			"optionsJSON":           `{"useMargins":true,"hidePanelTitles":false}`,
+			"timeRestore":           false,
+			"kibanaSavedObjectMeta": map[string]string{"searchSourceJSON": string(dashboardSource)},
+		},
+		"references": references,
+	})
+	return json.MarshalIndent(map[string]interface{}{"objects": objects}, "", "  ")
+}
+
+func generateDashboardCommand(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	name := c.String("dataview")
+	if name == "" {
+		return cli.NewExitError("--dataview missing", 1)
+	}
+	template := c.String("template")
+	if _, ok := dashboardTemplates[template]; !ok {
+		return cli.NewExitError(fmt.Sprintf("unknown template %v, generic or weblogs expected", template), 1)
+	}
+	title := c.String("title")
+	if title == "" {
+		title = name + " overview"
+	}
+
+	client := newClient()
+	raw, err := client.getIndexPattern(name)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	dataView := gjson.ParseBytes(raw)
+	fields, err := client.fieldsForWildcard(dataView.Get("attributes.title").String())
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	panels := generatePanels(dataView.Get("attributes.timeFieldName").String(), termsFields(fields, template))
+	export, err := generateDashboard(dataView.Get("id").String(), title, panels)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	os.Stdout.Write(append(export, '\n'))
+	return nil
+}
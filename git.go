@@ -0,0 +1,179 @@
+package kibctl
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+// gitCommand runs git in the repository and returns its trimmed output.
+func gitCommand(repo string, args ...string) (string, error) {
+	var out, stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", errors.Errorf("git %v failed: %v %v.\n", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// checkoutBranch switches the repository to the branch, creating it from the
+// current HEAD when it does not exist yet.
+func checkoutBranch(repo, branch string) error {
+	if _, err := gitCommand(repo, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err != nil {
+		_, err = gitCommand(repo, "checkout", "-b", branch)
+		return err
+	}
+	_, err := gitCommand(repo, "checkout", branch)
+	return err
+}
+
+// exportMatching returns the normalized export of the dashboards matching the
+// glob pattern, as kept in git.
+func (c *client) exportMatching(pattern string) ([]byte, error) {
+	dashboards, err := c.listDashboards(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(dashboards) == 0 {
+		return nil, errors.Errorf("no dashboard found matching: %v.\n", pattern)
+	}
+	ids := make([]string, len(dashboards))
+	for i, d := range dashboards {
+		ids[i] = d.ID
+	}
+	export, err := c.exportDashboards(ids...)
+	if err != nil {
+		return nil, err
+	}
+	return normalizeExport(export)
+}
+
+// snapshot writes the normalized split export of the matching dashboards under
+// dir, replacing the previous snapshot.
+func (c *client) snapshot(pattern, dir string) error {
+	export, err := c.exportMatching(pattern)
+	if err != nil {
+		return err
+	}
+	if err := removeBundle(dir); err != nil {
+		return err
+	}
+	return splitBundle(export, dir, splitOptions{})
+}
+
+func gitSnapshot(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	repo, branch := c.String("repo"), c.String("branch")
+	if repo == "" {
+		return cli.NewExitError("repository missing", 1)
+	}
+	if _, err := gitCommand(repo, "rev-parse", "--is-inside-work-tree"); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	if branch != "" {
+		if err := checkoutBranch(repo, branch); err != nil {
+			return cli.NewExitError(err, 2)
+		}
+	}
+
+	client := newClient()
+	dir := filepath.Join(repo, c.String("path"))
+	if err := client.snapshot(c.Args().First(), dir); err != nil {
+		return cli.NewExitError(err, 2)
+	}
+
+	if _, err := gitCommand(repo, "add", "-A", "--", c.String("path")); err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	status, err := gitCommand(repo, "status", "--porcelain", "--", c.String("path"))
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if status == "" {
+		client.Logger.Printf("no change since the last snapshot\n")
+		return nil
+	}
+	message := c.String("message")
+	if message == "" {
+		message = fmt.Sprintf("Snapshot of %v", host)
+		if space != "" {
+			message += " space " + space
+		}
+	}
+	if _, err := gitCommand(repo, "commit", "-m", message, "--", c.String("path")); err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	commit, err := gitCommand(repo, "rev-parse", "--short", "HEAD")
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+
+	if c.Bool("push") {
+		args := []string{"push", c.String("remote")}
+		if branch != "" {
+			args = append(args, branch)
+		}
+		if _, err := gitCommand(repo, args...); err != nil {
+			return cli.NewExitError(err, 2)
+		}
+	}
+	if quiet {
+		os.Stdout.WriteString(commit + "\n")
+		return nil
+	}
+	fmt.Fprintf(os.Stdout, "committed snapshot %v\n", commit)
+	return nil
+}
+
+// readCommittedBundle reads the split export committed under path at the
+// revision, ignoring the uncommitted changes of the work tree.
+func readCommittedBundle(repo, revision, path string) ([]byte, error) {
+	tmp, err := ioutil.TempDir("", "kibctl-git-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	var archive, stderr bytes.Buffer
+	cmd := exec.Command("git", "-C", repo, "archive", "--format=tar", revision, "--", path)
+	cmd.Stdout = &archive
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Errorf("git archive %v failed: %v %v.\n", revision, err, strings.TrimSpace(stderr.String()))
+	}
+
+	reader := tar.NewReader(&archive)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "could not read git archive")
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not read git archive")
+		}
+		if err := writeBundleFile(tmp, header.Name, content); err != nil {
+			return nil, err
+		}
+	}
+	return joinBundle(filepath.Join(tmp, path))
+}
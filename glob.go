@@ -0,0 +1,93 @@
+package kibctl
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// globRegexp translates a glob pattern matching a whole title into a regular
+// expression: * matches any sequence, ? any character and [...] a character
+// class, negated with [!...] or [^...]. A backslash escapes the next character.
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+			}
+			expr.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case '[':
+			end := i + 1
+			if end < len(runes) && (runes[end] == '!' || runes[end] == '^') {
+				end++
+			}
+			if end < len(runes) && runes[end] == ']' {
+				end++
+			}
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, errors.Errorf("unterminated character class in %v", pattern)
+			}
+			class := string(runes[i+1 : end])
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.Replace(class, `\`, `\\`, -1) + "]")
+			i = end
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
+
+// globSearch derives a Kibana search narrowing the candidates of a glob
+// pattern: the literal words starting at a token boundary become prefix terms,
+// lowercased as wildcard terms are not analyzed.
+// Words following a wildcard may be the end of a token and are left to the
+// client-side filtering. An empty search means all titles are candidates.
+func globSearch(pattern string) string {
+	var terms []string
+	var word strings.Builder
+	safe := true
+	flush := func() {
+		if word.Len() > 0 && safe {
+			terms = append(terms, strings.ToLower(word.String())+"*")
+		}
+		word.Reset()
+	}
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '*' || r == '?' || r == '[':
+			flush()
+			safe = false
+			if r == '[' {
+				for i < len(runes) && runes[i] != ']' {
+					i++
+				}
+			}
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		default:
+			flush()
+			safe = r != '\\'
+		}
+	}
+	flush()
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,131 @@
+package kibctl
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+type graphNode struct {
+	Key   string
+	Type  string
+	Title string
+}
+
+type graphEdge struct {
+	From string
+	To   string
+}
+
+type dependencyGraph struct {
+	Nodes []graphNode
+	Edges []graphEdge
+}
+
+// buildGraph links the objects of an export through their dependencies.
+// Dependencies known only by title are matched against the exported
+// index-patterns, unresolved ones still show up as nodes of their own.
+func buildGraph(export []byte) dependencyGraph {
+	var graph dependencyGraph
+	nodes := make(map[string]struct{})
+	addNode := func(node graphNode) {
+		if _, ok := nodes[node.Key]; !ok {
+			nodes[node.Key] = struct{}{}
+			graph.Nodes = append(graph.Nodes, node)
+		}
+	}
+
+	objects := gjson.GetBytes(export, "objects").Array()
+	titles := make(map[string]string)
+	for _, object := range objects {
+		key := object.Get("type").String() + ":" + object.Get("id").String()
+		addNode(graphNode{Key: key, Type: object.Get("type").String(), Title: object.Get("attributes.title").String()})
+		if object.Get("type").String() == "index-pattern" {
+			titles[object.Get("attributes.title").String()] = key
+		}
+	}
+
+	for _, object := range objects {
+		from := object.Get("type").String() + ":" + object.Get("id").String()
+		for _, dep := range objectDependencies(object) {
+			to := dep.Type + ":" + dep.ID
+			if dep.ID == "" {
+				var ok bool
+				if to, ok = titles[dep.Title]; !ok {
+					to = dep.Type + ":" + dep.Title
+				}
+			}
+			addNode(graphNode{Key: to, Type: dep.Type, Title: dep.Title})
+			graph.Edges = append(graph.Edges, graphEdge{From: from, To: to})
+		}
+	}
+
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].From != graph.Edges[j].From {
+			return graph.Edges[i].From < graph.Edges[j].From
+		}
+		return graph.Edges[i].To < graph.Edges[j].To
+	})
+	return graph
+}
+
+func (n graphNode) label() string {
+	if n.Title == "" {
+		return n.Key
+	}
+	return n.Type + ": " + n.Title
+}
+
+func writeDot(w io.Writer, name string, graph dependencyGraph) {
+	fmt.Fprintf(w, "digraph %q {\n", name)
+	fmt.Fprintf(w, "  rankdir=LR;\n")
+	for _, node := range graph.Nodes {
+		fmt.Fprintf(w, "  %q [label=%q];\n", node.Key, node.label())
+	}
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(w, "  %q -> %q;\n", edge.From, edge.To)
+	}
+	fmt.Fprintf(w, "}\n")
+}
+
+func writeMermaid(w io.Writer, graph dependencyGraph) {
+	ids := make(map[string]string)
+	fmt.Fprintf(w, "graph LR\n")
+	for i, node := range graph.Nodes {
+		ids[node.Key] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(w, "  %v[\"%v\"]\n", ids[node.Key], strings.Replace(node.label(), `"`, "#quot;", -1))
+	}
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(w, "  %v --> %v\n", ids[edge.From], ids[edge.To])
+	}
+}
+
+func graph(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	name := c.Args().First()
+	if name == "" {
+		return cli.NewExitError("dashboard name missing", 1)
+	}
+	format := c.String("format")
+	if format != "dot" && format != "mermaid" {
+		return cli.NewExitError(fmt.Sprintf("unsupported graph format %v", format), 1)
+	}
+	dashboard, err := newClient().export(name)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+
+	if format == "mermaid" {
+		writeMermaid(os.Stdout, buildGraph(dashboard))
+	} else {
+		writeDot(os.Stdout, name, buildGraph(dashboard))
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package kibctl
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+// grepAttributes returns the paths of the attributes whose string values, or
+// their nested json strings such as the visState and panelsJSON, match.
+func grepAttributes(attributes gjson.Result, match func(string) bool) []string {
+	var paths []string
+	var walk func(path string, value gjson.Result)
+	walk = func(path string, value gjson.Result) {
+		switch {
+		case value.IsObject() || value.IsArray():
+			value.ForEach(func(key, child gjson.Result) bool {
+				childPath := key.String()
+				if path != "" {
+					childPath = path + "." + childPath
+				}
+				walk(childPath, child)
+				return true
+			})
+		case value.Type == gjson.String:
+			if match(value.String()) {
+				paths = append(paths, path)
+			}
+		}
+	}
+	walk("", attributes)
+	return paths
+}
+
+func grepObjects(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	pattern := c.Args().First()
+	if pattern == "" {
+		return cli.NewExitError("search pattern missing", 1)
+	}
+	match := func(text string) bool { return strings.Contains(text, pattern) }
+	if c.Bool("regex") || c.Bool("ignore-case") {
+		expr := pattern
+		if !c.Bool("regex") {
+			expr = regexp.QuoteMeta(pattern)
+		}
+		if c.Bool("ignore-case") {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return cli.NewExitError(fmt.Sprintf("invalid regular expression: %v", err), 1)
+		}
+		match = re.MatchString
+	}
+	types := savedObjectTypes
+	if list := c.String("types"); list != "" {
+		types = strings.Split(list, ",")
+	}
+
+	objects, err := newClient().findObjects(types, "")
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if !quiet {
+		os.Stdout.WriteString(stdout.header(fmt.Sprintf("%-15v %-40v %-40v %v", "TYPE", "ID", "TITLE", "ATTRIBUTES")) + "\n")
+	}
+	for _, o := range objects {
+		paths := grepAttributes(gjson.ParseBytes(o.Attributes), match)
+		if len(paths) == 0 {
+			continue
+		}
+		if quiet {
+			printIDs(o.ID)
+			continue
+		}
+		os.Stdout.WriteString(fmt.Sprintf("%-15v %v %-40v %v\n", o.Type, stdout.paint(cyan, fmt.Sprintf("%-40v", o.ID)), o.label(), strings.Join(paths, ", ")))
+	}
+	return nil
+}
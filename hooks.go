@@ -0,0 +1,152 @@
+package kibctl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+)
+
+// hook is run before or after an operation, either as a shell command reading
+// the operation summary on stdin or as a webhook receiving it as a POST.
+type hook struct {
+	Command string `json:"command"`
+	URL     string `json:"url"`
+	// Headers are sent along with the webhook requests, e.g. an authorization
+	Headers map[string]string `json:"headers"`
+}
+
+func (h hook) String() string {
+	if h.URL != "" {
+		return h.URL
+	}
+	return h.Command
+}
+
+// hooks are the hooks of the config file by operation stage, the import ones
+// also running around the applies.
+type hooks struct {
+	PreImport  []hook `json:"preImport"`
+	PostImport []hook `json:"postImport"`
+}
+
+// hookTimeout bounds the run of a hook, so that a hung webhook does not
+// block the deployment.
+const hookTimeout = 30 * time.Second
+
+type summaryObject struct {
+	Type  string `json:"type"`
+	ID    string `json:"id"`
+	Title string `json:"title,omitempty"`
+}
+
+// operationSummary is the json the hooks receive.
+type operationSummary struct {
+	Operation string          `json:"operation"`
+	Stage     string          `json:"stage"`
+	Time      time.Time       `json:"time"`
+	Host      string          `json:"host"`
+	Space     string          `json:"space"`
+	Context   string          `json:"context,omitempty"`
+	Objects   []summaryObject `json:"objects"`
+	// Status is succeeded or failed after the operation
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// ApprovedBy is the approver of the applies requiring an approval
+	ApprovedBy string `json:"approvedBy,omitempty"`
+}
+
+func newOperationSummary(operation, stage string, payload []byte, err error) operationSummary {
+	summary := operationSummary{
+		Operation: operation,
+		Stage:     stage,
+		Time:      time.Now().UTC(),
+		Host:      publicHost(host),
+		Space:     spaceID(space),
+		Context:   contextName,
+		Objects:   []summaryObject{},
+	}
+	for _, object := range gjson.GetBytes(payload, "objects").Array() {
+		summary.Objects = append(summary.Objects, summaryObject{
+			Type:  object.Get("type").String(),
+			ID:    object.Get("id").String(),
+			Title: object.Get("attributes.title").String(),
+		})
+	}
+	if stage == "post" {
+		summary.Status = "succeeded"
+		if err != nil {
+			summary.Status, summary.Error = "failed", err.Error()
+		}
+	}
+	return summary
+}
+
+func (h hook) run(summary []byte) error {
+	if h.URL != "" {
+		req, err := http.NewRequest("POST", h.URL, bytes.NewReader(summary))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for name, value := range h.Headers {
+			req.Header.Set(name, value)
+		}
+		resp, err := (&http.Client{Timeout: hookTimeout}).Do(req)
+		if err != nil {
+			return err
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return errors.Errorf("webhook responded %v: %v.\n", resp.Status, string(body))
+		}
+		return nil
+	}
+	cmd := exec.Command("sh", "-c", h.Command)
+	cmd.Stdin = bytes.NewReader(summary)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(hookTimeout):
+		cmd.Process.Kill()
+		return errors.Errorf("timed out after %v.\n", hookTimeout)
+	}
+}
+
+// runHooks runs the hooks one after the other with the summary, stopping at
+// the first failure.
+func runHooks(list []hook, summary operationSummary) error {
+	content, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	for _, h := range list {
+		if err := h.run(content); err != nil {
+			return errors.Wrapf(err, "%v hook %v failed", summary.Stage, h)
+		}
+	}
+	return nil
+}
+
+// runPostHooks runs the hooks after the operation, only warning when they
+// fail as the operation is done already.
+func runPostHooks(list []hook, summary operationSummary) {
+	if err := runHooks(list, summary); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+}
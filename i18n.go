@@ -0,0 +1,217 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"github.com/urfave/cli"
+)
+
+// catalog is the string catalog of the user-facing strings of an export and
+// their translations, by object ("type:id") and string path. The paths of the
+// strings held by json attributes join the attribute and the path inside it
+// with a #, e.g. attributes.visState#params.markdown.
+type catalog struct {
+	Strings      map[string]map[string]string            `json:"strings"`
+	Translations map[string]map[string]map[string]string `json:"translations,omitempty"`
+}
+
+// i18nStrings returns the user-facing strings of the object by path: its
+// title and description, the titles and markdown of the visualizations and
+// the custom titles of the dashboard panels.
+func i18nStrings(object gjson.Result) map[string]string {
+	found := make(map[string]string)
+	attributes := object.Get("attributes")
+	for _, path := range []string{"title", "description"} {
+		if value := attributes.Get(path).String(); value != "" {
+			found["attributes."+path] = value
+		}
+	}
+	if visState := attributes.Get("visState").String(); visState != "" {
+		for _, path := range []string{"title", markdownPath} {
+			if value := gjson.Get(visState, path).String(); value != "" {
+				found["attributes.visState#"+path] = value
+			}
+		}
+	}
+	for i, panel := range gjson.Parse(attributes.Get("panelsJSON").String()).Array() {
+		if value := panel.Get("embeddableConfig.title").String(); value != "" {
+			found[fmt.Sprintf("attributes.panelsJSON#%d.embeddableConfig.title", i)] = value
+		}
+	}
+	return found
+}
+
+// setI18nString replaces the string of the object at the catalog path.
+func setI18nString(object []byte, path, value string) ([]byte, error) {
+	parts := strings.SplitN(path, "#", 2)
+	if len(parts) == 1 {
+		return sjson.SetBytes(object, path, value)
+	}
+	inner, err := sjson.Set(gjson.GetBytes(object, parts[0]).String(), parts[1], value)
+	if err != nil {
+		return nil, err
+	}
+	return sjson.SetBytes(object, parts[0], inner)
+}
+
+func loadCatalog(file string) (*catalog, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read translations file")
+	}
+	var result catalog
+	if err := json.Unmarshal(content, &result); err != nil {
+		return nil, errors.Wrap(err, "could not parse translations file")
+	}
+	return &result, nil
+}
+
+// extractCatalog updates the catalog with the strings of the export, keeping
+// the translations already made. The strings new to the catalog are added
+// untranslated to every locale, for the translators to fill.
+func extractCatalog(existing *catalog, export []byte, locales []string) *catalog {
+	result := &catalog{Strings: make(map[string]map[string]string), Translations: make(map[string]map[string]map[string]string)}
+	if existing != nil && existing.Translations != nil {
+		result.Translations = existing.Translations
+	}
+	for _, object := range gjson.GetBytes(export, "objects").Array() {
+		if found := i18nStrings(object); len(found) > 0 {
+			result.Strings[object.Get("type").String()+":"+object.Get("id").String()] = found
+		}
+	}
+	for _, locale := range locales {
+		if _, ok := result.Translations[locale]; !ok {
+			result.Translations[locale] = make(map[string]map[string]string)
+		}
+	}
+	for _, translations := range result.Translations {
+		for key, paths := range result.Strings {
+			if translations[key] == nil {
+				translations[key] = make(map[string]string)
+			}
+			for path := range paths {
+				if _, ok := translations[key][path]; !ok {
+					translations[key][path] = ""
+				}
+			}
+		}
+	}
+	return result
+}
+
+// applyLocale replaces the strings of the export with their translation in
+// the locale, and returns the strings left untranslated, along with the ones
+// which changed since their translation was extracted.
+func applyLocale(export []byte, translations *catalog, locale string) ([]byte, []string, error) {
+	localized, ok := translations.Translations[locale]
+	if !ok {
+		return nil, nil, errors.Errorf("no %v translations in the translations file.\n", locale)
+	}
+	var warnings []string
+	for i, object := range gjson.GetBytes(export, "objects").Array() {
+		key := object.Get("type").String() + ":" + object.Get("id").String()
+		found := i18nStrings(object)
+		paths := make([]string, 0, len(found))
+		for path := range found {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		raw := []byte(object.Raw)
+		for _, path := range paths {
+			translation := localized[key][path]
+			if translation == "" {
+				warnings = append(warnings, fmt.Sprintf("%v %v untranslated", key, path))
+				continue
+			}
+			if source, ok := translations.Strings[key][path]; ok && source != found[path] {
+				warnings = append(warnings, fmt.Sprintf("%v %v changed since its translation", key, path))
+			}
+			var err error
+			if raw, err = setI18nString(raw, path, translation); err != nil {
+				return nil, nil, err
+			}
+		}
+		var err error
+		if export, err = sjson.SetRawBytes(export, fmt.Sprintf("objects.%d", i), raw); err != nil {
+			return nil, nil, err
+		}
+	}
+	return export, warnings, nil
+}
+
+// localize applies the locale of the translations file to the payload,
+// warning about the strings left in the source language.
+func localize(payload []byte, file, locale string) ([]byte, error) {
+	translations, err := loadCatalog(file)
+	if err != nil {
+		return nil, err
+	}
+	payload, warnings, err := applyLocale(payload, translations, locale)
+	if err != nil {
+		return nil, err
+	}
+	if !quiet {
+		for _, warning := range warnings {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", warning)
+		}
+	}
+	return payload, nil
+}
+
+func i18nExtract(c *cli.Context) error {
+	path := c.Args().First()
+	if path == "" {
+		return cli.NewExitError("export file or directory missing", 1)
+	}
+	file := c.String("translations")
+	export, err := readBundle(path)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	var existing *catalog
+	if _, statErr := os.Stat(file); statErr == nil {
+		if existing, err = loadCatalog(file); err != nil {
+			return cli.NewExitError(err, 1)
+		}
+	}
+	var locales []string
+	if list := c.String("locale"); list != "" {
+		locales = strings.Split(list, ",")
+	}
+	content, err := json.MarshalIndent(extractCatalog(existing, export, locales), "", "  ")
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if err := ioutil.WriteFile(file, append(content, '\n'), 0644); err != nil {
+		return cli.NewExitError(errors.Wrapf(err, "could not write %v", file), 2)
+	}
+	return nil
+}
+
+func i18nApply(c *cli.Context) error {
+	path := c.Args().First()
+	if path == "" {
+		return cli.NewExitError("export file or directory missing", 1)
+	}
+	if c.String("locale") == "" {
+		return cli.NewExitError("--locale missing", 1)
+	}
+	export, err := readBundle(path)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	localized, err := localize(export, c.String("translations"), c.String("locale"))
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	os.Stdout.Write(localized)
+	return nil
+}
@@ -0,0 +1,32 @@
+package kibctl
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli"
+)
+
+// exitInputRequired is the exit code of the commands failing with --no-input
+// instead of waiting for the user.
+const exitInputRequired = 4
+
+var noInput bool
+
+// requireInput fails when --no-input is given, as the action would wait for
+// the user.
+func requireInput(action string) error {
+	if !noInput {
+		return nil
+	}
+	return cli.NewExitError(fmt.Sprintf("%v requires user input, which --no-input forbids", action), exitInputRequired)
+}
+
+// requireStdin fails when --no-input is given and stdin is a terminal rather
+// than a pipe or a file, as reading it would wait for the user to type.
+func requireStdin(action string) error {
+	if !isTerminal(os.Stdin) {
+		return nil
+	}
+	return requireInput(action)
+}
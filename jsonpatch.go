@@ -0,0 +1,234 @@
+package kibctl
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// applyJSONPatch applies a json patch (RFC 6902) to the document. As with
+// mergePatch, pointers may go through json encoded string attributes, e.g.
+// /attributes/optionsJSON/useMargins.
+func applyJSONPatch(document interface{}, patch interface{}) (interface{}, error) {
+	operations, ok := patch.([]interface{})
+	if !ok {
+		return nil, errors.New("json patch must be an array of operations")
+	}
+	for i, item := range operations {
+		operation, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("operation %d is not an object", i)
+		}
+		op, _ := operation["op"].(string)
+		path, _ := operation["path"].(string)
+		from, _ := operation["from"].(string)
+		value, hasValue := operation["value"]
+
+		var err error
+		switch op {
+		case "add", "replace", "test":
+			if !hasValue {
+				return nil, errors.Errorf("operation %d: %v requires a value", i, op)
+			}
+		}
+		switch op {
+		case "add":
+			document, err = patchAdd(document, path, value)
+		case "remove":
+			document, err = patchRemove(document, path)
+		case "replace":
+			document, err = patchRemove(document, path)
+			if err == nil {
+				document, err = patchAdd(document, path, value)
+			}
+		case "move":
+			var moved interface{}
+			moved, err = patchGet(document, from)
+			if err == nil {
+				document, err = patchRemove(document, from)
+			}
+			if err == nil {
+				document, err = patchAdd(document, path, moved)
+			}
+		case "copy":
+			var copied interface{}
+			copied, err = patchGet(document, from)
+			if err == nil {
+				document, err = patchAdd(document, path, copied)
+			}
+		case "test":
+			var current interface{}
+			current, err = patchGet(document, path)
+			if err == nil && !reflect.DeepEqual(current, value) {
+				err = errors.Errorf("test failed, %v does not match the expected value", path)
+			}
+		default:
+			err = errors.Errorf("unsupported operation %q", op)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "operation %d", i)
+		}
+	}
+	return document, nil
+}
+
+func pointerTokens(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, errors.Errorf("invalid json pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return length, nil
+	}
+	index, err := strconv.Atoi(token)
+	max := length - 1
+	if allowEnd {
+		max = length
+	}
+	if err != nil || index < 0 || index > max {
+		return 0, errors.Errorf("invalid array index %v", token)
+	}
+	return index, nil
+}
+
+// patchUpdate walks the document down to the parent of the last token and
+// replaces it by the result of the update function.
+func patchUpdate(document interface{}, tokens []string, update func(parent interface{}, key string) (interface{}, error)) (interface{}, error) {
+	if encoded, ok := document.(string); ok {
+		decoded, err := decodeJSON(encoded)
+		if err != nil {
+			return nil, errors.New("path goes through a value which is not a json document")
+		}
+		updated, err := patchUpdate(decoded, tokens, update)
+		if err != nil {
+			return nil, err
+		}
+		return encodeJSON(updated)
+	}
+	if len(tokens) == 1 {
+		return update(document, tokens[0])
+	}
+
+	switch parent := document.(type) {
+	case map[string]interface{}:
+		child, ok := parent[tokens[0]]
+		if !ok {
+			return nil, errors.Errorf("missing member %v", tokens[0])
+		}
+		updated, err := patchUpdate(child, tokens[1:], update)
+		if err != nil {
+			return nil, err
+		}
+		parent[tokens[0]] = updated
+		return parent, nil
+	case []interface{}:
+		index, err := arrayIndex(tokens[0], len(parent), false)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := patchUpdate(parent[index], tokens[1:], update)
+		if err != nil {
+			return nil, err
+		}
+		parent[index] = updated
+		return parent, nil
+	}
+	return nil, errors.Errorf("cannot traverse %v", tokens[0])
+}
+
+func patchGet(document interface{}, pointer string) (interface{}, error) {
+	tokens, err := pointerTokens(pointer)
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range tokens {
+		if encoded, ok := document.(string); ok {
+			if document, err = decodeJSON(encoded); err != nil {
+				return nil, errors.Errorf("%v goes through a value which is not a json document", pointer)
+			}
+		}
+		switch current := document.(type) {
+		case map[string]interface{}:
+			value, ok := current[token]
+			if !ok {
+				return nil, errors.Errorf("missing member %v", pointer)
+			}
+			document = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(current), false)
+			if err != nil {
+				return nil, err
+			}
+			document = current[index]
+		default:
+			return nil, errors.Errorf("cannot traverse %v", pointer)
+		}
+	}
+	return document, nil
+}
+
+func patchAdd(document interface{}, pointer string, value interface{}) (interface{}, error) {
+	tokens, err := pointerTokens(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return patchUpdate(document, tokens, func(parent interface{}, key string) (interface{}, error) {
+		switch container := parent.(type) {
+		case map[string]interface{}:
+			container[key] = value
+			return container, nil
+		case []interface{}:
+			index, err := arrayIndex(key, len(container), true)
+			if err != nil {
+				return nil, err
+			}
+			container = append(container, nil)
+			copy(container[index+1:], container[index:])
+			container[index] = value
+			return container, nil
+		}
+		return nil, errors.Errorf("cannot add to %v", pointer)
+	})
+}
+
+func patchRemove(document interface{}, pointer string) (interface{}, error) {
+	tokens, err := pointerTokens(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("cannot remove the whole document")
+	}
+	return patchUpdate(document, tokens, func(parent interface{}, key string) (interface{}, error) {
+		switch container := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := container[key]; !ok {
+				return nil, errors.Errorf("missing member %v", pointer)
+			}
+			delete(container, key)
+			return container, nil
+		case []interface{}:
+			index, err := arrayIndex(key, len(container), false)
+			if err != nil {
+				return nil, err
+			}
+			return append(container[:index], container[index+1:]...), nil
+		}
+		return nil, errors.Errorf("cannot remove %v", pointer)
+	})
+}
@@ -0,0 +1,49 @@
+package kibctl
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// junitSuite is a JUnit XML test suite, as rendered by the CI servers.
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	ClassName string         `xml:"classname,attr"`
+	Name      string         `xml:"name,attr"`
+	Failures  []junitFailure `xml:"failure"`
+	SystemOut string         `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes the test cases as a JUnit XML report.
+func writeJUnit(file, name string, cases []junitCase) error {
+	suite := junitSuite{Name: name, Tests: len(cases), Cases: cases}
+	for _, c := range cases {
+		if len(c.Failures) > 0 {
+			suite.Failures++
+		}
+	}
+	content, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return err
+	}
+	content = append([]byte(xml.Header), append(content, '\n')...)
+	if err := ioutil.WriteFile(file, content, 0644); err != nil {
+		return errors.Wrap(err, "could not write report")
+	}
+	return nil
+}
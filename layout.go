@@ -0,0 +1,109 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"github.com/urfave/cli"
+)
+
+// gridColumns is the width of the kibana dashboard grid.
+const gridColumns = 48
+
+// layoutPanels lays the panels out in reading order on a uniform grid of the
+// given number of columns, every panel being of the given height. The last
+// column takes the remaining width when the grid does not divide evenly.
+func layoutPanels(panelsJSON string, columns, height int) (string, error) {
+	var panels []map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(panelsJSON))
+	decoder.UseNumber()
+	if err := decoder.Decode(&panels); err != nil {
+		return "", err
+	}
+	sort.SliceStable(panels, func(i, j int) bool {
+		rowI, colI := panelPosition(panels[i])
+		rowJ, colJ := panelPosition(panels[j])
+		if rowI != rowJ {
+			return rowI < rowJ
+		}
+		return colI < colJ
+	})
+
+	width := gridColumns / columns
+	for i, panel := range panels {
+		column := i % columns
+		w := width
+		if column == columns-1 {
+			w = gridColumns - column*width
+		}
+		grid, ok := panel["gridData"].(map[string]interface{})
+		if !ok {
+			// legacy 6.x panels positioned by row and col
+			grid = map[string]interface{}{"i": fmt.Sprint(panel["panelIndex"])}
+			for _, key := range []string{"row", "col", "size_x", "size_y"} {
+				delete(panel, key)
+			}
+			panel["gridData"] = grid
+		}
+		grid["x"], grid["y"], grid["w"], grid["h"] = column*width, (i/columns)*height, w, height
+	}
+	return encodeJSON(panels)
+}
+
+// layoutDashboard rewrites the panel grid of the dashboard in place, the
+// update failing when the dashboard changed meanwhile unless forced.
+func (c *client) layoutDashboard(id string, columns, height int, force bool) error {
+	current, err := c.getObject("dashboard", id)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return errors.Errorf("no dashboard found with id: %v.\n", id)
+	}
+	panels, err := layoutPanels(gjson.GetBytes(current, "attributes.panelsJSON").String(), columns, height)
+	if err != nil {
+		return errors.Wrapf(err, "could not parse the panels of dashboard %v", id)
+	}
+	attributes, err := sjson.Set(gjson.GetBytes(current, "attributes").Raw, "panelsJSON", panels)
+	if err != nil {
+		return err
+	}
+	version := gjson.GetBytes(current, "version").String()
+	if force {
+		version = ""
+	}
+	return c.updateObject("dashboard", id, version, attributes, gjson.GetBytes(current, "references").Raw)
+}
+
+func layoutDashboardCommand(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	name := c.Args().First()
+	if name == "" {
+		return cli.NewExitError("dashboard name missing", 1)
+	}
+	columns, height := c.Int("columns"), c.Int("panel-height")
+	if columns < 1 || columns > gridColumns {
+		return cli.NewExitError(fmt.Sprintf("invalid --columns %d, 1 to %d expected", columns, gridColumns), 1)
+	}
+	if height < 1 {
+		return cli.NewExitError(fmt.Sprintf("invalid --panel-height %d", height), 1)
+	}
+
+	client := newClient()
+	dashboard, err := client.findDashboard(name)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if err := client.layoutDashboard(dashboard.ID, columns, height, c.Bool("force")); err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	printIDs(dashboard.ID)
+	return nil
+}
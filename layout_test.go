@@ -0,0 +1,129 @@
+package kibctl
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestLayoutPanels(t *testing.T) {
+	tests := []struct {
+		name    string
+		panels  string
+		columns int
+		height  int
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "reading order",
+			panels:  `[{"panelIndex":"2","gridData":{"x":24,"y":0,"w":24,"h":10,"i":"2"}},{"panelIndex":"1","gridData":{"x":0,"y":0,"w":12,"h":5,"i":"1"}},{"panelIndex":"3","gridData":{"x":0,"y":10,"w":48,"h":8,"i":"3"}}]`,
+			columns: 2,
+			height:  12,
+			want:    []string{"1:0,0,24,12", "2:24,0,24,12", "3:0,12,24,12"},
+		},
+		{
+			name:    "uneven grid",
+			panels:  `[{"panelIndex":"1","gridData":{"x":0,"y":0,"w":8,"h":8,"i":"1"}},{"panelIndex":"2","gridData":{"x":8,"y":0,"w":8,"h":8,"i":"2"}},{"panelIndex":"3","gridData":{"x":16,"y":0,"w":8,"h":8,"i":"3"}},{"panelIndex":"4","gridData":{"x":24,"y":0,"w":8,"h":8,"i":"4"}},{"panelIndex":"5","gridData":{"x":32,"y":0,"w":8,"h":8,"i":"5"}}]`,
+			columns: 5,
+			height:  10,
+			want:    []string{"1:0,0,9,10", "2:9,0,9,10", "3:18,0,9,10", "4:27,0,9,10", "5:36,0,12,10"},
+		},
+		{
+			name:    "legacy panels",
+			panels:  `[{"panelIndex":2,"row":1,"col":7,"size_x":6,"size_y":3},{"panelIndex":1,"row":1,"col":1,"size_x":6,"size_y":3}]`,
+			columns: 1,
+			height:  15,
+			want:    []string{"1:0,0,48,15", "2:0,15,48,15"},
+		},
+		{
+			name:    "invalid panels",
+			panels:  `{`,
+			columns: 2,
+			height:  15,
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := layoutPanels(test.panels, test.columns, test.height)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("layoutPanels() error = %v, want error %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			panels := gjson.Parse(got).Array()
+			if len(panels) != len(test.want) {
+				t.Fatalf("layoutPanels() = %v, want %d panels", got, len(test.want))
+			}
+			for i, panel := range panels {
+				grid := panel.Get("gridData")
+				position := grid.Get("i").String() + ":" + grid.Get("x").Raw + "," + grid.Get("y").Raw + "," + grid.Get("w").Raw + "," + grid.Get("h").Raw
+				if position != test.want[i] {
+					t.Errorf("panel %d laid out at %v, want %v", i, position, test.want[i])
+				}
+				if panel.Get("row").Exists() || panel.Get("size_x").Exists() {
+					t.Errorf("panel %d kept its legacy position: %v", i, panel.Raw)
+				}
+			}
+		})
+	}
+}
+
+func TestLayoutDashboardUpdate(t *testing.T) {
+	const dashboard = `{"id":"d1","type":"dashboard","version":"WzEsMV0=","attributes":{"title":"Nginx",` +
+		`"panelsJSON":"[{\"panelIndex\":\"1\",\"gridData\":{\"x\":7,\"y\":3,\"w\":5,\"h\":4,\"i\":\"1\"}}]"},` +
+		`"references":[{"name":"panel_0","type":"visualization","id":"v1"}]}`
+	tests := []struct {
+		name     string
+		force    bool
+		conflict bool
+		version  string
+		want     string
+	}{
+		{"checked update", false, false, "WzEsMV0=", ""},
+		{"changed meanwhile", false, true, "WzEsMV0=", "object changed since you exported it"},
+		{"forced", true, false, "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var update []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case "GET":
+					w.Write([]byte(dashboard))
+				case "PUT":
+					update, _ = ioutil.ReadAll(r.Body)
+					if test.conflict {
+						w.WriteHeader(http.StatusConflict)
+					}
+					w.Write([]byte(`{}`))
+				}
+			}))
+			defer server.Close()
+
+			err := newTestClient(server).layoutDashboard("d1", 2, 10, test.force)
+			switch {
+			case test.want == "" && err != nil:
+				t.Fatalf("layoutDashboard() error: %v", err)
+			case test.want != "" && (err == nil || !strings.Contains(err.Error(), test.want)):
+				t.Fatalf("layoutDashboard() error = %v, want %v", err, test.want)
+			}
+			if version := gjson.GetBytes(update, "version").String(); version != test.version {
+				t.Errorf("updated version %q, want %q", version, test.version)
+			}
+			grid := gjson.Get(gjson.GetBytes(update, "attributes.panelsJSON").String(), "0.gridData")
+			if grid.Get("x").Int() != 0 || grid.Get("y").Int() != 0 || grid.Get("w").Int() != 24 || grid.Get("h").Int() != 10 {
+				t.Errorf("updated grid %v, want the panel laid out at 0,0 24x10", grid.Raw)
+			}
+			if id := gjson.GetBytes(update, "references.0.id").String(); id != "v1" {
+				t.Errorf("updated references %v, want them kept", gjson.GetBytes(update, "references").Raw)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package kibctl
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// readBody reads the response body in memory up to the limit, failing past it
+// without reading on rather than letting an oversized response exhaust the
+// memory. The body is never limited when the limit is 0.
+func readBody(req *http.Request, resp *http.Response, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return ioutil.ReadAll(resp.Body)
+	}
+	if resp.ContentLength > limit {
+		return nil, errTooLarge(req, limit)
+	}
+	// one byte past the limit tells a body of exactly the maximum size from
+	// a larger one
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, errTooLarge(req, limit)
+	}
+	return body, nil
+}
+
+func errTooLarge(req *http.Request, limit int64) error {
+	return errors.Errorf("response of %v %v larger than --max-response-mb %d, raise it to read the response.\n", req.Method, req.URL.Path, limit>>20)
+}
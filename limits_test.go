@@ -0,0 +1,78 @@
+package kibctl
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestReadBody(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int
+		length int64
+		limit  int64
+		fails  bool
+	}{
+		{"empty", 0, -1, 16, false},
+		{"under the limit", 15, -1, 16, false},
+		{"at the limit", 16, -1, 16, false},
+		{"past the limit", 1000, -1, 16, true},
+		{"declared past the limit", 10, 1000, 16, true},
+		{"no limit", 1000, -1, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/saved_objects/_find", nil)
+			content := strings.Repeat("x", test.size)
+			resp := &http.Response{Body: ioutil.NopCloser(strings.NewReader(content)), ContentLength: test.length}
+			body, err := readBody(req, resp, test.limit)
+			if (err != nil) != test.fails {
+				t.Fatalf("readBody() error = %v, want failure %v", err, test.fails)
+			}
+			if err == nil && !bytes.Equal(body, []byte(content)) {
+				t.Errorf("readBody() read %d bytes, want %d", len(body), len(content))
+			}
+		})
+	}
+}
+
+func TestDoRequestMaxResponseSize(t *testing.T) {
+	const total = 256 << 20
+	chunk := bytes.Repeat([]byte("x"), 32<<10)
+	var mu sync.Mutex
+	written := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// streamed without a content length, only the reads tell its size
+		for sent := 0; sent < total; sent += len(chunk) {
+			n, err := w.Write(chunk)
+			mu.Lock()
+			written += n
+			mu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}))
+	c := newTestClient(server)
+	c.MaxResponseSize = 1 << 20
+	req, err := c.newRequest("GET", "/api/saved_objects/_find", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, body, err := c.doRequest(req)
+	if err == nil || !strings.Contains(err.Error(), "--max-response-mb") {
+		t.Errorf("doRequest() error = %v, want the response rejected past --max-response-mb", err)
+	}
+	if body != nil {
+		t.Errorf("doRequest() returned %d bytes of the oversized response", len(body))
+	}
+	server.Close()
+	if written >= total {
+		t.Errorf("doRequest() read the whole %d MB response", total>>20)
+	}
+}
@@ -0,0 +1,87 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// linkMap maps the hosts and dashboard ids of the source environment to the
+// ones of the target environment.
+type linkMap struct {
+	Hosts      map[string]string `json:"hosts"`
+	Dashboards map[string]string `json:"dashboards"`
+}
+
+func loadLinkMap(file string) (*linkMap, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read link map")
+	}
+	var links linkMap
+	if err := json.Unmarshal(content, &links); err != nil {
+		return nil, errors.Wrapf(err, "could not parse link map %v", file)
+	}
+	return &links, nil
+}
+
+// dashboardLink matches the dashboard id of the dashboard urls, legacy and
+// current, and of the dashboard drilldowns, even within escaped json strings.
+const dashboardLink = `(/app/dashboards#/view/|/app/kibana#/dashboard/|dashboardId\\*"\s*:\s*\\*")%v([^A-Za-z0-9_-]|$)`
+
+// rewriteLinks rewrites the urls of the markdown panels and drilldowns of the
+// payload objects from the source hosts and dashboard ids to the target ones,
+// as well as the dashboard references of the drilldowns. The ids of the
+// objects themselves are left untouched.
+func rewriteLinks(payload []byte, links *linkMap) ([]byte, error) {
+	// the longest hosts are replaced first so that a host prefixing another
+	// does not take over its links
+	hosts := make([]string, 0, len(links.Hosts))
+	for host := range links.Hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Slice(hosts, func(i, j int) bool { return len(hosts[i]) > len(hosts[j]) })
+	var replacements []string
+	for _, host := range hosts {
+		replacements = append(replacements, strings.TrimSuffix(host, "/"), strings.TrimSuffix(links.Hosts[host], "/"))
+	}
+	replacer := strings.NewReplacer(replacements...)
+
+	patterns := make(map[string]*regexp.Regexp)
+	for oldID := range links.Dashboards {
+		patterns[oldID] = regexp.MustCompile(fmt.Sprintf(dashboardLink, regexp.QuoteMeta(oldID)))
+	}
+
+	var err error
+	for i, object := range gjson.GetBytes(payload, "objects").Array() {
+		attributes := object.Get("attributes").Raw
+		rewritten := replacer.Replace(attributes)
+		for oldID, re := range patterns {
+			rewritten = re.ReplaceAllString(rewritten, "${1}"+strings.Replace(links.Dashboards[oldID], "$", "$$", -1)+"${2}")
+		}
+		if rewritten == attributes {
+			continue
+		}
+		if !gjson.Valid(rewritten) {
+			return nil, errors.Errorf("rewriting the links of %v %v breaks its attributes.\n", object.Get("type").String(), object.Get("id").String())
+		}
+		payload, err = sjson.SetRawBytes(payload, fmt.Sprintf("objects.%d.attributes", i), []byte(rewritten))
+		if err != nil {
+			return nil, err
+		}
+	}
+	for oldID, newID := range links.Dashboards {
+		payload, err = rewriteReferences(payload, "dashboard", oldID, newID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return payload, nil
+}
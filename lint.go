@@ -0,0 +1,131 @@
+package kibctl
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+type lintFinding struct {
+	Severity string
+	Type     string
+	ID       string
+	Title    string
+	Check    string
+	Message  string
+}
+
+// lintCheck inspects a saved object of an export and reports its findings.
+type lintCheck func(object gjson.Result) []lintFinding
+
+var lintChecks = []lintCheck{
+	lintScripts,
+	lintVega,
+}
+
+func newFinding(object gjson.Result, severity, check, format string, v ...interface{}) lintFinding {
+	return lintFinding{
+		Severity: severity,
+		Type:     object.Get("type").String(),
+		ID:       object.Get("id").String(),
+		Title:    object.Get("attributes.title").String(),
+		Check:    check,
+		Message:  fmt.Sprintf(format, v...),
+	}
+}
+
+// lintExport runs the lint checks, along with the extra ones given, on every
+// object of the export.
+func lintExport(export []byte, extra ...lintCheck) []lintFinding {
+	checks := append(append([]lintCheck{}, lintChecks...), drilldownCheck(export))
+	checks = append(checks, extra...)
+	var findings []lintFinding
+	for _, object := range gjson.GetBytes(export, "objects").Array() {
+		for _, check := range checks {
+			findings = append(findings, check(object)...)
+		}
+	}
+	return findings
+}
+
+func lint(c *cli.Context) error {
+	path := c.Args().First()
+	if path == "" {
+		return cli.NewExitError("export file or directory missing", 1)
+	}
+	export, err := readBundle(path)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+
+	var extra []lintCheck
+	policy, err := loadPolicy(c.String("policy"))
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	if policy != nil {
+		check, err := policyCheck(policy, export)
+		if err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		extra = append(extra, check)
+	}
+
+	findings := lintExport(export, extra...)
+	errorCount := printFindings(findings)
+	if report := c.String("report"); report != "" {
+		if err := writeJUnit(report, "kibctl lint", lintCases(export, findings)); err != nil {
+			return cli.NewExitError(err, 2)
+		}
+	}
+	if errorCount > 0 {
+		return cli.NewExitError(fmt.Sprintf("%d error(s) found", errorCount), 3)
+	}
+	return nil
+}
+
+// printFindings prints the findings, only the errors when quiet, and returns
+// the number of errors.
+func printFindings(findings []lintFinding) int {
+	errorCount := 0
+	for _, f := range findings {
+		if f.Severity == severityError {
+			errorCount++
+		} else if quiet {
+			continue
+		}
+		os.Stdout.WriteString(fmt.Sprintf("%v %v %v (%v) [%v] %v\n", stdout.severity(f.Severity), f.Type, f.ID, f.Title, f.Check, f.Message))
+	}
+	return errorCount
+}
+
+// lintCases reports every object of the export as a test case, failed by its
+// lint errors, the warnings being kept as output.
+func lintCases(export []byte, findings []lintFinding) []junitCase {
+	var cases []junitCase
+	index := make(map[string]int)
+	for _, object := range gjson.GetBytes(export, "objects").Array() {
+		objectType, id := object.Get("type").String(), object.Get("id").String()
+		index[objectType+":"+id] = len(cases)
+		cases = append(cases, junitCase{
+			ClassName: objectType,
+			Name:      fmt.Sprintf("%v (%v)", id, object.Get("attributes.title").String()),
+		})
+	}
+	for _, f := range findings {
+		i := index[f.Type+":"+f.ID]
+		if f.Severity != severityError {
+			cases[i].SystemOut += fmt.Sprintf("%v [%v] %v\n", f.Severity, f.Check, f.Message)
+			continue
+		}
+		cases[i].Failures = append(cases[i].Failures, junitFailure{Type: f.Check, Message: f.Message, Text: f.Message})
+	}
+	return cases
+}
@@ -0,0 +1,67 @@
+package kibctl
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+// listObjects finds the objects of the types whose title matches the search,
+// all of them when the search is empty, retrieving their titles only.
+func (c *client) listObjects(types []string, search string) ([]savedObject, error) {
+	filters := url.Values{"fields": {"title", "name"}}
+	if search != "" {
+		filters.Set("search_fields", "title")
+		filters.Set("search", search)
+	}
+	objects, err := c.find(types, filters)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(objects, func(i, j int) bool {
+		if objects[i].Type != objects[j].Type {
+			return objects[i].Type < objects[j].Type
+		}
+		return strings.ToLower(objects[i].label()) < strings.ToLower(objects[j].label())
+	})
+	return objects, nil
+}
+
+// label returns the title of the object, or the name of the objects titled
+// by name such as the tags.
+func (o savedObject) label() string {
+	if title := o.title(); title != "" {
+		return title
+	}
+	return gjson.GetBytes(o.Attributes, "name").String()
+}
+
+func objectsList(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	types := savedObjectTypes
+	if list := c.String("types"); list != "" {
+		types = strings.Split(list, ",")
+	}
+	objects, err := newClient().listObjects(types, c.Args().First())
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if quiet {
+		for _, o := range objects {
+			printIDs(o.ID)
+		}
+		return nil
+	}
+	os.Stdout.WriteString(stdout.header(fmt.Sprintf("%-15v %-40v %-40v %v", "TYPE", "ID", "TITLE", "UPDATED")) + "\n")
+	for _, o := range objects {
+		os.Stdout.WriteString(fmt.Sprintf("%-15v %v %-40v %v\n", o.Type, stdout.paint(cyan, fmt.Sprintf("%-40v", o.ID)), o.label(), o.UpdatedAt))
+	}
+	return nil
+}
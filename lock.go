@@ -0,0 +1,223 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+// The advisory lock of a space is a saved query, a type every kibana and
+// opensearch dashboards version has, whose description holds the lock state.
+const (
+	lockType = "query"
+	lockID   = "kibctl-lock"
+	lockPath = "/api/saved_objects/" + lockType + "/" + lockID
+)
+
+// lockPollInterval is how often a held lock is checked while waiting for it.
+const lockPollInterval = 5 * time.Second
+
+type spaceLock struct {
+	Holder   string    `json:"holder"`
+	Acquired time.Time `json:"acquired"`
+	Expires  time.Time `json:"expires"`
+}
+
+func (l spaceLock) expired() bool {
+	return time.Now().After(l.Expires)
+}
+
+func (l spaceLock) String() string {
+	return fmt.Sprintf("%v since %v, expiring %v", l.Holder, l.Acquired.Format(time.RFC3339), l.Expires.Format(time.RFC3339))
+}
+
+func (l spaceLock) attributes() (string, error) {
+	state, err := json.Marshal(l)
+	if err != nil {
+		return "", err
+	}
+	attributes, err := json.Marshal(map[string]interface{}{
+		"title":       "kibctl lock",
+		"description": string(state),
+		"query":       map[string]string{"query": "", "language": "kuery"},
+	})
+	return string(attributes), err
+}
+
+// defaultLockHolder identifies the user and machine taking the lock.
+func defaultLockHolder() string {
+	name := os.Getenv("USER")
+	if current, err := user.Current(); err == nil {
+		name = current.Username
+	}
+	hostname, _ := os.Hostname()
+	return name + "@" + hostname
+}
+
+// readLock returns the lock of the space along with the version of its saved
+// object, nil when the space is not locked.
+func (c *client) readLock() (*spaceLock, string, error) {
+	body, err := c.getObject(lockType, lockID)
+	if err != nil || body == nil {
+		return nil, "", err
+	}
+	var l spaceLock
+	if err := json.Unmarshal([]byte(gjson.GetBytes(body, "attributes.description").String()), &l); err != nil {
+		return nil, "", errors.Wrap(err, "could not parse the lock of the space")
+	}
+	return &l, gjson.GetBytes(body, "version").String(), nil
+}
+
+// tryLock takes the lock when free or expired, the creation and the version
+// checked update failing when another holder got it first. It returns the
+// lock standing in the way otherwise. Locks already held by the holder are
+// extended.
+func (c *client) tryLock(holder string, ttl time.Duration) (*spaceLock, error) {
+	now := time.Now().UTC()
+	wanted := spaceLock{Holder: holder, Acquired: now, Expires: now.Add(ttl)}
+	attributes, err := wanted.attributes()
+	if err != nil {
+		return nil, err
+	}
+	current, version, err := c.readLock()
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		_, err := c.jsonRequest("POST", lockPath, map[string]json.RawMessage{"attributes": json.RawMessage(attributes)})
+		if apiErr, ok := err.(*apiError); ok && apiErr.StatusCode == http.StatusConflict {
+			current, _, err = c.readLock()
+			if err == nil && current == nil {
+				err = errors.New("lock released while taking it, retry.\n")
+			}
+			return current, err
+		}
+		return nil, err
+	}
+	if current.Holder != holder && !current.expired() {
+		return current, nil
+	}
+	if current.Holder == holder {
+		wanted.Acquired = current.Acquired
+		if attributes, err = wanted.attributes(); err != nil {
+			return nil, err
+		}
+	}
+	err = c.updateObject(lockType, lockID, version, attributes, "")
+	if errors.Cause(err) == errVersionConflict {
+		current, _, err = c.readLock()
+		return current, err
+	}
+	return nil, err
+}
+
+// acquireLock waits for the lock of the space up to the timeout, and reports
+// whether it was already held by the holder.
+func (c *client) acquireLock(holder string, ttl, timeout time.Duration) (bool, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		current, _, err := c.readLock()
+		if err != nil {
+			return false, err
+		}
+		reentrant := current != nil && current.Holder == holder && !current.expired()
+		blocking, err := c.tryLock(holder, ttl)
+		if err != nil {
+			return false, err
+		}
+		if blocking == nil {
+			return reentrant, nil
+		}
+		if time.Now().Add(lockPollInterval).After(deadline) {
+			return false, errors.Errorf("%v is locked by %v.\n", c.spaceName(), blocking)
+		}
+		c.Logger.Printf("%v locked by %v, waiting\n", c.spaceName(), blocking)
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// releaseLock deletes the lock of the space when held by the holder, or by
+// anyone when forced.
+func (c *client) releaseLock(holder string, force bool) error {
+	current, _, err := c.readLock()
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return nil
+	}
+	if current.Holder != holder && !force {
+		return errors.Errorf("%v is locked by %v, use force-unlock to release it.\n", c.spaceName(), current)
+	}
+	_, err = c.jsonRequest("DELETE", lockPath, nil)
+	return err
+}
+
+func lockAcquire(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	client := newClient()
+	if _, err := client.acquireLock(c.String("holder"), c.Duration("ttl"), c.Duration("lock-timeout")); err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stdout, "locked %v for %v\n", client.spaceName(), c.String("holder"))
+	}
+	return nil
+}
+
+func lockRelease(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	if err := newClient().releaseLock(c.String("holder"), false); err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	return nil
+}
+
+func lockForceUnlock(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	client := newClient()
+	current, _, err := client.readLock()
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if err := client.releaseLock("", true); err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if current != nil && !quiet {
+		fmt.Fprintf(os.Stdout, "released the lock of %v\n", current)
+	}
+	return nil
+}
+
+func lockStatus(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	client := newClient()
+	current, _, err := client.readLock()
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	switch {
+	case current == nil:
+		fmt.Fprintf(os.Stdout, "%v is not locked\n", client.spaceName())
+	case current.expired():
+		fmt.Fprintf(os.Stdout, "%v lock expired: %v\n", client.spaceName(), current)
+	default:
+		fmt.Fprintf(os.Stdout, "%v locked by %v\n", client.spaceName(), current)
+	}
+	return nil
+}
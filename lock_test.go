@@ -0,0 +1,118 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockServer stores the lock saved object of a space the way kibana does,
+// creations of an existing object and updates of a stale version conflicting.
+type lockServer struct {
+	sync.Mutex
+	lock    *spaceLock
+	version int
+	// interloper takes the lock between the read and the write of tryLock
+	interloper *spaceLock
+}
+
+func (s *lockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	if r.URL.Path != lockPath {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Method != "GET" && s.interloper != nil {
+		s.lock, s.interloper = s.interloper, nil
+		s.version++
+	}
+	var body struct {
+		Attributes struct {
+			Description string `json:"description"`
+		} `json:"attributes"`
+		Version string `json:"version"`
+	}
+	if content, _ := ioutil.ReadAll(r.Body); len(content) > 0 {
+		json.Unmarshal(content, &body)
+	}
+	switch {
+	case r.Method == "GET" && s.lock == nil:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"statusCode":404,"error":"Not Found"}`))
+	case r.Method == "GET":
+		state, _ := json.Marshal(s.lock)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":         lockID,
+			"type":       lockType,
+			"version":    fmt.Sprint(s.version),
+			"attributes": map[string]string{"description": string(state)},
+		})
+	case r.Method == "POST" && s.lock != nil,
+		r.Method == "PUT" && body.Version != fmt.Sprint(s.version):
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"statusCode":409,"error":"Conflict"}`))
+	case r.Method == "POST" || r.Method == "PUT":
+		var l spaceLock
+		json.Unmarshal([]byte(body.Attributes.Description), &l)
+		s.lock = &l
+		s.version++
+		w.Write([]byte(`{}`))
+	case r.Method == "DELETE":
+		s.lock = nil
+		w.Write([]byte(`{}`))
+	}
+}
+
+func TestTryLock(t *testing.T) {
+	now := time.Now().UTC()
+	held := func(holder string, expires time.Time) *spaceLock {
+		return &spaceLock{Holder: holder, Acquired: now.Add(-time.Hour), Expires: expires}
+	}
+	tests := []struct {
+		name       string
+		lock       *spaceLock
+		interloper *spaceLock
+		blocking   string
+		holder     string
+	}{
+		{"free", nil, nil, "", "ci"},
+		{"held by another", held("alice", now.Add(time.Hour)), nil, "alice", "alice"},
+		{"expired", held("alice", now.Add(-time.Minute)), nil, "", "ci"},
+		{"held by the holder", held("ci", now.Add(time.Minute)), nil, "", "ci"},
+		{"created concurrently", nil, held("bob", now.Add(time.Hour)), "bob", "bob"},
+		{"taken over concurrently", held("alice", now.Add(-time.Minute)), held("bob", now.Add(time.Hour)), "bob", "bob"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &lockServer{lock: test.lock, interloper: test.interloper}
+			server := httptest.NewServer(s)
+			defer server.Close()
+
+			blocking, err := newTestClient(server).tryLock("ci", 10*time.Minute)
+			if err != nil {
+				t.Fatalf("tryLock() error: %v", err)
+			}
+			switch {
+			case test.blocking == "" && blocking != nil:
+				t.Errorf("tryLock() blocked by %v, want the lock taken", blocking)
+			case test.blocking != "" && (blocking == nil || blocking.Holder != test.blocking):
+				t.Errorf("tryLock() blocked by %v, want %v", blocking, test.blocking)
+			}
+			if s.lock == nil || s.lock.Holder != test.holder {
+				t.Fatalf("lock held by %v, want %v", s.lock, test.holder)
+			}
+			if test.holder == "ci" && s.lock.Expires.Before(now.Add(5*time.Minute)) {
+				t.Errorf("lock expires %v, want it extended by the ttl", s.lock.Expires)
+			}
+			if test.name == "held by the holder" && !s.lock.Acquired.Equal(test.lock.Acquired) {
+				t.Errorf("lock acquired %v, want %v kept when extended", s.lock.Acquired, test.lock.Acquired)
+			}
+		})
+	}
+}
@@ -1,17 +1,25 @@
-package main
+package kibctl
 
 import (
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
 	"github.com/urfave/cli"
 )
 
-var verbose bool
-var host, username, password string
+var verbose, quiet, noColor bool
+var cacheTTL time.Duration
+var maxResponseMB int
+var host, username, password, apiKey, compat, flavor, tenant, space string
+var auth, awsRegion string
+var contextName, output string
 
 type cmdLogger struct {
 	IsVerbose bool
@@ -24,11 +32,13 @@ func (c *cmdLogger) Printf(format string, v ...interface{}) {
 	}
 }
 
-func main() {
+// Main runs the kibctl command line with the arguments of the process.
+func Main() {
 
 	app := cli.NewApp()
 	app.Name = "kibctl"
 	app.Usage = "kibctl is a cli tool for kibana"
+	app.Version = kibctlVersion
 	cli.VersionFlag = cli.BoolFlag{Name: "version"}
 	cli.HelpFlag = cli.BoolFlag{Name: "help"}
 
@@ -38,6 +48,35 @@ func main() {
 			Usage:       "provide additional details",
 			Destination: &verbose,
 		},
+		cli.BoolFlag{
+			Name:        "quiet, q",
+			Usage:       "suppress all non-error output, only print the ids of the affected objects",
+			Destination: &quiet,
+		},
+		cli.BoolFlag{
+			Name:        "read-only",
+			Usage:       "refuse every request which may write to kibana, failing with exit code 5, e.g. when exploring with privileged credentials",
+			Destination: &readOnly,
+			EnvVar:      "KIBCTL_READ_ONLY",
+		},
+		cli.BoolFlag{
+			Name:        "no-input",
+			Usage:       "never wait for user input, failing with exit code 4 instead, e.g. in CI jobs",
+			Destination: &noInput,
+			EnvVar:      "KIBCTL_NO_INPUT",
+		},
+		cli.BoolFlag{
+			Name:        "no-color",
+			Usage:       "disable colored output, also disabled by the NO_COLOR environment variable",
+			Destination: &noColor,
+		},
+		cli.StringFlag{
+			Name:        "output, o",
+			Usage:       "output format: text, or jsonl to print an event per line as objects are processed by import, export-all and promote",
+			Value:       "text",
+			Destination: &output,
+			EnvVar:      "KIBCTL_OUTPUT",
+		},
 		cli.StringFlag{
 			Name:        "host, h",
 			Usage:       "Kibana api endpoint (required)",
@@ -56,6 +95,87 @@ func main() {
 			Destination: &password,
 			EnvVar:      "KIBANA_PASSWORD",
 		},
+		cli.StringFlag{
+			Name:  "password-file",
+			Usage: "read the basic auth password from `FILE`, e.g. a mounted kubernetes secret",
+		},
+		cli.BoolFlag{
+			Name:  "password-stdin",
+			Usage: "read the basic auth password from the first line of stdin",
+		},
+		cli.StringFlag{
+			Name:        "api-key",
+			Usage:       "Kibana api key, used instead of the basic auth credentials",
+			Destination: &apiKey,
+			EnvVar:      "KIBANA_API_KEY",
+		},
+		cli.StringFlag{
+			Name:  "api-key-file",
+			Usage: "read the kibana api key from `FILE`",
+		},
+		cli.StringFlag{
+			Name:        "auth",
+			Usage:       "authentication scheme: basic, or sigv4 for Amazon OpenSearch Service dashboards behind IAM",
+			Value:       "basic",
+			Destination: &auth,
+			EnvVar:      "KIBCTL_AUTH",
+		},
+		cli.StringFlag{
+			Name:        "aws-region",
+			Usage:       "aws region of the OpenSearch Service domain, with --auth sigv4",
+			Destination: &awsRegion,
+			EnvVar:      "AWS_REGION",
+		},
+		cli.StringFlag{
+			Name:        "compat",
+			Usage:       "Kibana major version to target: 6, 7 or auto",
+			Value:       "7",
+			Destination: &compat,
+			EnvVar:      "KIBANA_COMPAT",
+		},
+		cli.StringFlag{
+			Name:        "flavor",
+			Usage:       "dashboards product to target: kibana or opensearch",
+			Value:       "kibana",
+			Destination: &flavor,
+			EnvVar:      "KIBANA_FLAVOR",
+		},
+		cli.StringFlag{
+			Name:        "tenant",
+			Usage:       "security tenant for OpenSearch Dashboards or Search Guard",
+			Destination: &tenant,
+			EnvVar:      "KIBANA_TENANT",
+		},
+		cli.StringFlag{
+			Name:        "space, s",
+			Usage:       "Kibana space, the default space when omitted",
+			Destination: &space,
+			EnvVar:      "KIBANA_SPACE",
+		},
+		cli.StringFlag{
+			Name:        "context",
+			Usage:       "connection context of the config file providing the host, credentials, tenant and space not given as flags",
+			Destination: &contextName,
+			EnvVar:      "KIBCTL_CONTEXT",
+		},
+		cli.IntFlag{
+			Name:        "max-response-mb",
+			Usage:       "reject the kibana responses larger than the size in megabytes rather than reading them in memory, the csv reports being streamed to their file; 0 for no limit",
+			Value:       64,
+			Destination: &maxResponseMB,
+			EnvVar:      "KIBCTL_MAX_RESPONSE_MB",
+		},
+		cli.StringSliceFlag{
+			Name:   "type-extra",
+			Usage:  "custom saved object `TYPE` of a kibana plugin handled along with the built-in types, repeatable",
+			EnvVar: "KIBCTL_TYPE_EXTRA",
+		},
+		cli.DurationFlag{
+			Name:        "cache-ttl",
+			Usage:       "cache searches and status responses on disk for the duration, e.g. 5m, and revalidate cached objects with conditional requests",
+			Destination: &cacheTTL,
+			EnvVar:      "KIBCTL_CACHE_TTL",
+		},
 	}
 
 	app.Commands = []cli.Command{
@@ -64,51 +184,1430 @@ func main() {
 			Usage: "option for dashbaord",
 			Subcommands: []cli.Command{
 				{
-					Name:   "import",
-					Usage:  "import PAYLOAD - import the dashboard definition",
+					Name:  "import",
+					Usage: "import [DIR] - import the dashboard definition read from stdin or from a split export directory",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "merge-field-formats",
+							Usage: "merge index-pattern field formats and attributes with the ones already on kibana instead of replacing them",
+						},
+						cli.StringFlag{
+							Name:  "time-from",
+							Usage: "override the time range start restored by the dashboards, e.g. now-24h",
+						},
+						cli.StringFlag{
+							Name:  "time-to",
+							Usage: "override the time range end restored by the dashboards, e.g. now",
+						},
+						cli.DurationFlag{
+							Name:  "refresh-interval",
+							Usage: "override the refresh interval restored by the dashboards, 0 pauses the refresh",
+						},
+						cli.StringFlag{
+							Name:  "overlay",
+							Usage: "json merge patch `FILE` applied to every dashboard before import, e.g. {\"attributes\":{\"optionsJSON\":{\"useMargins\":false}}}",
+						},
+						cli.StringFlag{
+							Name:  "link-map",
+							Usage: "json `FILE` mapping source hosts and dashboard ids to the target ones, rewriting the links of markdown panels and drilldowns, e.g. {\"hosts\":{\"https://staging:5601\":\"https://prod:5601\"},\"dashboards\":{\"old-id\":\"new-id\"}}",
+						},
+						cli.StringFlag{
+							Name:  "values",
+							Usage: "json `FILE` of the values rendering the {{ .name }} placeholders of the payload",
+						},
+						cli.StringFlag{
+							Name:  "translations",
+							Usage: "translations `FILE` written by i18n extract, applied with --locale",
+							Value: "translations.json",
+						},
+						cli.StringFlag{
+							Name:  "locale",
+							Usage: "translate the titles, descriptions and markdown to the locale of the translations file",
+						},
+						cli.StringFlag{
+							Name:  "skip-type",
+							Usage: "comma separated `TYPES` of the objects left out of the import, e.g. index-pattern,search",
+						},
+						cli.BoolFlag{
+							Name:  "require-refs",
+							Usage: "fail without importing when objects referenced by the bundle are missing on kibana",
+						},
+						cli.BoolFlag{
+							Name:  "follow-aliases",
+							Usage: "import the objects whose id is a legacy url alias under the id of the alias target",
+						},
+						cli.StringFlag{
+							Name:  "annotate",
+							Usage: "comma separated key=value `PAIRS` recording e.g. the team and owner of the imported objects",
+						},
+						cli.StringFlag{
+							Name:  "annotate-as",
+							Usage: "store the annotations as tags named key:value, or in the kibctlAnnotations attribute of the objects",
+							Value: "tags",
+						},
+						cli.BoolFlag{
+							Name:  "no-hooks",
+							Usage: "skip the preImport and postImport hooks of the config file",
+						},
+						cli.BoolFlag{
+							Name:  "no-validate",
+							Usage: "skip the validation of the objects against the saved object schemas of the kibana version",
+						},
+						cli.BoolFlag{
+							Name:  "compress",
+							Usage: "gzip the import payloads larger than 64 KB, e.g. over slow links, unless kibana rejects compressed requests",
+						},
+						cli.IntFlag{
+							Name:  "chunk-size",
+							Usage: "split the imports larger than the size in kilobytes into several requests, below the 1 MB server.maxPayload of kibana by default, 0 to send them whole; chunks still rejected as too large are halved",
+							Value: 900,
+						},
+						cli.BoolFlag{
+							Name:  "managed",
+							Usage: "flag the imported objects as managed, shown read-only in the kibana UI (kibana 8.10 or later)",
+						},
+						cli.BoolFlag{
+							Name:  "enforce-policy",
+							Usage: "reject the import when objects violate the naming policy",
+						},
+						cli.StringFlag{
+							Name:  "policy",
+							Usage: "json `FILE` of the naming policy enforced, instead of the policy of the config file",
+						},
+					},
 					Action: _import,
 				},
 				{
-					Name:   "export",
-					Usage:  "export NAME - export a json including the visualisation and index-template dependencies",
+					Name:  "export",
+					Usage: "export NAME - export a json including the visualisation and index-template dependencies",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "split",
+							Usage: "write each saved object to its own file under `DIR` along with an index.json",
+						},
+						cli.BoolFlag{
+							Name:  "extract-vega",
+							Usage: "with --split, write the vega specs to standalone .vega.hjson files",
+						},
+						cli.BoolFlag{
+							Name:  "extract-markdown",
+							Usage: "with --split, write the markdown panel bodies to standalone .md files",
+						},
+						cli.BoolFlag{
+							Name:  "normalize",
+							Usage: "sort dashboard panels by grid position and round their coordinates",
+						},
+						cli.BoolFlag{
+							Name:  "no-deps",
+							Usage: "only export the dashboard objects, leaving out their dependencies",
+						},
+						cli.BoolFlag{
+							Name:  "deps-only",
+							Usage: "only export the dependencies of the dashboards, leaving out the dashboard objects",
+						},
+						cli.BoolFlag{
+							Name:  "skip-missing-deps",
+							Usage: "export despite index-patterns missing from kibana, recording them as placeholders in the missingReferences of the export",
+						},
+						cli.StringFlag{
+							Name:  "dep-match",
+							Usage: "how the index-patterns referred to by name are found: id, title-exact, or title-fuzzy preferring the exact title among several matches",
+							Value: "title-fuzzy",
+						},
+						cli.StringFlag{
+							Name:  "provenance",
+							Usage: "record the source host, space, kibctl version, export time and content hash in a provenance.json sidecar of the --split directory, or in the kibctlProvenance attribute of the objects: sidecar or attribute",
+						},
+					},
 					Action: export,
 				},
 				{
-					Name:   "list",
-					Usage:  "list PATTERN - list dashboards with title matching the pattern",
+					Name:  "warm",
+					Usage: "warm NAME - run the searches of the dashboard panels to prime the elasticsearch caches, e.g. after a deploy or an index rollover",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "time-range",
+							Usage: "start of the time range searched, up to now",
+							Value: "now-1h",
+						},
+					},
+					Action: warmDashboard,
+				},
+				{
+					Name:  "data",
+					Usage: "data NAME - run the searches of the dashboard panels and store their raw responses, e.g. to attach to an incident report",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "time-range",
+							Usage: "start of the time range searched, up to now",
+							Value: "now-7d",
+						},
+						cli.StringFlag{
+							Name:  "out",
+							Usage: "directory the responses are written to, one file per panel",
+							Value: "data",
+						},
+						cli.IntFlag{
+							Name:  "parallel",
+							Usage: "number of searches run concurrently",
+							Value: 4,
+						},
+					},
+					Action: dashboardData,
+				},
+				{
+					Name:  "layout",
+					Usage: "layout NAME - rewrite the panel grid of the dashboard to a uniform layout, in reading order",
+					Flags: []cli.Flag{
+						cli.IntFlag{
+							Name:  "columns",
+							Usage: "number of panels per row",
+							Value: 2,
+						},
+						cli.IntFlag{
+							Name:  "panel-height",
+							Usage: "height of the panels in grid rows",
+							Value: 15,
+						},
+						cli.BoolFlag{
+							Name:  "force",
+							Usage: "overwrite the dashboard even if it changed concurrently",
+						},
+					},
+					Action: layoutDashboardCommand,
+				},
+				{
+					Name:  "merge",
+					Usage: "merge NAME NAME... - export a single dashboard combining the panels of the dashboards, stacked in order",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "title",
+							Usage: "title of the combined dashboard (required)",
+						},
+						cli.BoolFlag{
+							Name:  "section-per-source",
+							Usage: "title the panels of every dashboard with a markdown panel",
+						},
+						cli.StringFlag{
+							Name:  "split",
+							Usage: "write each saved object of the export to its own file under `DIR` along with an index.json",
+						},
+						cli.BoolFlag{
+							Name:  "normalize",
+							Usage: "sort dashboard panels of the export by grid position and round their coordinates",
+						},
+					},
+					Action: mergeDashboardsCommand,
+				},
+				{
+					Name:  "split",
+					Usage: "split NAME - export the dashboard split into a dashboard per group of panels, the dashboard becoming an index of links to them",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "by",
+							Usage: "grouping of the panels: tag, row or panel-title-prefix",
+							Value: "row",
+						},
+						cli.StringFlag{
+							Name:  "separator",
+							Usage: "separator ending the panel title prefix with --by panel-title-prefix",
+							Value: " - ",
+						},
+						cli.StringFlag{
+							Name:  "split",
+							Usage: "write each saved object of the export to its own file under `DIR` along with an index.json",
+						},
+						cli.BoolFlag{
+							Name:  "normalize",
+							Usage: "sort dashboard panels of the export by grid position and round their coordinates",
+						},
+					},
+					Action: splitDashboardCommand,
+				},
+				{
+					Name:  "export-all",
+					Usage: "export-all PATTERN - export a single json including every dashboard with title matching the glob pattern and their dependencies",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "regex",
+							Usage: "match the titles against PATTERN as a Go regular expression",
+						},
+						cli.StringFlag{
+							Name:  "split",
+							Usage: "write each saved object to its own file under `DIR` along with an index.json",
+						},
+						cli.BoolFlag{
+							Name:  "extract-vega",
+							Usage: "with --split, write the vega specs to standalone .vega.hjson files",
+						},
+						cli.BoolFlag{
+							Name:  "extract-markdown",
+							Usage: "with --split, write the markdown panel bodies to standalone .md files",
+						},
+						cli.BoolFlag{
+							Name:  "normalize",
+							Usage: "sort dashboard panels by grid position and round their coordinates",
+						},
+						cli.BoolFlag{
+							Name:  "no-deps",
+							Usage: "only export the dashboard objects, leaving out their dependencies",
+						},
+						cli.BoolFlag{
+							Name:  "deps-only",
+							Usage: "only export the dependencies of the dashboards, leaving out the dashboard objects",
+						},
+						cli.BoolFlag{
+							Name:  "skip-missing-deps",
+							Usage: "export despite index-patterns missing from kibana, recording them as placeholders in the missingReferences of the export",
+						},
+						cli.StringFlag{
+							Name:  "dep-match",
+							Usage: "how the index-patterns referred to by name are found: id, title-exact, or title-fuzzy preferring the exact title among several matches",
+							Value: "title-fuzzy",
+						},
+						cli.StringFlag{
+							Name:  "provenance",
+							Usage: "record the source host, space, kibctl version, export time and content hash in a provenance.json sidecar of the --split directory, or in the kibctlProvenance attribute of the objects: sidecar or attribute",
+						},
+					},
+					Action: exportAll,
+				},
+				{
+					Name:  "list",
+					Usage: "list PATTERN - list dashboards with title matching the glob pattern, e.g. \"prod-*-errors\"",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "regex",
+							Usage: "match the titles against PATTERN as a Go regular expression, e.g. \"^(prod|staging)-[a-z]+$\"",
+						},
+					},
 					Action: list,
 				},
+				{
+					Name:  "graph",
+					Usage: "graph NAME - output the dependency graph of the dashboard",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "format, f",
+							Usage: "graph format: dot or mermaid",
+							Value: "dot",
+						},
+					},
+					Action: graph,
+				},
 			},
 		},
+		{
+			Name:  "scrub",
+			Usage: "scrub FILE - rewrite index-pattern titles, queries and filter values of an export according to a rules file",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "rules, r",
+					Usage: "json file listing the indexPatterns, queries and filters rewrite rules (required)",
+				},
+			},
+			Action: scrub,
+		},
+		{
+			Name:   "normalize",
+			Usage:  "normalize FILE - sort dashboard panels by grid position and round their coordinates",
+			Action: normalize,
+		},
+		{
+			Name:  "patch",
+			Usage: "option for patching saved objects in place",
+			Subcommands: []cli.Command{
+				{
+					Name:  "dashboard",
+					Usage: "dashboard NAME - apply a merge or json patch to the dashboard",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "patch, p",
+							Usage: "patch `FILE` applied to the saved object (required)",
+						},
+						cli.StringFlag{
+							Name:  "type",
+							Usage: "patch type: merge (RFC 7386) or json (RFC 6902)",
+							Value: "merge",
+						},
+						cli.BoolFlag{
+							Name:  "force",
+							Usage: "overwrite the dashboard even if it changed concurrently",
+						},
+					},
+					Action: patchDashboard,
+				},
+			},
+		},
+		{
+			Name:  "edit",
+			Usage: "option for editing saved objects with $EDITOR",
+			Subcommands: []cli.Command{
+				{
+					Name:  "dashboard",
+					Usage: "dashboard NAME - edit the dashboard export in $KIBCTL_EDITOR or $EDITOR and import it on save",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "force",
+							Usage: "import even if the objects changed on kibana since they were exported",
+						},
+					},
+					Action: editDashboard,
+				},
+			},
+		},
+		{
+			Name:  "cache",
+			Usage: "option for the on-disk cache",
+			Subcommands: []cli.Command{
+				{
+					Name:   "clear",
+					Usage:  "clear - remove all cached responses",
+					Action: cacheClear,
+				},
+			},
+		},
+		{
+			Name:  "git",
+			Usage: "option for syncing kibana with a git repository",
+			Subcommands: []cli.Command{
+				{
+					Name:  "snapshot",
+					Usage: "snapshot [PATTERN] - write the normalized split export of the dashboards matching the glob pattern to the repository and commit it",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "repo",
+							Usage: "`DIR` of the git work tree (required)",
+						},
+						cli.StringFlag{
+							Name:  "path",
+							Usage: "`DIR` of the snapshot inside the repository",
+							Value: ".",
+						},
+						cli.StringFlag{
+							Name:  "branch",
+							Usage: "commit on the `BRANCH`, created when missing, instead of the current one",
+						},
+						cli.StringFlag{
+							Name:  "message, m",
+							Usage: "commit message",
+						},
+						cli.BoolFlag{
+							Name:  "push",
+							Usage: "push the branch after committing",
+						},
+						cli.StringFlag{
+							Name:  "remote",
+							Usage: "remote to push to",
+							Value: "origin",
+						},
+					},
+					Action: gitSnapshot,
+				},
+			},
+		},
+		{
+			Name:  "drift",
+			Usage: "drift [PATTERN] - compare the snapshot committed in git with the dashboards matching the glob pattern on kibana, exit 3 on drift",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "repo",
+					Usage: "`DIR` of the git repository (required)",
+				},
+				cli.StringFlag{
+					Name:  "path",
+					Usage: "`DIR` of the snapshot inside the repository",
+					Value: ".",
+				},
+				cli.StringFlag{
+					Name:  "revision",
+					Usage: "git `REVISION` of the snapshot",
+					Value: "HEAD",
+				},
+				cli.StringFlag{
+					Name:  "format, f",
+					Usage: "report format: text or markdown",
+					Value: "text",
+				},
+				cli.StringFlag{
+					Name:  "report",
+					Usage: "also write the drifted objects as a JUnit XML report to `FILE`",
+				},
+			},
+			Action: drift,
+		},
+		{
+			Name:  "diff",
+			Usage: "diff OLD NEW - summarize the objects added, removed and changed between two export files or directories",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "format, f",
+					Usage: "diff format: text, or markdown for pull request comments",
+					Value: "text",
+				},
+				cli.StringFlag{
+					Name:  "report",
+					Usage: "also write the changed objects as a JUnit XML report to `FILE`",
+				},
+			},
+			Action: diff,
+		},
+		{
+			Name:  "apply",
+			Usage: "apply FILE|DIR - show the changes the export makes to kibana and import it, once approved with --require-approval",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "show the changes without applying them",
+				},
+				cli.BoolFlag{
+					Name:  "require-approval",
+					Usage: "wait for the token of an approver other than the current user before applying",
+				},
+				cli.StringFlag{
+					Name:  "approvers-file",
+					Usage: "json `FILE` listing the approvers and the sha256 of their token, e.g. {\"approvers\":[{\"name\":\"alice\",\"tokenHash\":\"...\"}]}",
+				},
+				cli.StringFlag{
+					Name:  "approval-listen",
+					Usage: "also accept the approver token posted to `ADDRESS`, e.g. by a chat workflow",
+				},
+				cli.DurationFlag{
+					Name:  "approval-timeout",
+					Usage: "duration to wait for the approval before giving up",
+					Value: 30 * time.Minute,
+				},
+				cli.BoolFlag{
+					Name:  "no-validate",
+					Usage: "skip the validation of the objects against the saved object schemas of the kibana version",
+				},
+				cli.BoolFlag{
+					Name:  "atomic",
+					Usage: "restore the objects already changed, and delete the ones created, when the import fails",
+				},
+				cli.StringFlag{
+					Name:  "canary-space",
+					Usage: "first import into the scratch space `ID` and check it there, e.g. kibctl-canary, deleted afterwards",
+				},
+				cli.BoolFlag{
+					Name:  "no-lock",
+					Usage: "apply without taking the advisory lock of the space",
+				},
+				cli.DurationFlag{
+					Name:  "lock-timeout",
+					Usage: "duration to wait for the lock held by another user before giving up",
+				},
+				cli.DurationFlag{
+					Name:  "lock-ttl",
+					Usage: "duration after which the lock expires if apply does not release it",
+					Value: 15 * time.Minute,
+				},
+			},
+			Action: apply,
+		},
+		{
+			Name:  "lock",
+			Usage: "manage the advisory lock of the space honored by apply",
+			Subcommands: []cli.Command{
+				{
+					Name:  "acquire",
+					Usage: "acquire - lock the space, e.g. for the duration of a deployment",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "holder",
+							Usage: "`NAME` recorded as the holder of the lock",
+							Value: defaultLockHolder(),
+						},
+						cli.DurationFlag{
+							Name:  "ttl",
+							Usage: "duration after which the lock expires",
+							Value: 15 * time.Minute,
+						},
+						cli.DurationFlag{
+							Name:  "lock-timeout",
+							Usage: "duration to wait for the lock held by another holder before giving up",
+						},
+					},
+					Action: lockAcquire,
+				},
+				{
+					Name:  "release",
+					Usage: "release - unlock the space locked by the holder",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "holder",
+							Usage: "`NAME` the lock was acquired with",
+							Value: defaultLockHolder(),
+						},
+					},
+					Action: lockRelease,
+				},
+				{
+					Name:   "status",
+					Usage:  "status - show the holder and expiry of the lock",
+					Action: lockStatus,
+				},
+				{
+					Name:   "force-unlock",
+					Usage:  "force-unlock - release the lock whoever holds it",
+					Action: lockForceUnlock,
+				},
+			},
+		},
+		{
+			Name:  "i18n",
+			Usage: "maintain the dashboards in several languages from one source",
+			Subcommands: []cli.Command{
+				{
+					Name:  "extract",
+					Usage: "extract FILE|DIR - update the translations file with the titles, descriptions and markdown of the export",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "translations",
+							Usage: "translations `FILE` updated, keeping the translations already made",
+							Value: "translations.json",
+						},
+						cli.StringFlag{
+							Name:  "locale",
+							Usage: "comma separated locales the new strings are added to untranslated, e.g. fr,de",
+						},
+					},
+					Action: i18nExtract,
+				},
+				{
+					Name:  "apply",
+					Usage: "apply FILE|DIR - print the export translated to the locale",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "translations",
+							Usage: "translations `FILE` written by i18n extract",
+							Value: "translations.json",
+						},
+						cli.StringFlag{
+							Name:  "locale",
+							Usage: "locale of the translations applied (required)",
+						},
+					},
+					Action: i18nApply,
+				},
+			},
+		},
+		{
+			Name:  "changelog",
+			Usage: "changelog OLD NEW - write the release notes of the dashboards added, updated and removed between two export files or directories",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "format, f",
+					Usage: "changelog format: text or markdown",
+					Value: "markdown",
+				},
+				cli.StringFlag{
+					Name:  "title",
+					Usage: "`TITLE` heading the changelog, e.g. the release version",
+				},
+			},
+			Action: changelogCommand,
+		},
+		{
+			Name:  "lint",
+			Usage: "lint FILE|DIR - check an export for broken or deprecated definitions",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "report",
+					Usage: "also write the findings as a JUnit XML report to `FILE`",
+				},
+				cli.StringFlag{
+					Name:  "policy",
+					Usage: "json `FILE` of the naming policy checked, instead of the policy of the config file",
+				},
+			},
+			Action: lint,
+		},
+		{
+			Name:  "repair",
+			Usage: "repair FILE|DIR|NAME - fix the double-escaped json attributes, references to old ids, missing search sources and out of sync panel indices, printing the repaired export or updating the dashboard live",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "report the fixes of the dashboard without updating it",
+				},
+				cli.BoolFlag{
+					Name:  "force",
+					Usage: "overwrite the objects even if they changed concurrently",
+				},
+			},
+			Action: repair,
+		},
+		{
+			Name:  "generate",
+			Usage: "option for generating saved objects",
+			Subcommands: []cli.Command{
+				{
+					Name:  "dashboard",
+					Usage: "dashboard - print a starter dashboard for a data view, ready to be piped to dashboard import",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "dataview",
+							Usage: "`TITLE` of the data view the dashboard visualizes, e.g. logs-nginx-* (required)",
+						},
+						cli.StringFlag{
+							Name:  "template",
+							Usage: "panels generated: generic for the top terms of the first keyword fields, or weblogs for the urls, statuses, clients and agents",
+							Value: "generic",
+						},
+						cli.StringFlag{
+							Name:  "title",
+							Usage: "`TITLE` of the dashboard, \"<dataview> overview\" when omitted",
+						},
+					},
+					Action: generateDashboardCommand,
+				},
+			},
+		},
+		{
+			Name:  "check",
+			Usage: "option for compatibility checks",
+			Subcommands: []cli.Command{
+				{
+					Name:  "compat",
+					Usage: "compat FILE|DIR - report the fields used by the visualizations which are missing from their data view or of an unsuitable type",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "against-cluster",
+							Usage: "check the fields mapped on the cluster behind the data views instead of the field lists saved in the export",
+						},
+					},
+					Action: checkCompat,
+				},
+			},
+		},
+		{
+			Name:    "object",
+			Aliases: []string{"objects"},
+			Usage:   "option for saved objects of any type",
+			Subcommands: []cli.Command{
+				{
+					Name:  "list",
+					Usage: "list [SEARCH] - list the saved objects of several types at once, optionally filtered by a title search",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "types",
+							Usage: "comma separated `TYPES` of the objects listed, e.g. dashboard,visualization,search,index-pattern, all the known types when omitted",
+						},
+					},
+					Action: objectsList,
+				},
+				{
+					Name:   "rdeps",
+					Usage:  "rdeps TYPE ID - list the objects depending directly or transitively on the object",
+					Action: rdeps,
+				},
+			},
+		},
+		{
+			Name:    "rule",
+			Aliases: []string{"rules"},
+			Usage:   "option for alerting rules",
+			Subcommands: []cli.Command{
+				{
+					Name:  "import",
+					Usage: "import FILE - create or update the alerting rules of the json file, a rule or an array of rules with their ids",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "values",
+							Usage: "json `FILE` of the values rendering the {{ .name }} placeholders of the rules, e.g. thresholds, indices or connector ids",
+						},
+					},
+					Action: ruleImport,
+				},
+				{
+					Name:  "snooze",
+					Usage: "snooze ID - silence the notifications of the alerting rule for a while",
+					Flags: []cli.Flag{
+						cli.DurationFlag{
+							Name:  "for",
+							Usage: "snooze `DURATION`, e.g. 2h (required)",
+						},
+					},
+					Action: ruleSnooze,
+				},
+			},
+		},
+		{
+			Name:  "siem",
+			Usage: "option for security detection content",
+			Subcommands: []cli.Command{
+				{
+					Name:  "rule",
+					Usage: "option for detection rules",
+					Subcommands: []cli.Command{
+						{
+							Name:  "export",
+							Usage: "export [RULE_ID...] - export the detection rules, all of them when none is given, with their exception lists as ndjson",
+							Flags: []cli.Flag{
+								cli.BoolFlag{
+									Name:  "no-exceptions",
+									Usage: "leave out the exception lists of the rules",
+								},
+							},
+							Action: siemRuleExport,
+						},
+						{
+							Name:   "import",
+							Usage:  "import FILE - import the detection rules and exception lists of an ndjson export, overwriting existing ones",
+							Action: siemRuleImport,
+						},
+					},
+				},
+				{
+					Name:  "exception-list",
+					Usage: "option for detection rule exception lists",
+					Subcommands: []cli.Command{
+						{
+							Name:   "list",
+							Usage:  "list - list the exception lists",
+							Action: exceptionListList,
+						},
+						{
+							Name:   "export",
+							Usage:  "export LIST_ID... - export the exception lists and their items as ndjson",
+							Action: exceptionListExport,
+						},
+						{
+							Name:   "import",
+							Usage:  "import FILE - import the exception lists and items of an ndjson export, overwriting existing ones",
+							Action: exceptionListImport,
+						},
+					},
+				},
+				{
+					Name:  "timeline",
+					Usage: "option for security timelines and timeline templates",
+					Subcommands: []cli.Command{
+						{
+							Name:  "list",
+							Usage: "list - list the timelines",
+							Flags: []cli.Flag{
+								cli.BoolFlag{
+									Name:  "templates",
+									Usage: "list the timeline templates instead",
+								},
+							},
+							Action: timelineList,
+						},
+						{
+							Name:  "export",
+							Usage: "export [ID...] - export the timelines as ndjson, all of them when none is given",
+							Flags: []cli.Flag{
+								cli.BoolFlag{
+									Name:  "templates",
+									Usage: "without ids, export all the timeline templates instead",
+								},
+							},
+							Action: timelineExport,
+						},
+						{
+							Name:   "import",
+							Usage:  "import FILE - import the timelines and timeline templates of an ndjson export",
+							Action: timelineImport,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:    "case",
+			Aliases: []string{"cases"},
+			Usage:   "option for cases",
+			Subcommands: []cli.Command{
+				{
+					Name:  "list",
+					Usage: "list - list the cases",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "status",
+							Usage: "only list the cases with the status: open, in-progress or closed",
+						},
+					},
+					Action: caseList,
+				},
+				{
+					Name:  "create",
+					Usage: "create TITLE - open a case, optionally linking dashboards",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "description",
+							Usage: "case description",
+						},
+						cli.StringSliceFlag{
+							Name:  "tag",
+							Usage: "case tag, repeatable",
+						},
+						cli.StringSliceFlag{
+							Name:  "dashboard",
+							Usage: "`NAME` of a dashboard whose link is attached to the case, repeatable",
+						},
+						cli.StringFlag{
+							Name:  "owner",
+							Usage: "application owning the case: cases, observability or securitySolution",
+							Value: "cases",
+						},
+					},
+					Action: caseCreate,
+				},
+				{
+					Name:  "update",
+					Usage: "update ID - change the title, description, status or tags of the case",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "title",
+							Usage: "new case title",
+						},
+						cli.StringFlag{
+							Name:  "description",
+							Usage: "new case description",
+						},
+						cli.StringFlag{
+							Name:  "status",
+							Usage: "new case status: open, in-progress or closed",
+						},
+						cli.StringSliceFlag{
+							Name:  "tag",
+							Usage: "case tag replacing the current ones, repeatable",
+						},
+					},
+					Action: caseUpdate,
+				},
+				{
+					Name:   "close",
+					Usage:  "close ID - close the case",
+					Action: caseClose,
+				},
+			},
+		},
+		{
+			Name:  "maintenance-window",
+			Usage: "option for alerting maintenance windows",
+			Subcommands: []cli.Command{
+				{
+					Name:  "create",
+					Usage: "create TITLE - create a one-off maintenance window silencing the alerting rules",
+					Flags: []cli.Flag{
+						cli.DurationFlag{
+							Name:  "duration",
+							Usage: "`DURATION` of the window, e.g. 30m (required)",
+						},
+						cli.StringFlag{
+							Name:  "start",
+							Usage: "RFC 3339 start `TIME` of the window, now when omitted",
+						},
+					},
+					Action: maintenanceWindowCreate,
+				},
+				{
+					Name:   "list",
+					Usage:  "list - list the maintenance windows",
+					Action: maintenanceWindowList,
+				},
+				{
+					Name:   "delete",
+					Usage:  "delete ID - delete the maintenance window",
+					Action: maintenanceWindowDelete,
+				},
+			},
+		},
+		{
+			Name:  "fleet",
+			Usage: "option for fleet integration packages",
+			Subcommands: []cli.Command{
+				{
+					Name:  "package",
+					Usage: "option for the installed packages",
+					Subcommands: []cli.Command{
+						{
+							Name:  "assets",
+							Usage: "assets NAME - list the kibana assets installed by the package, or export its dashboards",
+							Flags: []cli.Flag{
+								cli.BoolFlag{
+									Name:  "export",
+									Usage: "export the dashboards of the package with their dependencies, annotated with the package name and version",
+								},
+								cli.StringFlag{
+									Name:  "split",
+									Usage: "write each saved object of the export to its own file under `DIR` along with an index.json",
+								},
+								cli.BoolFlag{
+									Name:  "normalize",
+									Usage: "sort dashboard panels of the export by grid position and round their coordinates",
+								},
+							},
+							Action: fleetPackageAssets,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "sampledata",
+			Usage: "option for the kibana sample data sets",
+			Subcommands: []cli.Command{
+				{
+					Name:  "install",
+					Usage: "install flights|logs|ecommerce... - install the sample data sets and their saved objects in the space",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "skip-installed",
+							Usage: "leave the data sets already installed untouched instead of reinstalling them",
+						},
+					},
+					Action: sampleDataInstall,
+				},
+				{
+					Name:   "uninstall",
+					Usage:  "uninstall flights|logs|ecommerce... - remove the sample data sets and their saved objects",
+					Action: sampleDataUninstall,
+				},
+				{
+					Name:   "list",
+					Usage:  "list - list the sample data sets with their status",
+					Action: sampleDataList,
+				},
+			},
+		},
+		{
+			Name:  "plugin",
+			Usage: "option for the kibctl-NAME executables of the PATH run as kibctl NAME",
+			Subcommands: []cli.Command{
+				{
+					Name:   "list",
+					Usage:  "list - list the plugins found on the PATH",
+					Action: pluginList,
+				},
+			},
+		},
+		{
+			Name:  "promote",
+			Usage: "promote - copy every object carrying a tag from a config context to another and record it in the audit log",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "tag",
+					Usage: "`NAME` of the tag selecting the objects to promote (required)",
+				},
+				cli.StringFlag{
+					Name:  "from",
+					Usage: "source `CONTEXT` (required)",
+				},
+				cli.StringFlag{
+					Name:  "to",
+					Usage: "target `CONTEXT` (required)",
+				},
+			},
+			Action: promote,
+		},
+		{
+			Name:  "audit",
+			Usage: "option for audits",
+			Subcommands: []cli.Command{
+				{
+					Name:   "collisions",
+					Usage:  "collisions - report objects sharing ids or titles with diverging content across spaces",
+					Action: auditCollisions,
+				},
+				{
+					Name:  "duplicates",
+					Usage: "duplicates - report the dashboards with identical panels, query and filters, e.g. clones to consolidate",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "across-spaces",
+							Usage: "also group identical dashboards of different spaces",
+						},
+					},
+					Action: auditDuplicates,
+				},
+			},
+		},
+		{
+			Name:  "space",
+			Usage: "option for kibana spaces",
+			Subcommands: []cli.Command{
+				{
+					Name:  "copy",
+					Usage: "copy TYPE:NAME... - copy the objects to another space, the dashboards being given by name and the other objects by id",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "from",
+							Usage: "space `ID` the objects are copied from, the space of the connection by default",
+						},
+						cli.StringFlag{
+							Name:  "to",
+							Usage: "space `ID` the objects are copied to (required)",
+						},
+						cli.BoolTFlag{
+							Name:  "include-references",
+							Usage: "also copy the objects they refer to, e.g. the visualizations and data views of the dashboards",
+						},
+						cli.BoolFlag{
+							Name:  "create-new-copies",
+							Usage: "copy the objects under new ids instead of keeping theirs",
+						},
+						cli.BoolFlag{
+							Name:  "overwrite",
+							Usage: "overwrite the objects of the target space the copy conflicts with",
+						},
+					},
+					Action: spaceCopy,
+				},
+				{
+					Name:  "provision",
+					Usage: "provision - create a space and set up its features, settings, tags and dashboards after a template",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "template",
+							Usage: "json or yaml `FILE` of the space setup, e.g. {\"disabledFeatures\":[\"ml\"],\"settings\":{\"dateFormat:tz\":\"UTC\"},\"tags\":[\"team\"],\"bundle\":\"dashboards\"} (required)",
+						},
+						cli.StringFlag{
+							Name:  "name",
+							Usage: "name of the space (required)",
+						},
+						cli.StringFlag{
+							Name:  "id",
+							Usage: "id of the space, derived from the name by default",
+						},
+					},
+					Action: spaceProvision,
+				},
+				{
+					Name:  "features",
+					Usage: "option for the features visible in the spaces",
+					Subcommands: []cli.Command{
+						{
+							Name:  "set",
+							Usage: "set [SPACE] - set the features disabled in the space, leaving it unchanged when they already are",
+							Flags: []cli.Flag{
+								cli.StringFlag{
+									Name:  "disable",
+									Usage: "comma separated ids of the features disabled, every other feature being enabled, e.g. ml,canvas,enterpriseSearch",
+								},
+								cli.BoolFlag{
+									Name:  "all-spaces",
+									Usage: "set the features of every space",
+								},
+							},
+							Action: spaceFeaturesSet,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "search",
+			Usage: "manage the saved searches",
+			Subcommands: []cli.Command{
+				{
+					Name:  "csv",
+					Usage: "csv NAME - generate the csv report of the saved search through kibana reporting and download it once done",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "time-range",
+							Usage: "start of the time range reported, up to now",
+							Value: "now-24h",
+						},
+						cli.StringFlag{
+							Name:  "out",
+							Usage: "`FILE` the report is written to, - for stdout",
+							Value: "results.csv",
+						},
+						cli.DurationFlag{
+							Name:  "timeout",
+							Usage: "maximum time waited for the report to be generated",
+							Value: 10 * time.Minute,
+						},
+					},
+					Action: searchCSV,
+				},
+			},
+		},
+		{
+			Name:  "find",
+			Usage: "find ID|TITLE - report the saved objects with the id, or whose title contains the text, and the space they live in",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "all-spaces",
+					Usage: "search every space the user can access",
+				},
+				cli.StringFlag{
+					Name:  "type",
+					Usage: "comma separated saved object types searched, all the handled types by default",
+				},
+			},
+			Action: findCommand,
+		},
+		{
+			Name:  "es",
+			Usage: "es PATH - send the request to elasticsearch through the console proxy of kibana, with the kibana credentials, e.g. es '/_cat/indices?v'",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "request, X",
+					Usage: "http `METHOD` of the elasticsearch request",
+					Value: "GET",
+				},
+				cli.StringFlag{
+					Name:  "data, d",
+					Usage: "request body, read from `FILE` when given as @FILE, or from stdin as @-",
+				},
+			},
+			Action: esCommand,
+		},
+		{
+			Name:  "grep",
+			Usage: "grep PATTERN - list the saved objects whose attributes contain the text, e.g. a field name before renaming it",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "types",
+					Usage: "comma separated `TYPES` of the objects searched, all the known types when omitted",
+				},
+				cli.BoolFlag{
+					Name:  "regex",
+					Usage: "match PATTERN as a Go regular expression",
+				},
+				cli.BoolFlag{
+					Name:  "ignore-case, i",
+					Usage: "match regardless of case",
+				},
+			},
+			Action: grepObjects,
+		},
+		{
+			Name:  "stats",
+			Usage: "stats - summarize the saved objects of every space: counts per type and space, largest and recently updated objects",
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "top",
+					Usage: "number of largest and recently updated objects listed",
+					Value: 10,
+				},
+			},
+			Action: stats,
+		},
+		{
+			Name:  "auth",
+			Usage: "option for the privileges of the current credentials",
+			Subcommands: []cli.Command{
+				{
+					Name:  "can-i",
+					Usage: "can-i VERB TYPE - check the user may get, list, export, import, create, update or delete the saved objects of the type in the space, exiting with 3 when not",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "application",
+							Usage: "elasticsearch `APPLICATION` of the kibana privileges, kibana-<kibana.index>",
+							Value: kibanaApplication,
+						},
+					},
+					Action: authCanI,
+				},
+			},
+		},
+		{
+			Name:  "api-resources",
+			Usage: "api-resources - list the saved object types and apis of the kibana and the operations kibctl may perform on them with the current credentials, as jsonl with --output jsonl",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "available",
+					Usage: "list the available resources only",
+				},
+			},
+			Action: apiResources,
+		},
+		{
+			Name:  "proxy",
+			Usage: "proxy - serve kibana locally, caching the read requests in memory and forwarding the writes, for tools polling kibana in dev loops",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "listen",
+					Usage: "loopback `ADDRESS` the proxy listens on; the requests without credentials are sent with the kibctl ones",
+					Value: "127.0.0.1:5601",
+				},
+				cli.BoolFlag{
+					Name:  "allow-remote",
+					Usage: "listen on a --listen address reachable from other hosts, lending them the kibctl credentials",
+				},
+				cli.StringFlag{
+					Name:  "upstream",
+					Usage: "kibana `URL` the requests are forwarded to, the --host when omitted",
+				},
+				cli.DurationFlag{
+					Name:  "ttl",
+					Usage: "duration the read responses are served from the cache, which any write empties",
+					Value: time.Minute,
+				},
+			},
+			Action: proxy,
+		},
+		{
+			Name:  "bench",
+			Usage: "bench - measure the latency percentiles of representative kibana apis, telling slow networks or kibana from slow kibctl commands",
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "requests",
+					Usage: "number of requests sent to each api",
+					Value: 20,
+				},
+			},
+			Action: benchCommand,
+		},
+		{
+			Name:  "analyze",
+			Usage: "analyze FILE|DIR|NAME - report the size of the dashboard panels of an export, or of the dashboard exported live, and what to externalize",
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "top",
+					Usage: "number of largest panels listed",
+					Value: 10,
+				},
+				cli.IntFlag{
+					Name:  "max-size-kb",
+					Usage: "import payload limit of kibana in kilobytes, the server.maxPayload setting",
+					Value: maxPayloadBytes >> 10,
+				},
+				cli.IntFlag{
+					Name:  "vega-limit-kb",
+					Usage: "size in kilobytes above which vega specs are reported",
+					Value: 32,
+				},
+			},
+			Action: analyze,
+		},
+		{
+			Name:  "foreach",
+			Usage: "foreach -- COMMAND - run the kibctl command against each config context and aggregate the results per context",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "contexts",
+					Usage: "comma separated `CONTEXTS` to run the command against, all the contexts of the config when omitted",
+				},
+				cli.BoolFlag{
+					Name:  "parallel",
+					Usage: "run the command against the contexts concurrently, the outputs being printed once all runs are done",
+				},
+			},
+			Action: foreach,
+		},
+	}
+
+	app.Commands = withCommandDefaults(withConnectionFlags(app.Commands, ""), "")
+
+	expandedAlias := false
+	app.Action = func(c *cli.Context) error {
+		if c.NArg() == 0 {
+			return cli.ShowAppHelp(c)
+		}
+		conf, err := loadConfig()
+		if err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		// aliases are expanded once, so that an alias may not run itself
+		if args, ok := expandAlias(conf, c.Args()); ok && !expandedAlias {
+			expandedAlias = true
+			return app.Run(args)
+		}
+		return runPlugin(c)
+	}
+
+	app.Before = func(c *cli.Context) error {
+		stdout = newConsole(os.Stdout, noColor)
+		conf, err := loadConfig()
+		if err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		registerObjectTypes(conf.Types...)
+		registerObjectTypes(c.StringSlice("type-extra")...)
+		if err := applyContext(c, conf); err != nil {
+			return err
+		}
+		// validated once the context may have set it
+		switch output {
+		case "text", "jsonl":
+		default:
+			return cli.NewExitError(fmt.Sprintf("unsupported output format %v", output), 1)
+		}
+		return applyCredentials(c)
 	}
 
+	// the commands refused a write by --read-only exit with a distinct code,
+	// whatever the error they fail with
+	cli.OsExiter = func(code int) {
+		if readOnlyRefused {
+			code = exitReadOnly
+		}
+		os.Exit(code)
+	}
 	err := app.Run(os.Args)
 	if err != nil {
+		if readOnlyRefused {
+			log.Print(err)
+			os.Exit(exitReadOnly)
+		}
 		log.Fatal(err)
 	}
 }
 
 func newClient() *client {
 	return &client{
-		Host:     host,
-		Username: username,
-		Password: password,
+		Host:             host,
+		Username:         username,
+		Password:         password,
+		APIKey:           apiKey,
+		CredentialSource: credentialSource,
+		Auth:             auth,
+		AWSRegion:        awsRegion,
+		Compat:           compat,
+		Flavor:           flavor,
+		Tenant:           tenant,
+		Space:            space,
+		CacheTTL:         cacheTTL,
+		MaxResponseSize:  int64(maxResponseMB) << 20,
+		ReadOnly:         readOnly,
 		Logger: &cmdLogger{
-			Logger:    log.New(os.Stdout, "", log.LstdFlags),
-			IsVerbose: verbose,
+			Logger:    log.New(os.Stderr, "", log.LstdFlags),
+			IsVerbose: verbose && !quiet,
 		},
 	}
 }
 
 func checkGlobals(c *cli.Context) error {
+	if err := applyCommandFlags(c); err != nil {
+		return err
+	}
 	if host == "" {
 		return cli.NewExitError("kibana host not defined", 1)
 	}
-	if username == "" {
-		return cli.NewExitError("kibana username not defined", 1)
+	switch auth {
+	case "basic":
+	case "sigv4":
+		if awsRegion == "" {
+			return cli.NewExitError("aws region not defined, required by --auth sigv4", 1)
+		}
+	default:
+		return cli.NewExitError(fmt.Sprintf("unsupported auth scheme %v", auth), 1)
 	}
-	if password == "" {
-		return cli.NewExitError("kibana password not defined", 1)
+	// the credentials of a credential source are only fetched with the first request
+	if auth == "basic" && apiKey == "" && credentialSource == "" {
+		if username == "" {
+			return cli.NewExitError("kibana username not defined", 1)
+		}
+		if password == "" {
+			return cli.NewExitError("kibana password not defined", 1)
+		}
+	}
+	switch compat {
+	case "6", "7", "auto":
+	default:
+		return cli.NewExitError(fmt.Sprintf("unsupported compat mode %v", compat), 1)
+	}
+	switch flavor {
+	case "kibana", "opensearch":
+	default:
+		return cli.NewExitError(fmt.Sprintf("unsupported flavor %v", flavor), 1)
 	}
 	return nil
 }
@@ -117,14 +1616,153 @@ func _import(c *cli.Context) error {
 	if err := checkGlobals(c); err != nil {
 		return err
 	}
-	bytes, err := ioutil.ReadAll(os.Stdin)
+	if c.Args().First() == "" {
+		if err := requireStdin("reading the import from the terminal"); err != nil {
+			return err
+		}
+	}
+	bytes, err := readImportInput(c)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if file := c.String("values"); file != "" {
+		values, err := loadValues(file)
+		if err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		bytes, err = renderTemplate(bytes, values)
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+	}
+	if locale := c.String("locale"); locale != "" {
+		if bytes, err = localize(bytes, c.String("translations"), locale); err != nil {
+			return cli.NewExitError(err, 2)
+		}
+	}
+	overrides := timeOverrides{From: c.String("time-from"), To: c.String("time-to")}
+	if c.IsSet("refresh-interval") {
+		interval := c.Duration("refresh-interval")
+		overrides.RefreshInterval = &interval
+	}
+	if types := c.String("skip-type"); types != "" {
+		bytes, err = skipTypes(bytes, strings.Split(types, ","), func(object gjson.Result) {
+			emitObject("import", "skipped", object, nil)
+		})
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+	}
+	bytes, err = applyTimeOverrides(bytes, overrides)
 	if err != nil {
-		return cli.NewExitError(errors.Wrap(err, "could not read import input"), 2)
+		return cli.NewExitError(err, 2)
+	}
+	if file := c.String("overlay"); file != "" {
+		bytes, err = applyOverlay(bytes, file)
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+	}
+	if file := c.String("link-map"); file != "" {
+		links, err := loadLinkMap(file)
+		if err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		bytes, err = rewriteLinks(bytes, links)
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+	}
+	if c.Bool("enforce-policy") {
+		if err := enforcePolicy(c, bytes); err != nil {
+			return err
+		}
+	}
+	client := newClient()
+	client.ChunkSize = c.Int("chunk-size") << 10
+	client.Compress = c.Bool("compress")
+	if c.Bool("merge-field-formats") {
+		bytes, err = client.mergeFieldSettings(bytes)
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+	}
+	if c.Bool("follow-aliases") {
+		bytes, err = client.followObjectAliases(bytes)
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+	}
+	if c.Bool("require-refs") {
+		missing, err := client.missingDependencies(bytes)
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		if len(missing) > 0 {
+			list := make([]string, len(missing))
+			for i, dep := range missing {
+				list[i] = "  " + dep.String()
+			}
+			return cli.NewExitError(fmt.Sprintf("missing prerequisites, nothing imported:\n%v", strings.Join(list, "\n")), 2)
+		}
+	}
+	if spec := c.String("annotate"); spec != "" {
+		annotations, err := parseAnnotations(spec)
+		if err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		switch c.String("annotate-as") {
+		case "tags":
+			bytes, err = client.annotateTags(bytes, annotations)
+		case "attribute":
+			bytes, err = annotateAttribute(bytes, annotations)
+		default:
+			return cli.NewExitError(fmt.Sprintf("unsupported annotation storage %v, tags or attribute expected", c.String("annotate-as")), 1)
+		}
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+	}
+	if c.Bool("managed") {
+		supported, product, err := client.supportsManaged()
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		if !supported {
+			return cli.NewExitError(fmt.Sprintf("%v does not support managed objects, kibana 8.10 or later required", product), 2)
+		}
+		if bytes, err = markManaged(bytes); err != nil {
+			return cli.NewExitError(err, 2)
+		}
+	}
+	if !c.Bool("no-validate") {
+		findings, err := client.checkSchemas(bytes)
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		if errorCount := printFindings(findings); errorCount > 0 {
+			return cli.NewExitError(fmt.Sprintf("%d schema error(s) found, nothing imported", errorCount), 3)
+		}
+	}
+	var importHooks hooks
+	if !c.Bool("no-hooks") {
+		conf, err := loadConfig()
+		if err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		importHooks = conf.Hooks
 	}
-	err = newClient()._import(bytes)
+	if err := runHooks(importHooks.PreImport, newOperationSummary("import", "pre", bytes, nil)); err != nil {
+		return cli.NewExitError(errors.Wrap(err, "nothing imported"), 2)
+	}
+	emitObjects("import", "started", bytes, nil)
+	err = client._import(bytes)
+	emitObjects("import", "succeeded", bytes, err)
+	runPostHooks(importHooks.PostImport, newOperationSummary("import", "post", bytes, err))
 	if err != nil {
 		return cli.NewExitError(err, 2)
 	}
+	printIDs(payloadIDs(bytes)...)
 	return nil
 }
 
@@ -136,26 +1774,200 @@ func export(c *cli.Context) error {
 	if name == "" {
 		return cli.NewExitError("dashboard name missing", 1)
 	}
-	dashboard, err := newClient().export(name)
+	if err := checkExportFlags(c); err != nil {
+		return err
+	}
+	client := newClient()
+	client.SkipMissingDeps = c.Bool("skip-missing-deps")
+	client.DepMatch = c.String("dep-match")
+	dashboard, err := client.export(name)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	return writeExport(c, dashboard)
+}
+
+func exportAll(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	if err := checkExportFlags(c); err != nil {
+		return err
+	}
+	client := newClient()
+	client.SkipMissingDeps = c.Bool("skip-missing-deps")
+	client.DepMatch = c.String("dep-match")
+	dashboards, err := selectDashboards(c, client)
 	if err != nil {
+		return err
+	}
+	if len(dashboards) == 0 {
+		return cli.NewExitError(fmt.Sprintf("no dashboard found matching: %v", c.Args().First()), 2)
+	}
+	var ids []string
+	for _, d := range dashboards {
+		ids = append(ids, d.ID)
+		emit(event{Command: "export-all", Event: "started", Type: "dashboard", ID: d.ID, Title: d.Attributes.Title})
+	}
+	bundle, err := client.exportDashboards(ids...)
+	if err != nil {
+		for _, d := range dashboards {
+			emit(event{Command: "export-all", Event: "failed", Type: "dashboard", ID: d.ID, Title: d.Attributes.Title, Error: err.Error()})
+		}
 		return cli.NewExitError(err, 2)
 	}
-	os.Stdout.Write(dashboard)
+	if err := writeExport(c, bundle); err != nil {
+		return err
+	}
+	emitObjects("export-all", "succeeded", bundle, nil)
 	return nil
 }
 
+func checkExportFlags(c *cli.Context) error {
+	if c.Bool("extract-vega") && c.String("split") == "" {
+		return cli.NewExitError("--extract-vega requires --split", 1)
+	}
+	if c.Bool("extract-markdown") && c.String("split") == "" {
+		return cli.NewExitError("--extract-markdown requires --split", 1)
+	}
+	if c.Bool("no-deps") && c.Bool("deps-only") {
+		return cli.NewExitError("--no-deps and --deps-only are mutually exclusive", 1)
+	}
+	switch c.String("dep-match") {
+	case "id", "title-exact", "title-fuzzy":
+	default:
+		return cli.NewExitError(fmt.Sprintf("unsupported dependency match %v", c.String("dep-match")), 1)
+	}
+	switch c.String("provenance") {
+	case "", "attribute":
+	case "sidecar":
+		if c.String("split") == "" {
+			return cli.NewExitError("--provenance sidecar requires --split", 1)
+		}
+	default:
+		return cli.NewExitError(fmt.Sprintf("unsupported provenance storage %v, sidecar or attribute expected", c.String("provenance")), 1)
+	}
+	return nil
+}
+
+// writeExport writes the export to stdout or split under the --split
+// directory, filtered and normalized first when requested.
+func writeExport(c *cli.Context, export []byte) error {
+	var err error
+	if c.Bool("no-deps") || c.Bool("deps-only") {
+		dashboards := c.Bool("no-deps")
+		export, err = filterObjects(export, func(object gjson.Result) bool {
+			return (object.Get("type").String() == "dashboard") == dashboards
+		})
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+	}
+	if c.Bool("normalize") {
+		export, err = normalizeExport(export)
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+	}
+	origin := exportProvenance(export, host, space)
+	if c.String("provenance") == "attribute" {
+		if export, err = addProvenance(export, origin); err != nil {
+			return cli.NewExitError(err, 2)
+		}
+	}
+	if dir := c.String("split"); dir != "" {
+		if err := splitBundle(export, dir, splitOptions{ExtractVega: c.Bool("extract-vega"), ExtractMarkdown: c.Bool("extract-markdown")}); err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		if c.String("provenance") == "sidecar" {
+			if err := writeProvenance(dir, origin); err != nil {
+				return cli.NewExitError(err, 2)
+			}
+		}
+		return nil
+	}
+	if jsonl() {
+		return cli.NewExitError("--output jsonl requires --split as the export goes to stdout otherwise", 1)
+	}
+	os.Stdout.Write(export)
+	return nil
+}
+
+// selectDashboards returns the dashboards matching the pattern argument, as a
+// glob or as a regular expression with --regex.
+func selectDashboards(c *cli.Context, client *client) ([]dashboard, error) {
+	pattern := c.Args().First()
+	if !c.Bool("regex") {
+		dashboards, err := client.listDashboards(pattern)
+		if err != nil {
+			return nil, cli.NewExitError(err, 2)
+		}
+		return dashboards, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, cli.NewExitError(errors.Wrapf(err, "invalid regular expression %v", pattern), 1)
+	}
+	dashboards, err := client.matchDashboards("", re)
+	if err != nil {
+		return nil, cli.NewExitError(err, 2)
+	}
+	return dashboards, nil
+}
+
+// printIDs prints the ids of the objects affected by a command in quiet mode,
+// unless they are reported as events.
+func printIDs(ids ...string) {
+	if !quiet || jsonl() {
+		return
+	}
+	for _, id := range ids {
+		os.Stdout.WriteString(id + "\n")
+	}
+}
+
+// payloadIDs returns the ids of the objects of an export.
+func payloadIDs(payload []byte) []string {
+	var ids []string
+	for _, id := range gjson.GetBytes(payload, "objects.#.id").Array() {
+		ids = append(ids, id.String())
+	}
+	return ids
+}
+
+// readImportInput returns the payload to import from the split export
+// directory given as argument, or from stdin.
+func readImportInput(c *cli.Context) ([]byte, error) {
+	if dir := c.Args().First(); dir != "" {
+		return joinBundle(dir)
+	}
+	if c.GlobalBool("password-stdin") || c.Bool("password-stdin") {
+		return nil, errors.New("stdin holds the password with --password-stdin, give the export as a split directory.\n")
+	}
+	bytes, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read import input")
+	}
+	return bytes, nil
+}
+
 func list(c *cli.Context) error {
 	if err := checkGlobals(c); err != nil {
 		return err
 	}
-	pattern := c.Args().First()
-	dashboards, err := newClient().searchDashboard(pattern)
+	dashboards, err := selectDashboards(c, newClient())
 	if err != nil {
-		return cli.NewExitError(err, 2)
+		return err
+	}
+	if quiet {
+		for _, val := range dashboards {
+			printIDs(val.ID)
+		}
+		return nil
 	}
-	os.Stdout.WriteString(fmt.Sprintf("%-40v %v\n", "ID", "NAME"))
+	os.Stdout.WriteString(stdout.header(fmt.Sprintf("%-40v %v", "ID", "NAME")) + "\n")
 	for _, val := range dashboards {
-		os.Stdout.WriteString(fmt.Sprintf("%-40v %v\n", val.ID, val.Attributes.Title))
+		os.Stdout.WriteString(fmt.Sprintf("%v %v\n", stdout.paint(cyan, fmt.Sprintf("%-40v", val.ID)), val.Attributes.Title))
 	}
 	return nil
 }
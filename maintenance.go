@@ -0,0 +1,134 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+const maintenanceWindowAPI = "/internal/alerting/rules/maintenance_window"
+
+type maintenanceWindow struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Status string `json:"status"`
+	// Duration is in milliseconds
+	Duration int64 `json:"duration"`
+	RRule    struct {
+		Start string `json:"dtstart"`
+	} `json:"r_rule"`
+}
+
+func (c *client) createMaintenanceWindow(title string, start time.Time, duration time.Duration) (maintenanceWindow, error) {
+	payload := map[string]interface{}{
+		"title":    title,
+		"duration": int64(duration / time.Millisecond),
+		"r_rule": map[string]interface{}{
+			"dtstart": start.UTC().Format(time.RFC3339),
+			"tzid":    "UTC",
+			"count":   1,
+		},
+	}
+	body, err := c.jsonRequest("POST", maintenanceWindowAPI, payload)
+	if err != nil {
+		return maintenanceWindow{}, err
+	}
+	var window maintenanceWindow
+	if err := json.Unmarshal(body, &window); err != nil {
+		return maintenanceWindow{}, errors.Wrap(err, "could not parse maintenance window")
+	}
+	return window, nil
+}
+
+func (c *client) listMaintenanceWindows() ([]maintenanceWindow, error) {
+	body, err := c.jsonRequest("GET", maintenanceWindowAPI+"/_find", nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Data []maintenanceWindow `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, errors.Wrap(err, "could not parse maintenance windows")
+	}
+	return result.Data, nil
+}
+
+func (c *client) deleteMaintenanceWindow(id string) error {
+	_, err := c.jsonRequest("DELETE", maintenanceWindowAPI+"/"+url.PathEscape(id), nil)
+	return err
+}
+
+func maintenanceWindowCreate(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	title := c.Args().First()
+	if title == "" {
+		return cli.NewExitError("maintenance window title missing", 1)
+	}
+	duration := c.Duration("duration")
+	if duration <= 0 {
+		return cli.NewExitError("--duration required", 1)
+	}
+	start := time.Now()
+	if value := c.String("start"); value != "" {
+		var err error
+		start, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			return cli.NewExitError(errors.Wrap(err, "invalid --start, RFC 3339 time expected"), 1)
+		}
+	}
+	window, err := newClient().createMaintenanceWindow(title, start, duration)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if quiet {
+		printIDs(window.ID)
+		return nil
+	}
+	fmt.Fprintf(os.Stdout, "created maintenance window %v\n", window.ID)
+	return nil
+}
+
+func maintenanceWindowList(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	windows, err := newClient().listMaintenanceWindows()
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if quiet {
+		for _, w := range windows {
+			printIDs(w.ID)
+		}
+		return nil
+	}
+	os.Stdout.WriteString(stdout.header(fmt.Sprintf("%-40v %-10v %-25v %-10v %v", "ID", "STATUS", "START", "DURATION", "TITLE")) + "\n")
+	for _, w := range windows {
+		duration := time.Duration(w.Duration) * time.Millisecond
+		fmt.Fprintf(os.Stdout, "%v %-10v %-25v %-10v %v\n", stdout.paint(cyan, fmt.Sprintf("%-40v", w.ID)), w.Status, w.RRule.Start, duration, w.Title)
+	}
+	return nil
+}
+
+func maintenanceWindowDelete(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	id := c.Args().First()
+	if id == "" {
+		return cli.NewExitError("maintenance window id missing", 1)
+	}
+	if err := newClient().deleteMaintenanceWindow(id); err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	printIDs(id)
+	return nil
+}
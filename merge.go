@@ -0,0 +1,227 @@
+package kibctl
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"github.com/urfave/cli"
+)
+
+// sectionHeight is the height of the markdown panels titling the sections of
+// a merged dashboard.
+const sectionHeight = 3
+
+// mergeSource is a dashboard to merge along with the export including it.
+type mergeSource struct {
+	Dashboard gjson.Result
+	Export    []byte
+}
+
+// markdownVisualization returns a markdown visualization saved object.
+func markdownVisualization(id, title, markdown string) (string, error) {
+	visState, err := json.Marshal(map[string]interface{}{
+		"title":  title,
+		"type":   "markdown",
+		"params": map[string]interface{}{"markdown": markdown, "fontSize": 12, "openLinksInNewTab": false},
+		"aggs":   []interface{}{},
+	})
+	if err != nil {
+		return "", err
+	}
+	object, err := json.Marshal(map[string]interface{}{
+		"type": "visualization",
+		"id":   id,
+		"attributes": map[string]interface{}{
+			"title":                 title,
+			"visState":              string(visState),
+			"uiStateJSON":           "{}",
+			"description":           "",
+			"kibanaSavedObjectMeta": map[string]string{"searchSourceJSON": `{"query":{"query":"","language":"kuery"},"filter":[]}`},
+		},
+		"references": []reference{},
+	})
+	return string(object), err
+}
+
+// mergeDashboards combines the dashboards into a single one titled title:
+// their panels are stacked in the given order, offsetting the grid rows, and
+// the objects they share are only kept once. The settings and the filters of
+// the combined dashboard are the ones of the first dashboard. With sections,
+// a markdown panel titles the panels of every source dashboard.
+func mergeDashboards(sources []mergeSource, title string, sections bool) ([]byte, error) {
+	var ids []string
+	for _, source := range sources {
+		ids = append(ids, source.Dashboard.Get("id").String())
+	}
+	id := fmt.Sprintf("kibctl-%x", sha256.Sum256([]byte(strings.Join(ids, "\x00")+"\x00"+title)))[:19]
+
+	seen := make(map[string]bool)
+	var objects []string
+	var panels []map[string]interface{}
+	var references, otherRefs []reference
+	offset := 0
+	addPanel := func(panel map[string]interface{}, ref *reference) {
+		index := fmt.Sprint(len(panels) + 1)
+		panel["panelIndex"] = index
+		if grid, ok := panel["gridData"].(map[string]interface{}); ok {
+			grid["i"] = index
+			grid["y"] = int(number(grid["y"])) + offset
+		}
+		if ref != nil {
+			ref.Name = fmt.Sprintf("panel_%d", len(panels))
+			panel["panelRefName"] = ref.Name
+			references = append(references, *ref)
+		}
+		panels = append(panels, panel)
+	}
+
+	for i, source := range sources {
+		for _, object := range gjson.GetBytes(source.Export, "objects").Array() {
+			key := object.Get("type").String() + ":" + object.Get("id").String()
+			if object.Get("type").String() == "dashboard" && object.Get("id").String() == source.Dashboard.Get("id").String() || seen[key] {
+				continue
+			}
+			seen[key] = true
+			objects = append(objects, object.Raw)
+		}
+
+		dashboardTitle := source.Dashboard.Get("attributes.title").String()
+		if sections {
+			sectionID := fmt.Sprintf("%v-section-%d", id, i)
+			section, err := markdownVisualization(sectionID, dashboardTitle, "## "+dashboardTitle)
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, section)
+			addPanel(map[string]interface{}{
+				"gridData":         map[string]interface{}{"x": 0, "y": 0, "w": gridColumns, "h": sectionHeight},
+				"embeddableConfig": map[string]interface{}{"hidePanelTitles": true},
+				"version":          "7.10.0",
+			}, &reference{Type: "visualization", ID: sectionID})
+			offset += sectionHeight
+		}
+
+		refs := make(map[string]reference)
+		for _, ref := range source.Dashboard.Get("references").Array() {
+			refs[ref.Get("name").String()] = reference{Type: ref.Get("type").String(), ID: ref.Get("id").String()}
+		}
+		panelRefs := make(map[string]bool)
+		var sourcePanels []map[string]interface{}
+		decoder := json.NewDecoder(strings.NewReader(source.Dashboard.Get("attributes.panelsJSON").String()))
+		decoder.UseNumber()
+		if err := decoder.Decode(&sourcePanels); err != nil {
+			return nil, errors.Wrapf(err, "could not parse the panels of dashboard %v", dashboardTitle)
+		}
+		bottom := 0
+		for _, panel := range sourcePanels {
+			if grid, ok := panel["gridData"].(map[string]interface{}); ok {
+				if y := int(number(grid["y"]) + number(grid["h"])); y > bottom {
+					bottom = y
+				}
+			}
+			var panelRef *reference
+			if ref, ok := refs[fmt.Sprint(panel["panelRefName"])]; ok {
+				panelRefs[fmt.Sprint(panel["panelRefName"])] = true
+				panelRef = &ref
+			}
+			addPanel(panel, panelRef)
+		}
+		offset += bottom
+		if i == 0 {
+			// the data views of the filters and controls of the first dashboard
+			for _, ref := range source.Dashboard.Get("references").Array() {
+				if !panelRefs[ref.Get("name").String()] {
+					otherRefs = append(otherRefs, reference{Type: ref.Get("type").String(), ID: ref.Get("id").String(), Name: ref.Get("name").String()})
+				}
+			}
+		}
+	}
+	references = append(references, otherRefs...)
+
+	first := sources[0].Dashboard
+	panelsJSON, err := encodeJSON(panels)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := sjson.Set(first.Raw, "id", id)
+	if err != nil {
+		return nil, err
+	}
+	for _, update := range []struct {
+		path  string
+		value interface{}
+	}{
+		{"attributes.title", title},
+		{"attributes.panelsJSON", panelsJSON},
+		{"references", references},
+		{"version", nil},
+	} {
+		if update.value == nil {
+			merged, err = sjson.Delete(merged, update.path)
+		} else {
+			merged, err = sjson.Set(merged, update.path, update.value)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	objects = append(objects, merged)
+
+	export := []byte(`{"objects":[]}`)
+	for _, object := range objects {
+		if export, err = sjson.SetRawBytes(export, "objects.-1", []byte(object)); err != nil {
+			return nil, err
+		}
+	}
+	return export, nil
+}
+
+// mergeSources exports the dashboards of the names, each along with the
+// objects it depends on.
+func (c *client) mergeSources(names []string) ([]mergeSource, error) {
+	var sources []mergeSource
+	for _, name := range names {
+		found, err := c.findDashboard(name)
+		if err != nil {
+			return nil, err
+		}
+		export, err := c.exportDashboards(found.ID)
+		if err != nil {
+			return nil, err
+		}
+		dashboard := gjson.GetBytes(export, fmt.Sprintf(`objects.#(id==%q)`, found.ID))
+		if !dashboard.Exists() {
+			return nil, errors.Errorf("dashboard %v missing from its export.\n", found.ID)
+		}
+		sources = append(sources, mergeSource{Dashboard: dashboard, Export: export})
+	}
+	return sources, nil
+}
+
+func mergeDashboardsCommand(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	if c.NArg() < 2 {
+		return cli.NewExitError("at least two dashboard names expected", 1)
+	}
+	title := c.String("title")
+	if title == "" {
+		return cli.NewExitError("--title missing", 1)
+	}
+
+	sources, err := newClient().mergeSources(c.Args())
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	merged, err := mergeDashboards(sources, title, c.Bool("section-per-source"))
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	return writeExport(c, merged)
+}
@@ -0,0 +1,144 @@
+package kibctl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestMergeDashboards(t *testing.T) {
+	first := `{"type":"dashboard","id":"d1","version":"WzEsMV0=","attributes":{"title":"Nginx","panelsJSON":"[{\"panelIndex\":\"1\",\"gridData\":{\"x\":0,\"y\":0,\"w\":24,\"h\":10,\"i\":\"1\"},\"panelRefName\":\"panel_0\"}]"},"references":[{"name":"panel_0","type":"visualization","id":"shared"},{"name":"filter_0","type":"index-pattern","id":"logs"}]}`
+	second := `{"type":"dashboard","id":"d2","attributes":{"title":"Redis","panelsJSON":"[{\"panelIndex\":\"1\",\"gridData\":{\"x\":0,\"y\":0,\"w\":24,\"h\":8,\"i\":\"1\"},\"panelRefName\":\"panel_0\"},{\"panelIndex\":\"2\",\"gridData\":{\"x\":24,\"y\":0,\"w\":24,\"h\":8,\"i\":\"2\"},\"panelRefName\":\"panel_1\"}]"},"references":[{"name":"panel_0","type":"visualization","id":"shared"},{"name":"panel_1","type":"visualization","id":"redis"},{"name":"filter_0","type":"index-pattern","id":"metrics"}]}`
+	sources := []mergeSource{
+		{Dashboard: gjson.Parse(first), Export: []byte(`{"objects":[` + first + `,{"type":"visualization","id":"shared"},{"type":"index-pattern","id":"logs"}]}`)},
+		{Dashboard: gjson.Parse(second), Export: []byte(`{"objects":[` + second + `,{"type":"visualization","id":"shared"},{"type":"visualization","id":"redis"}]}`)},
+	}
+
+	tests := []struct {
+		name       string
+		sections   bool
+		objects    []string
+		panels     []string
+		references []string
+	}{
+		{
+			name:       "stacked",
+			objects:    []string{"visualization:shared", "index-pattern:logs", "visualization:redis", "dashboard:"},
+			panels:     []string{"1:0", "2:10", "3:10"},
+			references: []string{"panel_0:shared", "panel_1:shared", "panel_2:redis", "filter_0:logs"},
+		},
+		{
+			name:       "sections",
+			sections:   true,
+			objects:    []string{"visualization:shared", "index-pattern:logs", "visualization:-section-0", "visualization:redis", "visualization:-section-1", "dashboard:"},
+			panels:     []string{"1:0", "2:3", "3:13", "4:16", "5:16"},
+			references: []string{"panel_0:-section-0", "panel_1:shared", "panel_2:-section-1", "panel_3:shared", "panel_4:redis", "filter_0:logs"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			export, err := mergeDashboards(sources, "Services", test.sections)
+			if err != nil {
+				t.Fatalf("mergeDashboards() error: %v", err)
+			}
+			objects := gjson.GetBytes(export, "objects").Array()
+			merged := objects[len(objects)-1]
+			id := merged.Get("id").String()
+			if !strings.HasPrefix(id, "kibctl-") {
+				t.Errorf("merged dashboard id = %v, want a kibctl- id", id)
+			}
+			// the ids derived from the merged dashboard id are compared without it
+			strip := func(s string) string { return strings.Replace(s, id, "", 1) }
+
+			var gotObjects []string
+			for _, object := range objects {
+				gotObjects = append(gotObjects, object.Get("type").String()+":"+strip(object.Get("id").String()))
+			}
+			if strings.Join(gotObjects, " ") != strings.Join(test.objects, " ") {
+				t.Errorf("objects = %v, want %v", gotObjects, test.objects)
+			}
+			if title := merged.Get("attributes.title").String(); title != "Services" {
+				t.Errorf("title = %v, want Services", title)
+			}
+			if merged.Get("version").Exists() {
+				t.Error("merged dashboard kept the version of the first dashboard")
+			}
+
+			var gotPanels []string
+			for _, panel := range gjson.Parse(merged.Get("attributes.panelsJSON").String()).Array() {
+				gotPanels = append(gotPanels, panel.Get("panelIndex").String()+":"+panel.Get("gridData.y").Raw)
+			}
+			if strings.Join(gotPanels, " ") != strings.Join(test.panels, " ") {
+				t.Errorf("panels = %v, want %v", gotPanels, test.panels)
+			}
+
+			var gotReferences []string
+			for _, ref := range merged.Get("references").Array() {
+				gotReferences = append(gotReferences, ref.Get("name").String()+":"+strip(ref.Get("id").String()))
+			}
+			if strings.Join(gotReferences, " ") != strings.Join(test.references, " ") {
+				t.Errorf("references = %v, want %v", gotReferences, test.references)
+			}
+		})
+	}
+}
+
+func TestMergeSources(t *testing.T) {
+	tests := []struct {
+		name    string
+		exports map[string]string
+		want    string
+	}{
+		{
+			"exported",
+			map[string]string{
+				"d1": `{"objects":[{"id":"d1","type":"dashboard","attributes":{"title":"Nginx","panelsJSON":"[]"},"references":[]}]}`,
+				"d2": `{"objects":[{"id":"d2","type":"dashboard","attributes":{"title":"Redis","panelsJSON":"[]"},"references":[]}]}`,
+			},
+			"",
+		},
+		{
+			"dashboard missing from its export",
+			map[string]string{
+				"d1": `{"objects":[{"id":"d1","type":"dashboard","attributes":{"title":"Nginx","panelsJSON":"[]"},"references":[]}]}`,
+				"d2": `{"objects":[]}`,
+			},
+			"dashboard d2 missing from its export",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/saved_objects/_find":
+					title := strings.Trim(r.URL.Query().Get("search"), `"`)
+					id := map[string]string{"Nginx": "d1", "Redis": "d2"}[title]
+					w.Write([]byte(`{"total":1,"saved_objects":[{"id":"` + id + `","type":"dashboard","attributes":{"title":"` + title + `"}}]}`))
+				case "/api/kibana/dashboards/export":
+					w.Write([]byte(test.exports[r.URL.Query().Get("dashboard")]))
+				default:
+					t.Errorf("unexpected request %v %v", r.Method, r.URL)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			sources, err := newTestClient(server).mergeSources([]string{"Nginx", "Redis"})
+			switch {
+			case test.want == "" && err != nil:
+				t.Fatalf("mergeSources() error: %v", err)
+			case test.want != "":
+				if err == nil || !strings.Contains(err.Error(), test.want) {
+					t.Errorf("mergeSources() error = %v, want %v", err, test.want)
+				}
+				return
+			}
+			if len(sources) != 2 || sources[0].Dashboard.Get("id").String() != "d1" || sources[1].Dashboard.Get("id").String() != "d2" {
+				t.Errorf("mergeSources() = %v, want the dashboards d1 and d2", sources)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// mergePatch applies a json merge patch (RFC 7386) to the target document.
+// Since Kibana stores nested documents such as optionsJSON or visState as json
+// encoded strings, a patch object applied to such a string is merged into the
+// decoded document which is then encoded back.
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	if encoded, ok := target.(string); ok {
+		if decoded, err := decodeJSON(encoded); err == nil {
+			if _, ok := decoded.(map[string]interface{}); ok {
+				merged, err := encodeJSON(mergePatch(decoded, patch))
+				if err == nil {
+					return merged
+				}
+			}
+		}
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+	return targetObject
+}
+
+// decodeJSON decodes a document keeping numbers as they were written.
+func decodeJSON(document string) (interface{}, error) {
+	var value interface{}
+	decoder := json.NewDecoder(strings.NewReader(document))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
@@ -0,0 +1,123 @@
+package kibctl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"github.com/urfave/cli"
+)
+
+// normalizeExport rewrites the panelsJSON of every dashboard in the export so
+// that panels are sorted by grid position and coordinates are integers,
+// leaving semantically identical dashboards byte for byte identical.
+func normalizeExport(export []byte) ([]byte, error) {
+	for i, object := range gjson.GetBytes(export, "objects").Array() {
+		panels := object.Get("attributes.panelsJSON")
+		if object.Get("type").String() != "dashboard" || !panels.Exists() {
+			continue
+		}
+		normalized, err := normalizePanels(panels.String())
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not normalize dashboard %v", object.Get("id").String())
+		}
+		export, err = sjson.SetBytes(export, fmt.Sprintf("objects.%d.attributes.panelsJSON", i), normalized)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return export, nil
+}
+
+func normalizePanels(panelsJSON string) (string, error) {
+	var panels []map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(panelsJSON))
+	decoder.UseNumber()
+	if err := decoder.Decode(&panels); err != nil {
+		return "", err
+	}
+
+	for _, panel := range panels {
+		grid, ok := panel["gridData"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, key := range []string{"x", "y", "w", "h"} {
+			if n, ok := grid[key].(json.Number); ok {
+				f, err := n.Float64()
+				if err != nil {
+					return "", err
+				}
+				grid[key] = int(math.Round(f))
+			}
+		}
+	}
+
+	sort.SliceStable(panels, func(i, j int) bool {
+		rowI, colI := panelPosition(panels[i])
+		rowJ, colJ := panelPosition(panels[j])
+		if rowI != rowJ {
+			return rowI < rowJ
+		}
+		return colI < colJ
+	})
+
+	return encodeJSON(panels)
+}
+
+// panelPosition returns the row and column of a panel, for both the 7.x
+// gridData layout and the legacy 6.x row/col layout.
+func panelPosition(panel map[string]interface{}) (float64, float64) {
+	if grid, ok := panel["gridData"].(map[string]interface{}); ok {
+		return number(grid["y"]), number(grid["x"])
+	}
+	return number(panel["row"]), number(panel["col"])
+}
+
+func number(value interface{}) float64 {
+	switch n := value.(type) {
+	case int:
+		return float64(n)
+	case json.Number:
+		f, _ := n.Float64()
+		return f
+	}
+	return 0
+}
+
+// encodeJSON marshals the value without escaping html characters, keeping
+// markdown and urls readable in diffs.
+func encodeJSON(value interface{}) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+func normalize(c *cli.Context) error {
+	file := c.Args().First()
+	if file == "" {
+		return cli.NewExitError("export file missing", 1)
+	}
+	export, err := ioutil.ReadFile(file)
+	if err != nil {
+		return cli.NewExitError(errors.Wrap(err, "could not read export file"), 2)
+	}
+	normalized, err := normalizeExport(export)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	os.Stdout.Write(normalized)
+	return nil
+}
@@ -0,0 +1,75 @@
+package kibctl
+
+import (
+	"fmt"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// rewriteReferences points the references of the payload objects to the
+// object of the given type at its new id, including the pre 7.0 index links
+// of the searchSourceJSON.
+func rewriteReferences(payload []byte, objectType, oldID, newID string) ([]byte, error) {
+	var err error
+	for i, object := range gjson.GetBytes(payload, "objects").Array() {
+		for j, ref := range object.Get("references").Array() {
+			if ref.Get("type").String() != objectType || ref.Get("id").String() != oldID {
+				continue
+			}
+			payload, err = sjson.SetBytes(payload, fmt.Sprintf("objects.%d.references.%d.id", i, j), newID)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		source := object.Get("attributes.kibanaSavedObjectMeta.searchSourceJSON")
+		if objectType != "index-pattern" || gjson.Get(source.String(), "index").String() != oldID {
+			continue
+		}
+		rewritten, err := sjson.Set(source.String(), "index", newID)
+		if err != nil {
+			return nil, err
+		}
+		payload, err = sjson.SetBytes(payload, fmt.Sprintf("objects.%d.attributes.kibanaSavedObjectMeta.searchSourceJSON", i), rewritten)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return payload, nil
+}
+
+// followObjectAliases moves the payload objects whose id is a legacy url alias
+// on kibana to the id of the alias target, so that the import updates the
+// object the alias leads to instead of creating a copy under the old id.
+func (c *client) followObjectAliases(payload []byte) ([]byte, error) {
+	objects := gjson.GetBytes(payload, "objects").Array()
+	if len(objects) == 0 {
+		return payload, nil
+	}
+	deps := make([]dependency, len(objects))
+	for i, object := range objects {
+		deps[i] = dependency{Type: object.Get("type").String(), ID: object.Get("id").String()}
+	}
+	resolved, err := c.bulkResolve(deps)
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range resolved {
+		switch r.Outcome {
+		case "aliasMatch":
+			c.Logger.Printf("%v id %v is an alias of %v, importing under the target id\n", deps[i].Type, deps[i].ID, r.SavedObject.ID)
+			payload, err = sjson.SetBytes(payload, fmt.Sprintf("objects.%d.id", i), r.SavedObject.ID)
+			if err != nil {
+				return nil, err
+			}
+			payload, err = rewriteReferences(payload, deps[i].Type, deps[i].ID, r.SavedObject.ID)
+			if err != nil {
+				return nil, err
+			}
+		case "conflict":
+			c.Logger.Printf("%v id %v is also the alias of %v, importing under the exact id\n", deps[i].Type, deps[i].ID, r.AliasTargetID)
+		}
+	}
+	return payload, nil
+}
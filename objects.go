@@ -0,0 +1,305 @@
+package kibctl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+)
+
+// savedObjectTypes are the saved object types handled by the generic commands.
+var savedObjectTypes = []string{"dashboard", "visualization", "lens", "search", "index-pattern"}
+
+// registerObjectTypes adds custom saved object types, e.g. of third-party
+// kibana plugins, to the ones handled by the generic commands.
+func registerObjectTypes(types ...string) {
+	for _, t := range types {
+		t = strings.TrimSpace(t)
+		if t == "" || contains(savedObjectTypes, t) {
+			continue
+		}
+		savedObjectTypes = append(savedObjectTypes, t)
+	}
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+type savedObject struct {
+	Type       string          `json:"type"`
+	ID         string          `json:"id"`
+	Attributes json.RawMessage `json:"attributes"`
+	References []reference     `json:"references"`
+	UpdatedAt  string          `json:"updated_at"`
+	Version    string          `json:"version"`
+}
+
+type reference struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func (o savedObject) title() string {
+	return gjson.GetBytes(o.Attributes, "title").String()
+}
+
+const findPageSize = 1000
+
+// findObjects pages through the saved objects of the given types, optionally
+// filtered by a title search whose terms must all match.
+func (c *client) findObjects(types []string, search string) ([]savedObject, error) {
+	query := url.Values{}
+	if search != "" {
+		query.Set("search_fields", "title")
+		query.Set("search", search)
+		query.Set("default_search_operator", "AND")
+	}
+	major, err := c.major()
+	if err != nil {
+		return nil, err
+	}
+	if major == 6 && search != "" {
+		return c.findLegacyObjects(types, query)
+	}
+	return c.find(types, query)
+}
+
+// findReferencing pages through the saved objects of the given types which
+// refer to the object.
+func (c *client) findReferencing(types []string, objectType, id string) ([]savedObject, error) {
+	major, err := c.major()
+	if err != nil {
+		return nil, err
+	}
+	if major == 6 {
+		return nil, errors.Errorf("kibana 6 objects carry no references, the objects referring to %v %v cannot be searched.\n", objectType, id)
+	}
+	ref, err := json.Marshal(map[string]string{"type": objectType, "id": id})
+	if err != nil {
+		return nil, err
+	}
+	return c.find(types, url.Values{"has_reference": {string(ref)}})
+}
+
+func (c *client) find(types []string, filters url.Values) ([]savedObject, error) {
+	var objects []savedObject
+	for page := 1; ; page++ {
+		query := url.Values{}
+		for key, values := range filters {
+			query[key] = values
+		}
+		for _, t := range types {
+			query.Add("type", t)
+		}
+		query.Set("per_page", fmt.Sprint(findPageSize))
+		query.Set("page", fmt.Sprint(page))
+
+		req, err := c.newRequest("GET", "/api/saved_objects/_find?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, body, err := c.doRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, c.apiError(fmt.Sprintf("find %v objects", strings.Join(types, ",")), resp, body)
+		}
+
+		var result struct {
+			Total        int           `json:"total"`
+			SavedObjects []savedObject `json:"saved_objects"`
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, errors.Wrap(err, "could not parse saved objects")
+		}
+		objects = append(objects, result.SavedObjects...)
+		if len(result.SavedObjects) < findPageSize || len(objects) >= result.Total {
+			return objects, nil
+		}
+	}
+}
+
+// getObject retrieves a saved object, returning nil when it does not exist.
+func (c *client) getObject(objectType, id string) ([]byte, error) {
+	req, err := c.newRequest("GET", fmt.Sprintf("/api/saved_objects/%v/%v", objectType, url.PathEscape(id)), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, body, err := c.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.apiError(fmt.Sprintf("retrieve %v id %v", objectType, id), resp, body)
+	}
+	return body, nil
+}
+
+// deleteObject deletes a saved object, doing nothing when it does not exist.
+func (c *client) deleteObject(objectType, id string) error {
+	_, err := c.jsonRequest("DELETE", fmt.Sprintf("/api/saved_objects/%v/%v", objectType, url.PathEscape(id)), nil)
+	if apiErr, ok := err.(*apiError); ok && apiErr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// errVersionConflict reports an update rejected because the object was
+// modified since its version was retrieved.
+var errVersionConflict = errors.New("object changed since you exported it, retry or use --force to overwrite")
+
+// updateObject replaces the attributes and references of a saved object,
+// both given as raw json. When a version is given, the update is rejected if
+// the object changed since that version.
+func (c *client) updateObject(objectType, id, version, attributes, references string) error {
+	body := map[string]json.RawMessage{"attributes": json.RawMessage(attributes)}
+	if references != "" {
+		body["references"] = json.RawMessage(references)
+	}
+	if version != "" {
+		body["version"], _ = json.Marshal(version)
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := c.newRequest("PUT", fmt.Sprintf("/api/saved_objects/%v/%v", objectType, url.PathEscape(id)), bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+	resp, details, err := c.doRequest(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusConflict {
+		return errors.Wrapf(errVersionConflict, "%v %v", objectType, id)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return c.apiError(fmt.Sprintf("update %v id %v", objectType, id), resp, details)
+	}
+	return nil
+}
+
+// checkVersions verifies the objects of the export were not modified on kibana
+// since they were exported.
+func (c *client) checkVersions(export []byte) error {
+	var changed []string
+	for _, object := range gjson.GetBytes(export, "objects").Array() {
+		version := object.Get("version").String()
+		if version == "" {
+			continue
+		}
+		objectType, id := object.Get("type").String(), object.Get("id").String()
+		current, err := c.getObject(objectType, id)
+		if err != nil {
+			return err
+		}
+		if current != nil && gjson.GetBytes(current, "version").String() != version {
+			changed = append(changed, objectType+" "+id)
+		}
+	}
+	if len(changed) > 0 {
+		return errors.Wrap(errVersionConflict, strings.Join(changed, ", "))
+	}
+	return nil
+}
+
+// resolvedObject is the outcome of the resolution of a saved object id.
+type resolvedObject struct {
+	SavedObject struct {
+		Type  string `json:"type"`
+		ID    string `json:"id"`
+		Error *struct {
+			StatusCode int `json:"statusCode"`
+		} `json:"error"`
+	} `json:"saved_object"`
+	// Outcome is exactMatch, aliasMatch or conflict
+	Outcome       string `json:"outcome"`
+	AliasTargetID string `json:"alias_target_id"`
+}
+
+func (r resolvedObject) found() bool {
+	return r.SavedObject.Error == nil
+}
+
+// bulkResolve resolves the saved objects in a single request, following the
+// legacy url aliases left by the objects whose id changed. The results are in
+// the order of the dependencies. Kibana versions without the resolve api,
+// before 7.16, fall back to a plain retrieval.
+func (c *client) bulkResolve(deps []dependency) ([]resolvedObject, error) {
+	var body []map[string]string
+	for _, dep := range deps {
+		body = append(body, map[string]string{"type": dep.Type, "id": dep.ID})
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest("POST", "/api/saved_objects/_bulk_resolve", bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, err
+	}
+	resp, details, err := c.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return c.bulkGetResolved(deps)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.apiError("resolve saved objects", resp, details)
+	}
+	var result struct {
+		ResolvedObjects []resolvedObject `json:"resolved_objects"`
+	}
+	if err := json.Unmarshal(details, &result); err != nil {
+		return nil, errors.Wrap(err, "could not parse resolved objects")
+	}
+	if len(result.ResolvedObjects) != len(deps) {
+		return nil, errors.Errorf("expected %v resolved objects, got %v.\n", len(deps), len(result.ResolvedObjects))
+	}
+	return result.ResolvedObjects, nil
+}
+
+// resolveObject retrieves a saved object through the resolve api, following
+// the legacy url alias when its id changed. It returns the object along with
+// the resolution outcome, or nil when it does not exist. Kibana versions
+// without the resolve api fall back to a plain retrieval.
+func (c *client) resolveObject(objectType, id string) ([]byte, string, error) {
+	req, err := c.newRequest("GET", fmt.Sprintf("/api/saved_objects/resolve/%v/%v", objectType, url.PathEscape(id)), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, body, err := c.doRequest(req)
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		object, err := c.getObject(objectType, id)
+		return object, "exactMatch", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", c.apiError(fmt.Sprintf("resolve %v id %v", objectType, id), resp, body)
+	}
+	outcome := gjson.GetBytes(body, "outcome").String()
+	if outcome == "conflict" {
+		c.Logger.Printf("%v id %v is also the alias of %v, using the exact match\n", objectType, id, gjson.GetBytes(body, "alias_target_id").String())
+	}
+	return []byte(gjson.GetBytes(body, "saved_object").Raw), outcome, nil
+}
@@ -0,0 +1,124 @@
+package kibctl
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+type deprecation struct {
+	re      *regexp.Regexp
+	message string
+}
+
+var painlessDeprecations = []deprecation{
+	{regexp.MustCompile(`doc\[[^\]]+\]\.date\b`), "the .date accessor is deprecated, use .value"},
+	{regexp.MustCompile(`\.getMillis\(\)`), "getMillis() is deprecated, use toInstant().toEpochMilli()"},
+	{regexp.MustCompile(`\.value\.millis\b`), ".value.millis is deprecated, use .value.toInstant().toEpochMilli()"},
+	{regexp.MustCompile(`\.getDate\(\)`), "getDate() is deprecated, use .value"},
+	{regexp.MustCompile(`\bctx\._source\b`), "ctx._source is not available in search scripts, use doc values"},
+}
+
+var docValueAccess = regexp.MustCompile(`doc\[[^\]]+\]\.value\b`)
+var docSizeCheck = regexp.MustCompile(`\.size\(\)|\.empty\b|\.isEmpty\(\)|containsKey\(`)
+
+// lintScripts checks the painless sources of the scripted fields and runtime
+// fields of index-patterns.
+func lintScripts(object gjson.Result) []lintFinding {
+	if object.Get("type").String() != "index-pattern" {
+		return nil
+	}
+	var findings []lintFinding
+	attributes := object.Get("attributes")
+
+	for _, field := range gjson.Parse(attributes.Get("fields").String()).Array() {
+		if !field.Get("scripted").Bool() || field.Get("lang").String() != "painless" {
+			continue
+		}
+		for _, problem := range checkPainless(field.Get("script").String(), false) {
+			findings = append(findings, newFinding(object, problem.severity, "scripted-field", "%v: %v", field.Get("name").String(), problem.message))
+		}
+	}
+
+	gjson.Parse(attributes.Get("runtimeFieldMap").String()).ForEach(func(name, field gjson.Result) bool {
+		for _, problem := range checkPainless(field.Get("script.source").String(), true) {
+			findings = append(findings, newFinding(object, problem.severity, "runtime-field", "%v: %v", name.String(), problem.message))
+		}
+		return true
+	})
+
+	return findings
+}
+
+type painlessProblem struct {
+	severity string
+	message  string
+}
+
+func checkPainless(source string, runtime bool) []painlessProblem {
+	var problems []painlessProblem
+	if strings.TrimSpace(source) == "" {
+		return []painlessProblem{{severityError, "empty script"}}
+	}
+	if message := painlessSyntax(source); message != "" {
+		problems = append(problems, painlessProblem{severityError, message})
+	}
+	for _, d := range painlessDeprecations {
+		if d.re.MatchString(source) {
+			problems = append(problems, painlessProblem{severityWarning, d.message})
+		}
+	}
+	if docValueAccess.MatchString(source) && !docSizeCheck.MatchString(source) {
+		problems = append(problems, painlessProblem{severityWarning, "doc values are read without checking size(), documents missing the field will fail"})
+	}
+	if runtime && !strings.Contains(source, "emit(") {
+		problems = append(problems, painlessProblem{severityError, "runtime field script never calls emit()"})
+	}
+	return problems
+}
+
+// painlessSyntax catches the obvious syntax errors: unbalanced brackets,
+// unterminated strings and comments. It returns an empty string when none is found.
+func painlessSyntax(source string) string {
+	pairs := map[rune]rune{')': '(', ']': '[', '}': '{'}
+	var stack []rune
+	runes := []rune(source)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			end := strings.Index(string(runes[i+2:]), "*/")
+			if end < 0 {
+				return "unterminated comment"
+			}
+			i += 2 + len([]rune(string(runes[i+2:])[:end])) + 1
+		case r == '"' || r == '\'':
+			j := i + 1
+			for ; j < len(runes) && runes[j] != r && runes[j] != '\n'; j++ {
+				if runes[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(runes) || runes[j] != r {
+				return "unterminated string literal"
+			}
+			i = j
+		case r == '(' || r == '[' || r == '{':
+			stack = append(stack, r)
+		case r == ')' || r == ']' || r == '}':
+			if len(stack) == 0 || stack[len(stack)-1] != pairs[r] {
+				return "unbalanced " + string(r)
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if len(stack) > 0 {
+		return "unclosed " + string(stack[len(stack)-1])
+	}
+	return ""
+}
@@ -0,0 +1,86 @@
+package kibctl
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+// patchObject fetches a saved object, applies the merge or json patch to it
+// and updates its attributes and references. Unless forced, the update fails
+// when the object is modified concurrently.
+func (c *client) patchObject(objectType, id, patchType string, patch interface{}, force bool) error {
+	current, err := c.getObject(objectType, id)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return errors.Errorf("no %v found with id: %v.\n", objectType, id)
+	}
+	document, err := decodeJSON(string(current))
+	if err != nil {
+		return errors.Wrapf(err, "could not parse %v %v", objectType, id)
+	}
+
+	switch patchType {
+	case "merge":
+		document = mergePatch(document, patch)
+	case "json":
+		document, err = applyJSONPatch(document, patch)
+		if err != nil {
+			return err
+		}
+	default:
+		return errors.Errorf("unsupported patch type %v.\n", patchType)
+	}
+
+	patched, err := encodeJSON(document)
+	if err != nil {
+		return err
+	}
+	version := gjson.GetBytes(current, "version").String()
+	if force {
+		version = ""
+	}
+	c.Logger.Printf("updating %v %v\n", objectType, id)
+	return c.updateObject(objectType, id, version, gjson.Get(patched, "attributes").Raw, gjson.Get(patched, "references").Raw)
+}
+
+func patchDashboard(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	name := c.Args().First()
+	if name == "" {
+		return cli.NewExitError("dashboard name missing", 1)
+	}
+	if c.String("patch") == "" {
+		return cli.NewExitError("patch file missing", 1)
+	}
+	patchType := c.String("type")
+	if patchType != "merge" && patchType != "json" {
+		return cli.NewExitError(fmt.Sprintf("unsupported patch type %v", patchType), 1)
+	}
+	content, err := ioutil.ReadFile(c.String("patch"))
+	if err != nil {
+		return cli.NewExitError(errors.Wrap(err, "could not read patch file"), 1)
+	}
+	var patch interface{}
+	if patch, err = decodeJSON(string(content)); err != nil {
+		return cli.NewExitError(errors.Wrap(err, "could not parse patch file"), 1)
+	}
+
+	client := newClient()
+	dashboard, err := client.findDashboard(name)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if err := client.patchObject("dashboard", dashboard.ID, patchType, patch, c.Bool("force")); err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	printIDs(dashboard.ID)
+	return nil
+}
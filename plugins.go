@@ -0,0 +1,103 @@
+package kibctl
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+const pluginPrefix = "kibctl-"
+
+// pluginEnv passes the connection settings resolved from the flags, the
+// environment and the context to the plugins.
+func pluginEnv() []string {
+	return append(os.Environ(),
+		"KIBANA_HOST="+host,
+		"KIBANA_USERNAME="+username,
+		"KIBANA_PASSWORD="+password,
+		"KIBANA_API_KEY="+apiKey,
+		"KIBCTL_AUTH="+auth,
+		"AWS_REGION="+awsRegion,
+		"KIBANA_COMPAT="+compat,
+		"KIBANA_FLAVOR="+flavor,
+		"KIBANA_TENANT="+tenant,
+		"KIBANA_SPACE="+space,
+		"KIBCTL_CONTEXT="+contextName,
+		"KIBCTL_OUTPUT="+output,
+		fmt.Sprintf("KIBCTL_VERBOSE=%v", verbose),
+		fmt.Sprintf("KIBCTL_QUIET=%v", quiet),
+		fmt.Sprintf("KIBCTL_NO_INPUT=%v", noInput),
+		fmt.Sprintf("KIBCTL_READ_ONLY=%v", readOnly),
+	)
+}
+
+// runPlugin runs the kibctl-NAME executable found on the PATH for the unknown
+// command NAME, with the remaining arguments.
+func runPlugin(c *cli.Context) error {
+	name := c.Args().First()
+	path, err := exec.LookPath(pluginPrefix + name)
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("unknown command %v, and no %v%v plugin found on the PATH", name, pluginPrefix, name), 1)
+	}
+	cmd := exec.Command(path, c.Args().Tail()...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = pluginEnv()
+	if err := cmd.Run(); err != nil {
+		if exit, ok := err.(*exec.ExitError); ok {
+			return cli.NewExitError("", exit.ExitCode())
+		}
+		return cli.NewExitError(err, 2)
+	}
+	return nil
+}
+
+// plugins returns the plugin executables of the PATH by command name, the
+// first one found winning as it is the one run.
+func plugins() map[string]string {
+	found := make(map[string]string)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			name := file.Name()
+			if !strings.HasPrefix(name, pluginPrefix) || file.IsDir() || file.Mode()&0111 == 0 {
+				continue
+			}
+			command := strings.TrimPrefix(name, pluginPrefix)
+			if _, ok := found[command]; !ok {
+				found[command] = filepath.Join(dir, name)
+			}
+		}
+	}
+	return found
+}
+
+func pluginList(c *cli.Context) error {
+	found := plugins()
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if quiet {
+		for _, name := range names {
+			os.Stdout.WriteString(name + "\n")
+		}
+		return nil
+	}
+	os.Stdout.WriteString(stdout.header(fmt.Sprintf("%-20v %v", "COMMAND", "PATH")) + "\n")
+	for _, name := range names {
+		fmt.Fprintf(os.Stdout, "%v %v\n", stdout.paint(cyan, fmt.Sprintf("%-20v", name)), found[name])
+	}
+	return nil
+}
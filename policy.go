@@ -0,0 +1,143 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+// namingPolicy holds the organisation conventions the titles and tags of the
+// objects are checked against.
+type namingPolicy struct {
+	// Titles are the regular expressions the titles must match, by type
+	Titles map[string]string `json:"titles"`
+	// Prefixes are the prefixes one of which must start the titles, e.g. team names
+	Prefixes []string `json:"prefixes"`
+	// Tags are the names of the tags the objects must all carry
+	Tags           []string `json:"tags"`
+	MaxTitleLength int      `json:"maxTitleLength"`
+	// Types are the object types the prefixes, tags and length apply to, the
+	// dashboards, visualizations, lens and searches when empty
+	Types []string `json:"types"`
+}
+
+var policyTypes = []string{"dashboard", "visualization", "lens", "search"}
+
+func (p *namingPolicy) empty() bool {
+	return len(p.Titles) == 0 && len(p.Prefixes) == 0 && len(p.Tags) == 0 && p.MaxTitleLength == 0
+}
+
+// loadPolicy reads the naming policy of the file, or else the one of the
+// config file, nil when none is defined.
+func loadPolicy(file string) (*namingPolicy, error) {
+	if file == "" {
+		conf, err := loadConfig()
+		if err != nil {
+			return nil, err
+		}
+		if conf.Policy == nil || conf.Policy.empty() {
+			return nil, nil
+		}
+		return conf.Policy, nil
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read policy file")
+	}
+	var policy namingPolicy
+	if err := json.Unmarshal(content, &policy); err != nil {
+		return nil, errors.Wrapf(err, "could not parse policy file %v", file)
+	}
+	return &policy, nil
+}
+
+// policyCheck returns the lint check of the policy, the tags of the objects
+// being named after the tag objects of the export when it includes them.
+func policyCheck(policy *namingPolicy, export []byte) (lintCheck, error) {
+	patterns := make(map[string]*regexp.Regexp)
+	for objectType, expr := range policy.Titles {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid title pattern for %v", objectType)
+		}
+		patterns[objectType] = re
+	}
+	types := policy.Types
+	if len(types) == 0 {
+		types = policyTypes
+	}
+	tagNames := make(map[string]string)
+	for _, object := range gjson.GetBytes(export, `objects.#(type=="tag")#`).Array() {
+		tagNames[object.Get("id").String()] = object.Get("attributes.name").String()
+	}
+
+	return func(object gjson.Result) []lintFinding {
+		var findings []lintFinding
+		objectType := object.Get("type").String()
+		title := object.Get("attributes.title").String()
+		if re, ok := patterns[objectType]; ok && !re.MatchString(title) {
+			findings = append(findings, newFinding(object, severityError, "policy-title", "title does not match %v", re))
+		}
+		if !contains(types, objectType) {
+			return findings
+		}
+		if policy.MaxTitleLength > 0 && len([]rune(title)) > policy.MaxTitleLength {
+			findings = append(findings, newFinding(object, severityError, "policy-length", "title longer than %d characters", policy.MaxTitleLength))
+		}
+		if len(policy.Prefixes) > 0 {
+			prefixed := false
+			for _, prefix := range policy.Prefixes {
+				prefixed = prefixed || strings.HasPrefix(title, prefix)
+			}
+			if !prefixed {
+				findings = append(findings, newFinding(object, severityError, "policy-prefix", "title starts with none of %v", strings.Join(policy.Prefixes, ", ")))
+			}
+		}
+		var tags []string
+		for _, ref := range object.Get(`references.#(type=="tag")#`).Array() {
+			id := ref.Get("id").String()
+			if name, ok := tagNames[id]; ok {
+				tags = append(tags, name)
+			}
+			tags = append(tags, id)
+		}
+		for _, tag := range policy.Tags {
+			if !contains(tags, tag) {
+				findings = append(findings, newFinding(object, severityError, "policy-tag", "required tag %v missing", tag))
+			}
+		}
+		return findings
+	}, nil
+}
+
+// enforcePolicy rejects the import of a payload whose objects violate the
+// naming policy.
+func enforcePolicy(c *cli.Context, payload []byte) error {
+	policy, err := loadPolicy(c.String("policy"))
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	if policy == nil {
+		return cli.NewExitError("--enforce-policy requires a policy, given with --policy or in the config file", 1)
+	}
+	check, err := policyCheck(policy, payload)
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	var violations []string
+	for _, object := range gjson.GetBytes(payload, "objects").Array() {
+		for _, f := range check(object) {
+			violations = append(violations, fmt.Sprintf("  %v %v (%v) [%v] %v", f.Type, f.ID, f.Title, f.Check, f.Message))
+		}
+	}
+	if len(violations) > 0 {
+		return cli.NewExitError("naming policy violated, nothing imported:\n"+strings.Join(violations, "\n"), 3)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package kibctl
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"github.com/urfave/cli"
+)
+
+// findTag returns the id of the tag with the exact name.
+func (c *client) findTag(name string) (string, error) {
+	tags, err := c.find([]string{"tag"}, nil)
+	if err != nil {
+		return "", err
+	}
+	for _, tag := range tags {
+		if gjson.GetBytes(tag.Attributes, "name").String() == name {
+			return tag.ID, nil
+		}
+	}
+	return "", errors.Errorf("no tag found named: %v.\n", name)
+}
+
+// exportTagged exports the tag and every object carrying it, along with the
+// dependencies of the tagged dashboards.
+func (c *client) exportTagged(name string) ([]byte, error) {
+	tagID, err := c.findTag(name)
+	if err != nil {
+		return nil, err
+	}
+	tagged, err := c.findReferencing(savedObjectTypes, "tag", tagID)
+	if err != nil {
+		return nil, err
+	}
+
+	var dashboards []string
+	for _, o := range tagged {
+		if o.Type == "dashboard" {
+			dashboards = append(dashboards, o.ID)
+		}
+	}
+	export := []byte(`{"objects":[]}`)
+	if len(dashboards) > 0 {
+		export, err = c.exportDashboards(dashboards...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	included := make(map[string]struct{})
+	for _, object := range gjson.GetBytes(export, "objects").Array() {
+		included[object.Get("type").String()+":"+object.Get("id").String()] = struct{}{}
+	}
+	tag, err := c.getObject("tag", tagID)
+	if err != nil {
+		return nil, err
+	}
+	if tag == nil {
+		// deleted since it was looked up
+		return nil, errors.Errorf("no tag found named: %v.\n", name)
+	}
+	objects := [][]byte{tag}
+	for _, o := range tagged {
+		if _, ok := included[o.key()]; ok {
+			continue
+		}
+		objects = append(objects, []byte(o.json().Raw))
+	}
+	for _, object := range objects {
+		export, err = sjson.SetRawBytes(export, "objects.-1", object)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// versions are specific to the source environment
+	for i := range gjson.GetBytes(export, "objects").Array() {
+		export, err = sjson.DeleteBytes(export, fmt.Sprintf("objects.%d.version", i))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return export, nil
+}
+
+func promote(c *cli.Context) error {
+	tag, from, to := c.String("tag"), c.String("from"), c.String("to")
+	if tag == "" {
+		return cli.NewExitError("tag missing", 1)
+	}
+	if from == "" || to == "" {
+		return cli.NewExitError("--from and --to contexts are required", 1)
+	}
+	conf, err := loadConfig()
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	source, err := contextClient(conf, from)
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	target, err := contextClient(conf, to)
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	source.Logger.Printf("exporting objects tagged %v from %v\n", tag, from)
+	export, err := source.exportTagged(tag)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	ids := payloadIDs(export)
+	source.Logger.Printf("importing %v objects into %v\n", len(ids), to)
+	emitObjects("promote", "started", export, nil)
+	err = target._import(export)
+	emitObjects("promote", "succeeded", export, err)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+
+	var objects []string
+	for _, object := range gjson.GetBytes(export, "objects").Array() {
+		objects = append(objects, object.Get("type").String()+" "+object.Get("id").String())
+	}
+	err = conf.recordAudit(auditRecord{Operation: "promote", From: from, To: to, Tag: tag, Objects: objects})
+	if err != nil {
+		return cli.NewExitError(errors.Wrap(err, "promotion applied but not recorded"), 2)
+	}
+
+	if quiet || jsonl() {
+		printIDs(ids...)
+		return nil
+	}
+	fmt.Fprintf(os.Stdout, "promoted %v objects tagged %v from %v to %v\n", len(ids), tag, from, to)
+	return nil
+}
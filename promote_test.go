@@ -0,0 +1,50 @@
+package kibctl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestExportTagged(t *testing.T) {
+	tests := []struct {
+		name    string
+		tag     string
+		wantErr bool
+	}{
+		{"tag found", `{"type":"tag","id":"t1","version":"WzEsMV0=","attributes":{"name":"release"},"references":[]}`, false},
+		{"tag deleted", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/api/saved_objects/_find" && r.URL.Query().Get("has_reference") != "":
+					w.Write([]byte(`{"total":0,"saved_objects":[]}`))
+				case r.URL.Path == "/api/saved_objects/_find":
+					w.Write([]byte(`{"total":1,"saved_objects":[{"type":"tag","id":"t1","attributes":{"name":"release"}}]}`))
+				case r.URL.Path == "/api/saved_objects/tag/t1" && test.tag != "":
+					w.Write([]byte(test.tag))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+					w.Write([]byte(`{"statusCode":404,"error":"Not Found"}`))
+				}
+			}))
+			defer server.Close()
+
+			export, err := newTestClient(server).exportTagged("release")
+			if (err != nil) != test.wantErr {
+				t.Fatalf("exportTagged() error = %v, want error %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			objects := gjson.GetBytes(export, "objects").Array()
+			if len(objects) != 1 || objects[0].Get("id").String() != "t1" || objects[0].Get("version").Exists() {
+				t.Errorf("exportTagged() = %s, want the tag without its version", export)
+			}
+		})
+	}
+}
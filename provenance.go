@@ -0,0 +1,83 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// kibctlVersion is set at build time with -ldflags "-X github.com/lebaptiste/kibctl.kibctlVersion=v1.2.3".
+var kibctlVersion = "dev"
+
+// provenanceAttribute is the attribute the provenance is stored in with
+// --provenance attribute.
+const provenanceAttribute = "kibctlProvenance"
+
+// provenanceFile is the sidecar file of the split exports holding their
+// provenance.
+const provenanceFile = "provenance.json"
+
+// provenance records where and when an export was taken from.
+type provenance struct {
+	Host          string    `json:"host"`
+	Space         string    `json:"space"`
+	KibctlVersion string    `json:"kibctlVersion"`
+	ExportedAt    time.Time `json:"exportedAt"`
+	// ContentHash is the sha256 of the canonical json of the exported objects,
+	// before the provenance is added to them
+	ContentHash string `json:"contentHash"`
+}
+
+// exportProvenance returns the provenance of the export taken from the host
+// and space, the credentials of the host url being left out.
+func exportProvenance(export []byte, host, space string) provenance {
+	return provenance{
+		Host:          publicHost(host),
+		Space:         spaceID(space),
+		KibctlVersion: kibctlVersion,
+		ExportedAt:    time.Now().UTC().Truncate(time.Second),
+		ContentHash:   contentHash(json.RawMessage(gjson.GetBytes(export, "objects").Raw)),
+	}
+}
+
+// publicHost returns the host url without the credentials it may embed.
+func publicHost(host string) string {
+	if u, err := url.Parse(host); err == nil {
+		u.User = nil
+		return u.String()
+	}
+	return host
+}
+
+func spaceID(space string) string {
+	if space == "" {
+		return "default"
+	}
+	return space
+}
+
+// addProvenance stores the provenance in a dedicated attribute of every object
+// of the export.
+func addProvenance(export []byte, p provenance) ([]byte, error) {
+	var err error
+	for i := range gjson.GetBytes(export, "objects").Array() {
+		export, err = sjson.SetBytes(export, fmt.Sprintf("objects.%d.attributes.%v", i, provenanceAttribute), p)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not add provenance")
+		}
+	}
+	return export, nil
+}
+
+func writeProvenance(dir string, p provenance) error {
+	content, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeBundleFile(dir, provenanceFile, append(content, '\n'))
+}
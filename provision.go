@@ -0,0 +1,155 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+	"sigs.k8s.io/yaml"
+)
+
+// spaceTemplate is the standard setup of a space: its features, advanced
+// settings, tags and the dashboards bundle imported into it.
+type spaceTemplate struct {
+	Description      string   `json:"description"`
+	Color            string   `json:"color"`
+	Initials         string   `json:"initials"`
+	DisabledFeatures []string `json:"disabledFeatures"`
+	// Settings are the advanced settings of the space, e.g. {"dateFormat:tz": "UTC"}
+	Settings map[string]interface{} `json:"settings"`
+	Tags     []string               `json:"tags"`
+	// Bundle is the export file or split directory imported into the space,
+	// relative to the template file
+	Bundle string `json:"bundle"`
+	// Values render the {{ .name }} placeholders of the bundle, along with
+	// the space and name values of the provisioned space
+	Values map[string]interface{} `json:"values"`
+}
+
+func loadSpaceTemplate(file string) (*spaceTemplate, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read space template")
+	}
+	if ext := filepath.Ext(file); ext == ".yaml" || ext == ".yml" {
+		// converted to json, the template fields keeping their json tags
+		if content, err = yaml.YAMLToJSON(content); err != nil {
+			return nil, errors.Wrapf(err, "could not parse space template %v", file)
+		}
+	}
+	var template spaceTemplate
+	if err := json.Unmarshal(content, &template); err != nil {
+		return nil, errors.Wrap(err, "could not parse space template")
+	}
+	if template.Bundle != "" && !filepath.IsAbs(template.Bundle) {
+		template.Bundle = filepath.Join(filepath.Dir(file), template.Bundle)
+	}
+	return &template, nil
+}
+
+// updateSettings changes the advanced settings of the client space.
+func (c *client) updateSettings(changes map[string]interface{}) error {
+	path := "/api/kibana/settings"
+	if c.Flavor == "opensearch" {
+		path = "/api/opensearch-dashboards/settings"
+	}
+	_, err := c.jsonRequest("POST", path, map[string]interface{}{"changes": changes})
+	return err
+}
+
+// provisionSpace creates the space and sets it up after the template. The
+// space is left as far as it got when a step fails.
+func (c *client) provisionSpace(id, name string, template *spaceTemplate) error {
+	if err := c.createSpace(kibanaSpace{
+		ID:               id,
+		Name:             name,
+		Description:      template.Description,
+		Color:            template.Color,
+		Initials:         template.Initials,
+		DisabledFeatures: template.DisabledFeatures,
+	}); err != nil {
+		return err
+	}
+	space := c.inSpace(id)
+	if len(template.Settings) > 0 {
+		c.Logger.Printf("setting %d advanced setting(s)\n", len(template.Settings))
+		if err := space.updateSettings(template.Settings); err != nil {
+			return errors.Wrapf(err, "space %v created, could not apply its settings", id)
+		}
+	}
+	if len(template.Tags) > 0 {
+		if _, err := space.ensureTags(template.Tags); err != nil {
+			return errors.Wrapf(err, "space %v created, could not create its tags", id)
+		}
+	}
+	if template.Bundle == "" {
+		return nil
+	}
+	payload, err := readBundle(template.Bundle)
+	if err != nil {
+		return errors.Wrapf(err, "space %v created, could not read its bundle", id)
+	}
+	if template.Values != nil {
+		values := map[string]interface{}{"space": id, "name": name}
+		for key, value := range template.Values {
+			values[key] = value
+		}
+		if payload, err = renderTemplate(payload, values); err != nil {
+			return errors.Wrapf(err, "space %v created, could not render its bundle", id)
+		}
+	}
+	if err := space._import(payload); err != nil {
+		return errors.Wrapf(err, "space %v created, could not import its bundle", id)
+	}
+	return nil
+}
+
+// spaceIDFromName derives a space id from its name, e.g. team-payments from Team
+// Payments.
+func spaceIDFromName(name string) string {
+	id := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return '-'
+	}, strings.TrimSpace(name))
+	return strings.Trim(id, "-")
+}
+
+func spaceProvision(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	file, name := c.String("template"), c.String("name")
+	if file == "" {
+		return cli.NewExitError("--template missing", 1)
+	}
+	if name == "" {
+		return cli.NewExitError("--name missing", 1)
+	}
+	id := c.String("id")
+	if id == "" {
+		id = spaceIDFromName(name)
+	}
+	template, err := loadSpaceTemplate(file)
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	client := newClient()
+	if err := client.provisionSpace(id, name, template); err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "provisioned space %v\n", id)
+	}
+	printIDs(id)
+	return nil
+}
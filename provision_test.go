@@ -0,0 +1,49 @@
+package kibctl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadSpaceTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kibctl-template-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	want := &spaceTemplate{
+		Color:            "#00BFB3",
+		DisabledFeatures: []string{"ml", "canvas"},
+		Settings:         map[string]interface{}{"dateFormat:tz": "UTC"},
+		Tags:             []string{"team"},
+		Bundle:           filepath.Join(dir, "dashboards"),
+		Values:           map[string]interface{}{"env": "prod"},
+	}
+	tests := []struct {
+		file    string
+		content string
+	}{
+		{"space.json", `{"color":"#00BFB3","disabledFeatures":["ml","canvas"],"settings":{"dateFormat:tz":"UTC"},"tags":["team"],"bundle":"dashboards","values":{"env":"prod"}}`},
+		{"space.yaml", "color: '#00BFB3'\ndisabledFeatures: [ml, canvas]\nsettings:\n  dateFormat:tz: UTC\ntags:\n  - team\nbundle: dashboards\nvalues:\n  env: prod\n"},
+		{"json.yml", `{"color":"#00BFB3","disabledFeatures":["ml","canvas"],"settings":{"dateFormat:tz":"UTC"},"tags":["team"],"bundle":"dashboards","values":{"env":"prod"}}`},
+	}
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			file := filepath.Join(dir, test.file)
+			if err := ioutil.WriteFile(file, []byte(test.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadSpaceTemplate(file)
+			if err != nil {
+				t.Fatalf("loadSpaceTemplate() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("loadSpaceTemplate() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package kibctl
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+// hopHeaders are the hop-by-hop headers, which apply to a single connection
+// and are not forwarded.
+var hopHeaders = []string{"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization", "Te", "Trailer", "Transfer-Encoding", "Upgrade"}
+
+// kibanaProxy forwards the requests to kibana, serving the successful GET
+// responses from memory for the ttl. Any successful write drops the cached
+// responses, which it may have made stale, the searches and lookups posted to
+// kibana being no writes.
+type kibanaProxy struct {
+	client *client
+	ttl    time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+func newKibanaProxy(c *client, ttl time.Duration) *kibanaProxy {
+	return &kibanaProxy{client: c, ttl: ttl, entries: make(map[string]cacheEntry)}
+}
+
+// upstreamRequest copies the request for kibana, authenticated with the kibctl
+// credentials unless it carries its own.
+func (p *kibanaProxy) upstreamRequest(r *http.Request) (*http.Request, error) {
+	if err := p.client.checkReadOnly(r.Method, r.URL.RequestURI()); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(r.Method, strings.TrimSuffix(p.client.Host, "/")+r.URL.RequestURI(), r.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = r.ContentLength
+	for name, values := range r.Header {
+		req.Header[name] = values
+	}
+	for _, name := range hopHeaders {
+		req.Header.Del(name)
+	}
+	if req.Header.Get("Authorization") == "" {
+		if err := p.client.authenticate(req); err != nil {
+			return nil, err
+		}
+	}
+	if p.client.Tenant != "" && req.Header.Get("securitytenant") == "" {
+		req.Header.Set("securitytenant", p.client.Tenant)
+	}
+	return req, nil
+}
+
+func (p *kibanaProxy) cached(key string) (cacheEntry, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entry, ok := p.entries[key]
+	if ok && time.Since(entry.Stored) > p.ttl {
+		delete(p.entries, key)
+		return cacheEntry{}, false
+	}
+	return entry, ok
+}
+
+func (p *kibanaProxy) store(key string, entry cacheEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.entries[key] = entry
+}
+
+func (p *kibanaProxy) invalidate() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.entries = make(map[string]cacheEntry)
+}
+
+func writeResponse(w http.ResponseWriter, header http.Header, statusCode int, body io.Reader) {
+	for name, values := range header {
+		w.Header()[name] = values
+	}
+	for _, name := range hopHeaders {
+		w.Header().Del(name)
+	}
+	w.WriteHeader(statusCode)
+	io.Copy(w, body)
+}
+
+func (p *kibanaProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := p.upstreamRequest(r)
+	if _, refused := err.(readOnlyError); refused {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	read := r.Method == "GET" || r.Method == "HEAD"
+	signer, err := p.client.signingIdentity()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	// the encoding negotiated with the caller is part of the cached response
+	key := strings.Join([]string{r.Method, r.Header.Get("Accept-Encoding"), cacheKey(req, signer)}, "\x00")
+	if read {
+		if entry, ok := p.cached(key); ok {
+			p.client.Logger.Printf("hit %v %v\n", r.Method, r.URL)
+			w.Header().Set("X-Kibctl-Cache", "hit")
+			writeResponse(w, entry.Header, entry.StatusCode, bytes.NewReader(entry.Body))
+			return
+		}
+	}
+
+	resp, err := p.client.roundTrip(req)
+	if err != nil {
+		p.client.Logger.Printf("failed %v %v: %v\n", r.Method, r.URL, err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	p.client.Logger.Printf("%d %v %v\n", resp.StatusCode, r.Method, r.URL)
+	if !read {
+		if resp.StatusCode < 400 && !readsOnly(r.Method, r.URL.RequestURI()) {
+			p.invalidate()
+		}
+		writeResponse(w, resp.Header, resp.StatusCode, resp.Body)
+		return
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if resp.StatusCode == http.StatusOK {
+		p.store(key, cacheEntry{Stored: time.Now(), StatusCode: resp.StatusCode, Status: resp.Status, Header: resp.Header, Body: body})
+	}
+	w.Header().Set("X-Kibctl-Cache", "miss")
+	writeResponse(w, resp.Header, resp.StatusCode, bytes.NewReader(body))
+}
+
+// checkLoopback refuses the listen addresses reachable from other hosts, the
+// proxy lending the kibctl credentials to the requests without their own.
+func checkLoopback(listen string) error {
+	hostname, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return errors.Wrapf(err, "invalid --listen %v", listen)
+	}
+	if ip := net.ParseIP(hostname); hostname == "localhost" || ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return errors.Errorf("--listen %v is not a loopback address, the proxy sends the requests without credentials with the kibctl ones; use --allow-remote to listen on it anyway.\n", listen)
+}
+
+func proxy(c *cli.Context) error {
+	if upstream := c.String("upstream"); upstream != "" {
+		host = upstream
+	}
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	ttl := c.Duration("ttl")
+	if ttl <= 0 {
+		return cli.NewExitError("--ttl must be positive", 1)
+	}
+	listen := c.String("listen")
+	if !c.Bool("allow-remote") {
+		if err := checkLoopback(listen); err != nil {
+			return cli.NewExitError(err, 1)
+		}
+	}
+	client := newClient()
+	client.CacheTTL = 0
+	// fetched ahead of the concurrent requests of the proxy
+	if err := client.fetchCredentials(); err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "proxying %v to %v, caching reads for %v\n", listen, client.Host, ttl)
+	}
+	if err := http.ListenAndServe(listen, newKibanaProxy(client, ttl)); err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	return nil
+}
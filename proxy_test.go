@@ -0,0 +1,83 @@
+package kibctl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKibanaProxyCache(t *testing.T) {
+	var mu sync.Mutex
+	reads := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			mu.Lock()
+			reads++
+			mu.Unlock()
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer upstream.Close()
+	p := newKibanaProxy(newTestClient(upstream), time.Minute)
+
+	serve := func(method, path, encoding string) string {
+		r := httptest.NewRequest(method, path, strings.NewReader(`{}`))
+		if encoding != "" {
+			r.Header.Set("Accept-Encoding", encoding)
+		}
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, r)
+		return w.Header().Get("X-Kibctl-Cache")
+	}
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		encoding string
+		want     string
+	}{
+		{"first read", "GET", "/api/saved_objects/_find?type=dashboard", "", "miss"},
+		{"cached read", "GET", "/api/saved_objects/_find?type=dashboard", "", "hit"},
+		{"other encoding", "GET", "/api/saved_objects/_find?type=dashboard", "gzip", "miss"},
+		{"posted lookup", "POST", "/api/saved_objects/_bulk_get", "", ""},
+		{"read after lookup", "GET", "/api/saved_objects/_find?type=dashboard", "", "hit"},
+		{"write", "PUT", "/api/saved_objects/dashboard/d1", "", ""},
+		{"read after write", "GET", "/api/saved_objects/_find?type=dashboard", "", "miss"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := serve(test.method, test.path, test.encoding); got != test.want {
+				t.Errorf("%v %v served with cache %q, want %q", test.method, test.path, got, test.want)
+			}
+		})
+	}
+	if reads != 3 {
+		t.Errorf("kibana read %d times, want 3", reads)
+	}
+}
+
+func TestCheckLoopback(t *testing.T) {
+	tests := []struct {
+		listen  string
+		wantErr bool
+	}{
+		{"127.0.0.1:5601", false},
+		{"localhost:5601", false},
+		{"[::1]:5601", false},
+		{":5601", true},
+		{"0.0.0.0:5601", true},
+		{"10.0.0.3:5601", true},
+		{"kibana.internal:5601", true},
+		{"5601", true},
+	}
+	for _, test := range tests {
+		t.Run(test.listen, func(t *testing.T) {
+			if err := checkLoopback(test.listen); (err != nil) != test.wantErr {
+				t.Errorf("checkLoopback(%v) error = %v, want error %v", test.listen, err, test.wantErr)
+			}
+		})
+	}
+}
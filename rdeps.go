@@ -0,0 +1,103 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+func (o savedObject) key() string {
+	return o.Type + ":" + o.ID
+}
+
+// json returns the object as parsed json, as expected by the dependency scanner.
+func (o savedObject) json() gjson.Result {
+	raw, _ := json.Marshal(o)
+	return gjson.ParseBytes(raw)
+}
+
+type reverseDependency struct {
+	savedObject
+	// Via is the key of the object through which the dependency exists, empty when direct
+	Via string
+}
+
+// reverseDependencies walks the objects referring, directly or transitively,
+// to the target object, closest ones first.
+func reverseDependencies(objects []savedObject, targetType, targetID string) []reverseDependency {
+	byKey := make(map[string]savedObject)
+	dependents := make(map[string][]string)
+	for _, o := range objects {
+		byKey[o.key()] = o
+	}
+	for _, o := range objects {
+		for _, dep := range objectDependencies(o.json()) {
+			key := dep.Type + ":" + dep.ID
+			if dep.ID == "" {
+				for _, candidate := range objects {
+					if candidate.Type == dep.Type && candidate.title() == dep.Title {
+						key = candidate.key()
+						break
+					}
+				}
+			}
+			dependents[key] = append(dependents[key], o.key())
+		}
+	}
+
+	var result []reverseDependency
+	target := targetType + ":" + targetID
+	visited := map[string]struct{}{target: {}}
+	queue := []string{target}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, key := range dependents[current] {
+			if _, ok := visited[key]; ok {
+				continue
+			}
+			visited[key] = struct{}{}
+			via := current
+			if current == target {
+				via = ""
+			}
+			result = append(result, reverseDependency{savedObject: byKey[key], Via: via})
+			queue = append(queue, key)
+		}
+	}
+	return result
+}
+
+func rdeps(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	objectType, id := c.Args().Get(0), c.Args().Get(1)
+	if objectType == "" || id == "" {
+		return cli.NewExitError("object type and id missing", 1)
+	}
+	objects, err := newClient().findObjects(savedObjectTypes, "")
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+
+	dependencies := reverseDependencies(objects, objectType, id)
+	if quiet {
+		for _, dep := range dependencies {
+			printIDs(dep.ID)
+		}
+		return nil
+	}
+	os.Stdout.WriteString(stdout.header(fmt.Sprintf("%-15v %-40v %-40v %v", "TYPE", "ID", "TITLE", "VIA")) + "\n")
+	for _, dep := range dependencies {
+		via := dep.Via
+		if via == "" {
+			via = "-"
+		}
+		os.Stdout.WriteString(fmt.Sprintf("%-15v %v %-40v %v\n", dep.Type, stdout.paint(cyan, fmt.Sprintf("%-40v", dep.ID)), dep.title(), via))
+	}
+	return nil
+}
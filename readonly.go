@@ -0,0 +1,79 @@
+package kibctl
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// exitReadOnly is the exit code of the commands refused a write by
+// --read-only.
+const exitReadOnly = 5
+
+var readOnly bool
+
+// readOnlyRefused records that a request was refused, for the command to
+// exit with exitReadOnly whatever the error it fails with.
+var readOnlyRefused bool
+
+// readOnlyPosts are the apis only reading although posted to.
+var readOnlyPosts = []string{
+	"/api/saved_objects/_bulk_get",
+	"/api/saved_objects/_bulk_resolve",
+	"/api/core/capabilities",
+	"/internal/search/",
+}
+
+// readOnlyError is a request refused by --read-only.
+type readOnlyError struct {
+	Method string
+	Path   string
+}
+
+func (e readOnlyError) Error() string {
+	return fmt.Sprintf("%v %v refused, --read-only only allows reading.\n", e.Method, e.Path)
+}
+
+// checkReadOnly refuses the requests which may write when the client is
+// read-only, see readsOnly.
+func (c *client) checkReadOnly(method, path string) error {
+	if !c.ReadOnly || readsOnly(method, path) {
+		return nil
+	}
+	readOnlyRefused = true
+	return readOnlyError{Method: method, Path: spacePath(path)}
+}
+
+// readsOnly reports whether the request only reads: GET and HEAD, as well as
+// the searches and lookups posted to kibana and the reading console proxy
+// requests.
+func readsOnly(method, path string) bool {
+	if method == "GET" || method == "HEAD" {
+		return true
+	}
+	if method != "POST" {
+		return false
+	}
+	path = spacePath(path)
+	for _, prefix := range readOnlyPosts {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	if u, err := url.Parse(path); err == nil && u.Path == "/api/console/proxy" {
+		if m := strings.ToUpper(u.Query().Get("method")); m == "GET" || m == "HEAD" {
+			return true
+		}
+	}
+	return false
+}
+
+// spacePath returns the path without its /s/SPACE prefix.
+func spacePath(path string) string {
+	if strings.HasPrefix(path, "/s/") {
+		if i := strings.Index(path[len("/s/"):], "/"); i >= 0 {
+			return path[len("/s/")+i:]
+		}
+	}
+	return path
+}
@@ -0,0 +1,80 @@
+package kibctl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCheckReadOnly(t *testing.T) {
+	defer func() { readOnlyRefused = false }()
+	tests := []struct {
+		name     string
+		readOnly bool
+		method   string
+		path     string
+		refused  bool
+	}{
+		{"writable", false, "DELETE", "/api/saved_objects/dashboard/d1", false},
+		{"get", true, "GET", "/api/saved_objects/dashboard/d1", false},
+		{"head", true, "HEAD", "/api/status", false},
+		{"put", true, "PUT", "/api/saved_objects/dashboard/d1", true},
+		{"delete", true, "DELETE", "/api/saved_objects/dashboard/d1", true},
+		{"import", true, "POST", "/api/saved_objects/_import?overwrite=true", true},
+		{"bulk get", true, "POST", "/api/saved_objects/_bulk_get", false},
+		{"bulk get in a space", true, "POST", "/s/ops/api/saved_objects/_bulk_get", false},
+		{"import in a space", true, "POST", "/s/ops/api/saved_objects/_import", true},
+		{"search", true, "POST", "/internal/search/es", false},
+		{"console search", true, "POST", "/api/console/proxy?path=logs-*/_search&method=get", false},
+		{"console delete", true, "POST", "/api/console/proxy?path=logs-*&method=DELETE", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			readOnlyRefused = false
+			c := &client{ReadOnly: test.readOnly}
+			err := c.checkReadOnly(test.method, test.path)
+			if _, ok := err.(readOnlyError); ok != test.refused || (err != nil && !ok) {
+				t.Errorf("checkReadOnly(%v %v) = %v, want refused %v", test.method, test.path, err, test.refused)
+			}
+			if readOnlyRefused != test.refused {
+				t.Errorf("readOnlyRefused = %v, want %v", readOnlyRefused, test.refused)
+			}
+		})
+	}
+}
+
+func TestReadOnlyClientSendsNoWrites(t *testing.T) {
+	defer func() { readOnlyRefused = false }()
+	var mu sync.Mutex
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		sent = append(sent, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		w.Write([]byte(`{"id":"d1","type":"dashboard","attributes":{}}`))
+	}))
+	defer server.Close()
+	c := newTestClient(server)
+	c.ReadOnly = true
+
+	if _, err := c.getObject("dashboard", "d1"); err != nil {
+		t.Errorf("getObject() error: %v", err)
+	}
+	if err := c._import([]byte(`{"objects":[{"id":"d1","type":"dashboard","attributes":{}}]}`)); err == nil {
+		t.Error("_import() sent with --read-only")
+	}
+	if err := c.deleteObject("dashboard", "d1"); err == nil {
+		t.Error("deleteObject() sent with --read-only")
+	}
+	w := httptest.NewRecorder()
+	newKibanaProxy(c, time.Minute).ServeHTTP(w, httptest.NewRequest("PUT", "/api/saved_objects/dashboard/d1", strings.NewReader(`{}`)))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("proxied PUT answered %v, want %v", w.Code, http.StatusForbidden)
+	}
+	if want := []string{"GET /api/saved_objects/dashboard/d1"}; strings.Join(sent, ",") != strings.Join(want, ",") {
+		t.Errorf("sent %v, want only %v", sent, want)
+	}
+}
@@ -0,0 +1,335 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"github.com/urfave/cli"
+)
+
+const severityFixed = "fixed"
+
+// jsonAttributes are the attributes kibana stores as json strings.
+var jsonAttributes = []string{
+	"attributes.visState",
+	"attributes.uiStateJSON",
+	"attributes.panelsJSON",
+	"attributes.optionsJSON",
+	"attributes.kibanaSavedObjectMeta.searchSourceJSON",
+}
+
+// searchSourceTypes are the types of the objects kibana expects a search
+// source from, even an empty one.
+var searchSourceTypes = []string{"dashboard", "visualization", "search"}
+
+// repairFunc fixes a known corruption of the object, returning the object
+// along with the fixes made.
+type repairFunc func(object gjson.Result) (gjson.Result, []lintFinding, error)
+
+// repairJSONAttributes unwraps the json attributes encoded more than once,
+// and encodes the ones stored as json documents instead of strings.
+func repairJSONAttributes(object gjson.Result) (gjson.Result, []lintFinding, error) {
+	var fixes []lintFinding
+	raw := object.Raw
+	for _, path := range jsonAttributes {
+		value := gjson.Get(raw, path)
+		var fixed string
+		switch {
+		case value.IsObject() || value.IsArray():
+			fixed = value.Raw
+			fixes = append(fixes, newFinding(object, severityFixed, "json-attribute", "%v stored as json instead of a string", path))
+		case value.Type == gjson.String:
+			decoded, escapes := value.String(), 0
+			for gjson.Valid(decoded) && gjson.Parse(decoded).Type == gjson.String {
+				next := gjson.Parse(decoded).String()
+				if !gjson.Valid(next) {
+					break
+				}
+				decoded = next
+				escapes++
+			}
+			if escapes == 0 {
+				continue
+			}
+			fixed = decoded
+			fixes = append(fixes, newFinding(object, severityFixed, "json-attribute", "%v escaped %d time(s) too many", path, escapes))
+		default:
+			continue
+		}
+		var err error
+		if raw, err = sjson.Set(raw, path, fixed); err != nil {
+			return object, nil, err
+		}
+	}
+	return gjson.Parse(raw), fixes, nil
+}
+
+// repairSearchSource adds the empty search source kibana fails to render the
+// object without, pointing at the data view the object refers to.
+func repairSearchSource(object gjson.Result) (gjson.Result, []lintFinding, error) {
+	if !contains(searchSourceTypes, object.Get("type").String()) || object.Get("attributes.kibanaSavedObjectMeta.searchSourceJSON").Exists() {
+		return object, nil, nil
+	}
+	source := map[string]interface{}{
+		"query":  map[string]string{"query": "", "language": "kuery"},
+		"filter": []interface{}{},
+	}
+	const indexRefName = "kibanaSavedObjectMeta.searchSourceJSON.index"
+	if object.Get(fmt.Sprintf(`references.#(name==%q)`, indexRefName)).Exists() {
+		source["indexRefName"] = indexRefName
+	}
+	encoded, err := json.Marshal(source)
+	if err != nil {
+		return object, nil, err
+	}
+	raw, err := sjson.Set(object.Raw, "attributes.kibanaSavedObjectMeta", map[string]string{"searchSourceJSON": string(encoded)})
+	if err != nil {
+		return object, nil, err
+	}
+	return gjson.Parse(raw), []lintFinding{newFinding(object, severityFixed, "search-source", "missing kibanaSavedObjectMeta added")}, nil
+}
+
+// repairPanelIndices gives every panel of the dashboard a unique panel index,
+// matched by the index of its grid data.
+func repairPanelIndices(object gjson.Result) (gjson.Result, []lintFinding, error) {
+	panelsJSON := object.Get("attributes.panelsJSON")
+	if object.Get("type").String() != "dashboard" || panelsJSON.Type != gjson.String {
+		return object, nil, nil
+	}
+	decoder := json.NewDecoder(strings.NewReader(panelsJSON.String()))
+	decoder.UseNumber()
+	var panels []map[string]interface{}
+	if err := decoder.Decode(&panels); err != nil {
+		return object, []lintFinding{newFinding(object, severityWarning, "panel-index", "panelsJSON is not a list of panels: %v", err)}, nil
+	}
+
+	index := func(value interface{}) string {
+		if value == nil {
+			return ""
+		}
+		return fmt.Sprint(value)
+	}
+	used := make(map[string]bool)
+	for _, panel := range panels {
+		used[index(panel["panelIndex"])] = true
+		if grid, ok := panel["gridData"].(map[string]interface{}); ok {
+			used[index(grid["i"])] = true
+		}
+	}
+	next := len(panels)
+	var fixes []lintFinding
+	seen := make(map[string]bool)
+	for i, panel := range panels {
+		grid, _ := panel["gridData"].(map[string]interface{})
+		wanted := index(panel["panelIndex"])
+		if wanted == "" || seen[wanted] {
+			if grid != nil && index(grid["i"]) != "" && !seen[index(grid["i"])] {
+				wanted = index(grid["i"])
+			} else {
+				for wanted = ""; wanted == "" || used[wanted]; next++ {
+					wanted = fmt.Sprint(next + 1)
+				}
+				used[wanted] = true
+			}
+			fixes = append(fixes, newFinding(object, severityFixed, "panel-index", "panel %d given the unique panel index %v", i, wanted))
+			panel["panelIndex"] = wanted
+		}
+		seen[wanted] = true
+		if grid != nil && index(grid["i"]) != wanted {
+			fixes = append(fixes, newFinding(object, severityFixed, "panel-index", "grid data of panel %v out of sync with its panel index", wanted))
+			grid["i"] = wanted
+		}
+		if name, ok := panel["panelRefName"].(string); ok && !object.Get(fmt.Sprintf(`references.#(name==%q)`, name)).Exists() {
+			fixes = append(fixes, newFinding(object, severityWarning, "panel-index", "panel %v refers to the missing reference %v, remove the panel", wanted, name))
+		}
+	}
+	if len(fixes) == 0 {
+		return object, nil, nil
+	}
+	encoded, err := encodeJSON(panels)
+	if err != nil {
+		return object, nil, err
+	}
+	raw, err := sjson.Set(object.Raw, "attributes.panelsJSON", strings.TrimSpace(encoded))
+	if err != nil {
+		return object, nil, err
+	}
+	return gjson.Parse(raw), fixes, nil
+}
+
+// repairReferences returns the repair pointing the references at the new id
+// of the objects whose id changed, by "type:id" of their old id.
+func repairReferences(aliases map[string]string) repairFunc {
+	return func(object gjson.Result) (gjson.Result, []lintFinding, error) {
+		raw := object.Raw
+		var fixes []lintFinding
+		for i, ref := range object.Get("references").Array() {
+			key := ref.Get("type").String() + ":" + ref.Get("id").String()
+			target, ok := aliases[key]
+			if !ok {
+				continue
+			}
+			var err error
+			if raw, err = sjson.Set(raw, fmt.Sprintf("references.%d.id", i), target); err != nil {
+				return object, nil, err
+			}
+			fixes = append(fixes, newFinding(object, severityFixed, "reference", "reference %v to the old id of %v pointed at %v", ref.Get("name").String(), key, target))
+		}
+		return gjson.Parse(raw), fixes, nil
+	}
+}
+
+// exportAliases maps the old ids of the objects of the export, kept as their
+// origin id when kibana regenerated their id, to their new one.
+func exportAliases(export []byte) map[string]string {
+	aliases := make(map[string]string)
+	for _, object := range gjson.GetBytes(export, "objects").Array() {
+		if origin := object.Get("originId").String(); origin != "" && origin != object.Get("id").String() {
+			aliases[object.Get("type").String()+":"+origin] = object.Get("id").String()
+		}
+	}
+	return aliases
+}
+
+// resolveAliases adds to the aliases the legacy url aliases of the objects
+// referred to by the export but missing from it.
+func (c *client) resolveAliases(export []byte, aliases map[string]string) error {
+	objects := exportObjects(export)
+	seen := make(map[string]bool)
+	var missing []dependency
+	for _, object := range objects {
+		for _, ref := range object.Get("references").Array() {
+			key := ref.Get("type").String() + ":" + ref.Get("id").String()
+			if _, ok := objects[key]; ok || seen[key] || aliases[key] != "" {
+				continue
+			}
+			seen[key] = true
+			missing = append(missing, dependency{Type: ref.Get("type").String(), ID: ref.Get("id").String()})
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i].Type+missing[i].ID < missing[j].Type+missing[j].ID })
+	resolved, err := c.bulkResolve(missing)
+	if err != nil {
+		return err
+	}
+	for i, r := range resolved {
+		if r.Outcome == "aliasMatch" && r.AliasTargetID != "" {
+			aliases[missing[i].Type+":"+missing[i].ID] = r.AliasTargetID
+		}
+	}
+	return nil
+}
+
+// repairExport fixes the known corruptions of the objects of the export, and
+// returns the export along with the fixes and the keys of the objects fixed.
+func repairExport(export []byte, aliases map[string]string) ([]byte, []lintFinding, []string, error) {
+	repairs := []repairFunc{repairJSONAttributes, repairSearchSource, repairPanelIndices, repairReferences(aliases)}
+	var findings []lintFinding
+	var changed []string
+	for i, object := range gjson.GetBytes(export, "objects").Array() {
+		fixed := false
+		for _, repair := range repairs {
+			var fixes []lintFinding
+			var err error
+			if object, fixes, err = repair(object); err != nil {
+				return nil, nil, nil, errors.Wrapf(err, "could not repair %v %v", object.Get("type").String(), object.Get("id").String())
+			}
+			for _, f := range fixes {
+				fixed = fixed || f.Severity == severityFixed
+			}
+			findings = append(findings, fixes...)
+		}
+		if !fixed {
+			continue
+		}
+		changed = append(changed, object.Get("type").String()+":"+object.Get("id").String())
+		var err error
+		if export, err = sjson.SetRawBytes(export, fmt.Sprintf("objects.%d", i), []byte(object.Raw)); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return export, findings, changed, nil
+}
+
+func printRepairs(w io.Writer, findings []lintFinding) {
+	for _, f := range findings {
+		if quiet && f.Severity == severityFixed {
+			continue
+		}
+		fmt.Fprintf(w, "%v %v %v (%v) [%v] %v\n", stdout.severity(f.Severity), f.Type, f.ID, f.Title, f.Check, f.Message)
+	}
+}
+
+func repair(c *cli.Context) error {
+	arg := c.Args().First()
+	if arg == "" {
+		return cli.NewExitError("export file, directory or dashboard name missing", 1)
+	}
+	if _, statErr := os.Stat(arg); statErr == nil {
+		export, err := readBundle(arg)
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		repaired, findings, _, err := repairExport(export, exportAliases(export))
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		printRepairs(os.Stderr, findings)
+		os.Stdout.Write(repaired)
+		return nil
+	}
+
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	client := newClient()
+	found, err := client.findDashboard(arg)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	export, err := client.exportDashboards(found.ID)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	aliases := exportAliases(export)
+	if err := client.resolveAliases(export, aliases); err != nil {
+		client.Logger.Printf("could not resolve the legacy url aliases: %v\n", err)
+	}
+	repaired, findings, changed, err := repairExport(export, aliases)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	printRepairs(os.Stdout, findings)
+	if len(changed) == 0 {
+		if !quiet {
+			fmt.Fprintf(os.Stdout, "nothing to repair in dashboard %v\n", found.ID)
+		}
+		return nil
+	}
+	if c.Bool("dry-run") {
+		return nil
+	}
+	objects := exportObjects(repaired)
+	for _, key := range changed {
+		object := objects[key]
+		version := object.Get("version").String()
+		if c.Bool("force") {
+			version = ""
+		}
+		if err := client.updateObject(object.Get("type").String(), object.Get("id").String(), version, object.Get("attributes").Raw, object.Get("references").Raw); err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		printIDs(object.Get("id").String())
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestRepairExport(t *testing.T) {
+	const searchSource = `"kibanaSavedObjectMeta":{"searchSourceJSON":"{}"}`
+	tests := []struct {
+		name    string
+		object  string
+		aliases map[string]string
+		checks  []string
+		changed bool
+		path    string
+		want    string
+	}{
+		{
+			name:   "healthy",
+			object: `{"type":"visualization","id":"v1","attributes":{"visState":"{\"type\":\"line\"}",` + searchSource + `},"references":[]}`,
+		},
+		{
+			name:    "json stored as a document",
+			object:  `{"type":"visualization","id":"v1","attributes":{"visState":{"type":"line"},` + searchSource + `},"references":[]}`,
+			checks:  []string{"fixed json-attribute"},
+			changed: true,
+			path:    "attributes.visState",
+			want:    `{"type":"line"}`,
+		},
+		{
+			name:    "json escaped twice",
+			object:  `{"type":"visualization","id":"v1","attributes":{"visState":"\"{\\\"type\\\":\\\"line\\\"}\"",` + searchSource + `},"references":[]}`,
+			checks:  []string{"fixed json-attribute"},
+			changed: true,
+			path:    "attributes.visState",
+			want:    `{"type":"line"}`,
+		},
+		{
+			name:    "missing search source",
+			object:  `{"type":"search","id":"s1","attributes":{"title":"Errors"},"references":[{"name":"kibanaSavedObjectMeta.searchSourceJSON.index","type":"index-pattern","id":"logs"}]}`,
+			checks:  []string{"fixed search-source"},
+			changed: true,
+			path:    "attributes.kibanaSavedObjectMeta.searchSourceJSON",
+			want:    `{"filter":[],"indexRefName":"kibanaSavedObjectMeta.searchSourceJSON.index","query":{"language":"kuery","query":""}}`,
+		},
+		{
+			name:    "duplicate panel indices",
+			object:  `{"type":"dashboard","id":"d1","attributes":{"panelsJSON":"[{\"panelIndex\":\"1\",\"gridData\":{\"i\":\"1\"}},{\"panelIndex\":\"1\",\"gridData\":{\"i\":\"1\"}}]",` + searchSource + `},"references":[]}`,
+			checks:  []string{"fixed panel-index", "fixed panel-index"},
+			changed: true,
+			path:    "attributes.panelsJSON",
+			want:    `[{"gridData":{"i":"1"},"panelIndex":"1"},{"gridData":{"i":"3"},"panelIndex":"3"}]`,
+		},
+		{
+			name:   "dangling panel reference",
+			object: `{"type":"dashboard","id":"d1","attributes":{"panelsJSON":"[{\"panelIndex\":\"1\",\"gridData\":{\"i\":\"1\"},\"panelRefName\":\"panel_0\"}]",` + searchSource + `},"references":[]}`,
+			checks: []string{"warning panel-index"},
+		},
+		{
+			name:    "aliased reference",
+			object:  `{"type":"visualization","id":"v1","attributes":{` + searchSource + `},"references":[{"name":"kibanaSavedObjectMeta.searchSourceJSON.index","type":"index-pattern","id":"old"}]}`,
+			aliases: map[string]string{"index-pattern:old": "new"},
+			checks:  []string{"fixed reference"},
+			changed: true,
+			path:    "references.0.id",
+			want:    "new",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			export := []byte(`{"objects":[` + test.object + `]}`)
+			repaired, findings, changed, err := repairExport(export, test.aliases)
+			if err != nil {
+				t.Fatalf("repairExport() error: %v", err)
+			}
+			var checks []string
+			for _, f := range findings {
+				checks = append(checks, f.Severity+" "+f.Check)
+			}
+			if strings.Join(checks, ", ") != strings.Join(test.checks, ", ") {
+				t.Errorf("repairExport() findings = %v, want %v", checks, test.checks)
+			}
+			if (len(changed) > 0) != test.changed {
+				t.Errorf("repairExport() changed = %v, want changed %v", changed, test.changed)
+			}
+			if !test.changed && string(repaired) != string(export) {
+				t.Errorf("repairExport() rewrote the export to %s", repaired)
+			}
+			if test.path != "" {
+				if got := gjson.GetBytes(repaired, "objects.0."+test.path).String(); got != test.want {
+					t.Errorf("%v = %v, want %v", test.path, got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveAliases(t *testing.T) {
+	export := []byte(`{"objects":[
+		{"type":"visualization","id":"v1","attributes":{},"references":[
+			{"name":"kibanaSavedObjectMeta.searchSourceJSON.index","type":"index-pattern","id":"old"},
+			{"name":"search_0","type":"search","id":"s1"}]},
+		{"type":"visualization","id":"v2","attributes":{},"references":[
+			{"name":"kibanaSavedObjectMeta.searchSourceJSON.index","type":"index-pattern","id":"old"},
+			{"name":"search_0","type":"search","id":"v1-search"}]}]}`)
+	tests := []struct {
+		name          string
+		resolveStatus int
+		want          map[string]string
+	}{
+		{"resolve api", http.StatusOK, map[string]string{"index-pattern:old": "new", "search:v1-search": "kept"}},
+		{"kibana without the resolve api", http.StatusNotFound, map[string]string{"search:v1-search": "kept"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requested []map[string]string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				requested = nil
+				json.Unmarshal(body, &requested)
+				switch r.URL.Path {
+				case "/api/saved_objects/_bulk_resolve":
+					if test.resolveStatus != http.StatusOK {
+						w.WriteHeader(test.resolveStatus)
+						return
+					}
+					w.Write([]byte(`{"resolved_objects":[
+						{"saved_object":{"type":"index-pattern","id":"new"},"outcome":"aliasMatch","alias_target_id":"new"},
+						{"saved_object":{"type":"search","id":"s1"},"outcome":"exactMatch"}]}`))
+				case "/api/saved_objects/_bulk_get":
+					w.Write([]byte(`{"saved_objects":[{"type":"index-pattern","id":"old"},{"type":"search","id":"s1"}]}`))
+				default:
+					t.Errorf("unexpected request %v %v", r.Method, r.URL)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			aliases := map[string]string{"search:v1-search": "kept"}
+			if err := newTestClient(server).resolveAliases(export, aliases); err != nil {
+				t.Fatalf("resolveAliases() error: %v", err)
+			}
+			if !reflect.DeepEqual(aliases, test.want) {
+				t.Errorf("resolveAliases() aliases = %v, want %v", aliases, test.want)
+			}
+			// the references already aliased or repeated are resolved once
+			if want := []map[string]string{{"type": "index-pattern", "id": "old"}, {"type": "search", "id": "s1"}}; !reflect.DeepEqual(requested, want) {
+				t.Errorf("resolved %v, want %v", requested, want)
+			}
+		})
+	}
+}
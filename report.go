@@ -0,0 +1,301 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"github.com/urfave/cli"
+)
+
+// reportPollInterval is how often a pending report job is checked.
+const reportPollInterval = 5 * time.Second
+
+// rison encodes the value in the url friendly notation the reporting api
+// expects its job parameters in.
+func rison(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "!n"
+	case bool:
+		if v {
+			return "!t"
+		}
+		return "!f"
+	case string:
+		return "'" + strings.NewReplacer("!", "!!", "'", "!'").Replace(v) + "'"
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = rison(item)
+		}
+		return "!(" + strings.Join(items, ",") + ")"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		items := make([]string, len(keys))
+		for i, key := range keys {
+			items[i] = rison(key) + ":" + rison(v[key])
+		}
+		return "(" + strings.Join(items, ",") + ")"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v)
+	case json.Number:
+		return v.String()
+	}
+	return rison(fmt.Sprint(value))
+}
+
+// findSavedSearch returns the saved search with the id, or else the single one
+// titled as the name.
+func (c *client) findSavedSearch(name string) (gjson.Result, error) {
+	object, err := c.getObject("search", name)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	if object != nil {
+		return gjson.ParseBytes(object), nil
+	}
+	objects, err := c.findObjects([]string{"search"}, name)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	var found []savedObject
+	for _, o := range objects {
+		if strings.EqualFold(o.title(), name) {
+			found = append(found, o)
+		}
+	}
+	if len(found) == 0 {
+		return gjson.Result{}, errors.Errorf("no saved search found matching: %v.\n", name)
+	}
+	if len(found) > 1 {
+		return gjson.Result{}, errors.Errorf("more than one saved search found matching: %v.\n", name)
+	}
+	if object, err = c.getObject("search", found[0].ID); err != nil {
+		return gjson.Result{}, err
+	}
+	return gjson.ParseBytes(object), nil
+}
+
+// csvJobParams returns the parameters of the csv report of the saved search
+// over the time range up to now, as the discover share menu builds them.
+func csvJobParams(search, dataView gjson.Result, from, version string) (map[string]interface{}, error) {
+	searchSource := gjson.Parse(search.Get("attributes.kibanaSavedObjectMeta.searchSourceJSON").String())
+	refs := make(map[string]string)
+	for _, ref := range search.Get("references").Array() {
+		refs[ref.Get("name").String()] = ref.Get("id").String()
+	}
+
+	var filters []interface{}
+	for _, filter := range searchSource.Get("filter").Array() {
+		raw := filter.Raw
+		if name := filter.Get("meta.indexRefName").String(); name != "" {
+			var err error
+			if raw, err = sjson.Set(raw, "meta.index", refs[name]); err != nil {
+				return nil, err
+			}
+			if raw, err = sjson.Delete(raw, "meta.indexRefName"); err != nil {
+				return nil, err
+			}
+		}
+		filters = append(filters, gjson.Parse(raw).Value())
+	}
+	if timeField := dataView.Get("attributes.timeFieldName").String(); timeField != "" {
+		filters = append(filters, map[string]interface{}{
+			"meta": map[string]interface{}{"index": dataView.Get("id").String(), "params": map[string]interface{}{}},
+			"range": map[string]interface{}{timeField: map[string]interface{}{
+				"format": "strict_date_optional_time", "gte": from, "lte": "now",
+			}},
+		})
+	}
+
+	source := map[string]interface{}{
+		"index":  dataView.Get("id").String(),
+		"filter": filters,
+	}
+	if query := searchSource.Get("query"); query.Exists() {
+		source["query"] = query.Value()
+	}
+	var sorts []interface{}
+	for _, s := range search.Get("attributes.sort").Array() {
+		if pair := s.Array(); len(pair) == 2 {
+			sorts = append(sorts, map[string]interface{}{pair[0].String(): pair[1].String()})
+		}
+	}
+	if len(sorts) > 0 {
+		source["sort"] = sorts
+	}
+	var columns []interface{}
+	for _, column := range search.Get("attributes.columns").Array() {
+		if column.String() != "_source" {
+			columns = append(columns, column.String())
+		}
+	}
+	if len(columns) > 0 {
+		source["fields"] = columns
+	}
+	return map[string]interface{}{
+		"title":           search.Get("attributes.title").String(),
+		"objectType":      "search",
+		"browserTimezone": "UTC",
+		"version":         version,
+		"columns":         columns,
+		"searchSource":    source,
+	}, nil
+}
+
+// generateCSV queues the csv report of the saved search and returns the path
+// its content is downloaded from once generated.
+func (c *client) generateCSV(search gjson.Result, from string) (string, error) {
+	if c.Flavor == "opensearch" {
+		return "", errors.Errorf("csv reports are not supported with opensearch dashboards.\n")
+	}
+	version, err := c.version()
+	if err != nil {
+		return "", err
+	}
+	parts := append(strings.Split(version, "."), "0")
+	major, _ := strconv.Atoi(parts[0])
+	minor, _ := strconv.Atoi(parts[1])
+	if major < 7 || major == 7 && minor < 15 {
+		return "", errors.Errorf("csv reports of saved searches require kibana 7.15 or later, found %v.\n", version)
+	}
+	dataViewID := objectDataView(search)
+	if dataViewID == "" {
+		return "", errors.Errorf("saved search %v has no data view.\n", search.Get("id").String())
+	}
+	dataView, err := c.getObject("index-pattern", dataViewID)
+	if err != nil {
+		return "", err
+	}
+	if dataView == nil {
+		return "", errors.Errorf("data view %v of the saved search not found.\n", dataViewID)
+	}
+	params, err := csvJobParams(search, gjson.ParseBytes(dataView), from, version)
+	if err != nil {
+		return "", err
+	}
+	response, err := c.jsonRequest("POST", "/api/reporting/generate/csv_searchsource", map[string]string{"jobParams": rison(params)})
+	if err != nil {
+		return "", errors.Wrap(err, "could not queue the csv report")
+	}
+	path := gjson.GetBytes(response, "path").String()
+	if path == "" {
+		return "", errors.Errorf("reporting did not return the download path of the job.\n")
+	}
+	c.Logger.Printf("queued report job %v\n", gjson.GetBytes(response, "job.id").String())
+	return path, nil
+}
+
+// downloadReport waits for the report job up to the timeout and streams its
+// content to the writer, the csv reports being possibly larger than the
+// responses read in memory. The download path answers 503 while the job is
+// pending.
+func (c *client) downloadReport(path string, timeout time.Duration, w io.Writer) error {
+	if u, err := url.Parse(path); err == nil && u.IsAbs() {
+		path = u.RequestURI()
+	}
+	if c.Space != "" && c.Space != "default" {
+		path = strings.TrimPrefix(path, "/s/"+c.Space)
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		req, err := c.newRequest("GET", path, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Del("Accept")
+		resp, err := c.do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusOK {
+			if resp.Header.Get("kbn-csv-contains-formulas") == "true" && !quiet {
+				fmt.Fprintf(os.Stderr, "warning: the report contains values which spreadsheets may evaluate as formulas\n")
+			}
+			_, err := io.Copy(w, resp.Body)
+			resp.Body.Close()
+			return errors.Wrap(err, "could not download the report")
+		}
+		body, err := readBody(req, resp, c.MaxResponseSize)
+		resp.Body.Close()
+		switch {
+		case err != nil:
+			return err
+		case resp.StatusCode != http.StatusServiceUnavailable:
+			return c.apiError("generate csv report", resp, body)
+		case time.Now().Add(reportPollInterval).After(deadline):
+			return errors.Errorf("report not generated after %v, download it later from %v.\n", timeout, path)
+		}
+		c.Logger.Printf("report pending, waiting\n")
+		time.Sleep(reportPollInterval)
+	}
+}
+
+func searchCSV(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	name := c.Args().First()
+	if name == "" {
+		return cli.NewExitError("saved search name missing", 1)
+	}
+	from := c.String("time-range")
+	if !strings.HasPrefix(from, "now-") {
+		return cli.NewExitError(fmt.Sprintf("invalid --time-range %v, e.g. now-7d expected", from), 1)
+	}
+	out := c.String("out")
+	if out == "" {
+		return cli.NewExitError("--out missing", 1)
+	}
+
+	client := newClient()
+	search, err := client.findSavedSearch(name)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	path, err := client.generateCSV(search, from)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if out == "-" {
+		if err := client.downloadReport(path, c.Duration("timeout"), os.Stdout); err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		return nil
+	}
+	file, err := os.Create(out)
+	if err != nil {
+		return cli.NewExitError(errors.Wrapf(err, "could not write %v", out), 2)
+	}
+	err = client.downloadReport(path, c.Duration("timeout"), file)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = errors.Wrapf(closeErr, "could not write %v", out)
+	}
+	if err != nil {
+		os.Remove(out)
+		return cli.NewExitError(err, 2)
+	}
+	if !quiet {
+		fmt.Fprintf(os.Stdout, "%v written to %v\n", search.Get("attributes.title").String(), stdout.paint(cyan, out))
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package kibctl
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRison(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"null", nil, "!n"},
+		{"true", true, "!t"},
+		{"false", false, "!f"},
+		{"int", 42, "42"},
+		{"float", -1.5, "-1.5"},
+		{"string", "logs-*", "'logs-*'"},
+		{"escaped string", "it's done!", "'it!'s done!!'"},
+		{"empty array", []interface{}{}, "!()"},
+		{"array", []interface{}{"a", 1, nil}, "!('a',1,!n)"},
+		{"sorted object", map[string]interface{}{"title": "Logs", "columns": []interface{}{"host"}},
+			"('columns':!('host'),'title':'Logs')"},
+		{"int64", int64(7), "7"},
+		{"uint", uint(7), "7"},
+		{"float32", float32(0.5), "0.5"},
+		{"json number", json.Number("12.50"), "12.50"},
+		{"other values", time.Duration(0), "'0s'"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := rison(test.value); got != test.want {
+				t.Errorf("rison(%#v) = %v, want %v", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func TestDownloadReport(t *testing.T) {
+	report := strings.Repeat("host,bytes\n", 100)
+	tests := []struct {
+		name   string
+		status int
+		want   string
+	}{
+		{"ready", http.StatusOK, ""},
+		{"pending", http.StatusServiceUnavailable, "report not generated"},
+		{"failed", http.StatusInternalServerError, "generate csv report"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/s/ops/api/reporting/jobs/download/j1" {
+					t.Errorf("unexpected request %v", r.URL)
+				}
+				w.WriteHeader(test.status)
+				if test.status == http.StatusOK {
+					w.Write([]byte(report))
+					return
+				}
+				w.Write([]byte(`{"statusCode":500,"error":"Internal Server Error"}`))
+			}))
+			defer server.Close()
+			c := newTestClient(server)
+			c.Space = "ops"
+			// the report is streamed, whatever the size of the responses read in memory
+			c.MaxResponseSize = 128
+
+			var out bytes.Buffer
+			err := c.downloadReport(server.URL+"/s/ops/api/reporting/jobs/download/j1", 0, &out)
+			switch {
+			case test.want == "" && err != nil:
+				t.Fatalf("downloadReport() error: %v", err)
+			case test.want != "" && (err == nil || !strings.Contains(err.Error(), test.want)):
+				t.Fatalf("downloadReport() error = %v, want %v", err, test.want)
+			}
+			if test.want == "" && out.String() != report {
+				t.Errorf("downloadReport() wrote %d bytes, want %d", out.Len(), len(report))
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package kibctl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+// ruleUpdateFields are the rule properties accepted when updating a rule, the
+// creation also accepting its type, consumer and enablement.
+var ruleUpdateFields = []string{"name", "tags", "schedule", "throttle", "notify_when", "params", "actions"}
+var ruleCreateFields = append([]string{"rule_type_id", "consumer", "enabled"}, ruleUpdateFields...)
+
+// rulePayload keeps the fields of the rule definition accepted by the api.
+func rulePayload(rule gjson.Result, fields []string) ([]byte, error) {
+	payload := make(map[string]json.RawMessage)
+	for _, field := range fields {
+		value := rule.Get(field)
+		if !value.Exists() {
+			continue
+		}
+		if field == "actions" {
+			var actions []json.RawMessage
+			for _, action := range value.Array() {
+				kept := make(map[string]json.RawMessage)
+				for _, key := range []string{"group", "id", "params", "frequency"} {
+					if v := action.Get(key); v.Exists() {
+						kept[key] = json.RawMessage(v.Raw)
+					}
+				}
+				raw, _ := json.Marshal(kept)
+				actions = append(actions, raw)
+			}
+			raw, _ := json.Marshal(actions)
+			payload[field] = raw
+			continue
+		}
+		payload[field] = json.RawMessage(value.Raw)
+	}
+	return json.Marshal(payload)
+}
+
+// importRule creates the alerting rule with its id, or updates it when it
+// already exists.
+func (c *client) importRule(rule gjson.Result) error {
+	id := rule.Get("id").String()
+	if id == "" {
+		return errors.Errorf("rule %v has no id.\n", rule.Get("name").String())
+	}
+	path := "/api/alerting/rule/" + url.PathEscape(id)
+	existing, err := c.getRule(id)
+	if err != nil {
+		return err
+	}
+
+	method, fields := "POST", ruleCreateFields
+	if existing != nil {
+		method, fields = "PUT", ruleUpdateFields
+	}
+	payload, err := rulePayload(rule, fields)
+	if err != nil {
+		return err
+	}
+	c.Logger.Printf("importing rule %v:\n%v\n", id, string(payload))
+	req, err := c.newRequest(method, path, bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+	resp, details, err := c.doRequest(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return c.apiError(fmt.Sprintf("import rule %v", id), resp, details)
+	}
+	return nil
+}
+
+// getRule retrieves an alerting rule, returning nil when it does not exist.
+func (c *client) getRule(id string) ([]byte, error) {
+	req, err := c.newRequest("GET", "/api/alerting/rule/"+url.PathEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, body, err := c.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.apiError(fmt.Sprintf("retrieve rule %v", id), resp, body)
+	}
+	return body, nil
+}
+
+func ruleImport(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	file := c.Args().First()
+	if file == "" {
+		return cli.NewExitError("rules file missing", 1)
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return cli.NewExitError(errors.Wrap(err, "could not read rules file"), 2)
+	}
+	if valuesFile := c.String("values"); valuesFile != "" {
+		values, err := loadValues(valuesFile)
+		if err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		content, err = renderTemplate(content, values)
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+	}
+	if !gjson.ValidBytes(content) {
+		return cli.NewExitError(fmt.Sprintf("invalid json in %v", file), 2)
+	}
+
+	// the file holds a single rule or an array of rules
+	rules := []gjson.Result{gjson.ParseBytes(content)}
+	if rules[0].IsArray() {
+		rules = rules[0].Array()
+	}
+	client := newClient()
+	for _, rule := range rules {
+		if err := client.importRule(rule); err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		printIDs(rule.Get("id").String())
+	}
+	return nil
+}
+
+// snoozeRule silences the notifications of the rule for the duration.
+func (c *client) snoozeRule(id string, duration time.Duration) error {
+	schedule := map[string]interface{}{
+		"schedule": map[string]interface{}{
+			"custom": map[string]interface{}{
+				"start":    time.Now().UTC().Format(time.RFC3339),
+				"duration": kibanaDuration(duration),
+			},
+		},
+	}
+	_, err := c.jsonRequest("POST", fmt.Sprintf("/api/alerting/rule/%v/snooze_schedule", url.PathEscape(id)), schedule)
+	return err
+}
+
+// kibanaDuration formats the duration the way the alerting api expects it,
+// in the largest whole unit.
+func kibanaDuration(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+	return fmt.Sprintf("%ds", d/time.Second)
+}
+
+func ruleSnooze(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	id := c.Args().First()
+	if id == "" {
+		return cli.NewExitError("rule id missing", 1)
+	}
+	duration := c.Duration("for")
+	if duration < time.Minute {
+		return cli.NewExitError("--for duration of at least 1m required", 1)
+	}
+	if err := newClient().snoozeRule(id, duration); err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	printIDs(id)
+	return nil
+}
@@ -0,0 +1,138 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+const sampleDataAPI = "/api/sample_data"
+
+// sampleDatasets are the sample data sets shipped with kibana.
+var sampleDatasets = []string{"flights", "logs", "ecommerce"}
+
+type sampleDataset struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+func (c *client) listSampleData() ([]sampleDataset, error) {
+	body, err := c.jsonRequest("GET", sampleDataAPI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var datasets []sampleDataset
+	if err := json.Unmarshal(body, &datasets); err != nil {
+		return nil, errors.Wrap(err, "could not parse sample data sets")
+	}
+	return datasets, nil
+}
+
+// installSampleData loads the data set in elasticsearch and imports its saved
+// objects in the space of the client, replacing them when already installed.
+func (c *client) installSampleData(id string) error {
+	_, err := c.jsonRequest("POST", sampleDataAPI+"/"+url.PathEscape(id), nil)
+	return err
+}
+
+func (c *client) uninstallSampleData(id string) error {
+	_, err := c.jsonRequest("DELETE", sampleDataAPI+"/"+url.PathEscape(id), nil)
+	return err
+}
+
+// sampleDataArgs returns the data sets given as arguments, checking they are
+// known.
+func sampleDataArgs(c *cli.Context) ([]string, error) {
+	if c.NArg() == 0 {
+		return nil, cli.NewExitError(fmt.Sprintf("sample data set missing, %v expected", strings.Join(sampleDatasets, ", ")), 1)
+	}
+	for _, id := range c.Args() {
+		if !contains(sampleDatasets, id) {
+			return nil, cli.NewExitError(fmt.Sprintf("unknown sample data set %v, %v expected", id, strings.Join(sampleDatasets, ", ")), 1)
+		}
+	}
+	return c.Args(), nil
+}
+
+func sampleDataInstall(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	ids, err := sampleDataArgs(c)
+	if err != nil {
+		return err
+	}
+	client := newClient()
+	installed := make(map[string]bool)
+	if c.Bool("skip-installed") {
+		datasets, err := client.listSampleData()
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		for _, d := range datasets {
+			installed[d.ID] = d.Status == "installed"
+		}
+	}
+	for _, id := range ids {
+		if installed[id] {
+			if !quiet {
+				fmt.Fprintf(os.Stdout, "sample data %v already installed\n", id)
+			}
+			continue
+		}
+		if err := client.installSampleData(id); err != nil {
+			return cli.NewExitError(errors.Wrapf(err, "could not install sample data %v", id), 2)
+		}
+		if !quiet {
+			fmt.Fprintf(os.Stdout, "installed sample data %v\n", id)
+		}
+	}
+	return nil
+}
+
+func sampleDataUninstall(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	ids, err := sampleDataArgs(c)
+	if err != nil {
+		return err
+	}
+	client := newClient()
+	for _, id := range ids {
+		if err := client.uninstallSampleData(id); err != nil {
+			return cli.NewExitError(errors.Wrapf(err, "could not uninstall sample data %v", id), 2)
+		}
+		if !quiet {
+			fmt.Fprintf(os.Stdout, "uninstalled sample data %v\n", id)
+		}
+	}
+	return nil
+}
+
+func sampleDataList(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	datasets, err := newClient().listSampleData()
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if quiet {
+		for _, d := range datasets {
+			printIDs(d.ID)
+		}
+		return nil
+	}
+	os.Stdout.WriteString(stdout.header(fmt.Sprintf("%-15v %-15v %v", "ID", "STATUS", "NAME")) + "\n")
+	for _, d := range datasets {
+		fmt.Fprintf(os.Stdout, "%v %-15v %v\n", stdout.paint(cyan, fmt.Sprintf("%-15v", d.ID)), d.Status, d.Name)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package kibctl
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// The saved object schemas are a subset of json schema: type, required,
+// properties, items, enum, minimum and maximum, along with contentMediaType
+// and contentSchema for the attributes kibana stores as json strings.
+const (
+	gridDataSchema = `{"type":"object","required":["x","y","w","h"],"properties":{
+		"x":{"type":"integer","minimum":0},"y":{"type":"integer","minimum":0},
+		"w":{"type":"integer","minimum":1,"maximum":48},"h":{"type":"integer","minimum":1},
+		"i":{"type":"string"}}}`
+	searchSourceSchema = `{"type":"object","properties":{
+		"searchSourceJSON":{"type":"string","contentMediaType":"application/json","contentSchema":{"type":"object"}}}}`
+	referencesSchema = `{"type":"array","items":{"type":"object","required":["name","type","id"],"properties":{
+		"name":{"type":"string"},"type":{"type":"string"},"id":{"type":"string"}}}}`
+	visStateSchema = `{"type":"object","required":["type"],"properties":{
+		"type":{"type":"string"},"title":{"type":"string"},"params":{"type":"object"},
+		"aggs":{"type":"array","items":{"type":"object","required":["type"],"properties":{
+			"id":{"type":["string","integer"]},"type":{"type":"string"},"schema":{"type":"string"},"params":{"type":"object"}}}}}}`
+)
+
+// panelSchemas are the dashboard panels per kibana major version: kibana 6
+// panels name the object they display, later ones refer to it through the
+// references of the dashboard or embed it by value.
+var panelSchemas = map[int]string{
+	6: `{"type":"object","required":["gridData","panelIndex","id","type"],"properties":{
+		"gridData":` + gridDataSchema + `,"panelIndex":{"type":["string","integer"]},
+		"id":{"type":"string"},"type":{"type":"string"},"embeddableConfig":{"type":"object"}}}`,
+	7: `{"type":"object","required":["gridData","panelIndex"],"properties":{
+		"gridData":` + gridDataSchema + `,"panelIndex":{"type":"string"},
+		"panelRefName":{"type":"string"},"embeddableConfig":{"type":"object"},"version":{"type":"string"}}}`,
+	8: `{"type":"object","required":["gridData","panelIndex"],"properties":{
+		"gridData":` + gridDataSchema + `,"panelIndex":{"type":"string"},"type":{"type":"string"},
+		"panelRefName":{"type":"string"},"embeddableConfig":{"type":"object"},"version":{"type":"string"}}}`,
+}
+
+// attributeSchemas returns the attribute schemas of the saved object types
+// for the kibana major version. Kibana 8 moved the field list out of the data
+// views, which only keep the fields with customizations.
+func attributeSchemas(major int) map[string]string {
+	fieldsRequired := `,"fields"`
+	if major >= 8 {
+		fieldsRequired = ""
+	}
+	return map[string]string{
+		"dashboard": `{"type":"object","required":["title","panelsJSON"],"properties":{
+			"title":{"type":"string"},"description":{"type":"string"},
+			"panelsJSON":{"type":"string","contentMediaType":"application/json","contentSchema":{"type":"array","items":` + panelSchemas[major] + `}},
+			"optionsJSON":{"type":"string","contentMediaType":"application/json","contentSchema":{"type":"object"}},
+			"timeRestore":{"type":"boolean"},"timeFrom":{"type":"string"},"timeTo":{"type":"string"},
+			"refreshInterval":{"type":"object","required":["pause","value"],"properties":{"pause":{"type":"boolean"},"value":{"type":"integer","minimum":0}}},
+			"kibanaSavedObjectMeta":` + searchSourceSchema + `}}`,
+		"visualization": `{"type":"object","required":["title","visState"],"properties":{
+			"title":{"type":"string"},"description":{"type":"string"},
+			"visState":{"type":"string","contentMediaType":"application/json","contentSchema":` + visStateSchema + `},
+			"uiStateJSON":{"type":"string","contentMediaType":"application/json","contentSchema":{"type":"object"}},
+			"savedSearchRefName":{"type":"string"},
+			"kibanaSavedObjectMeta":` + searchSourceSchema + `}}`,
+		"index-pattern": `{"type":"object","required":["title"` + fieldsRequired + `],"properties":{
+			"title":{"type":"string"},"timeFieldName":{"type":"string"},
+			"fields":{"type":"string","contentMediaType":"application/json","contentSchema":{"type":"array","items":{"type":"object","required":["name","type"]}}},
+			"fieldFormatMap":{"type":"string","contentMediaType":"application/json","contentSchema":{"type":"object"}},
+			"sourceFilters":{"type":"string","contentMediaType":"application/json","contentSchema":{"type":"array"}}}}`,
+	}
+}
+
+// schemaMajor returns the closest kibana major version with schemas, the
+// schemas of a version being kept until a later one changes them.
+func schemaMajor(major int) int {
+	var majors []int
+	for m := range panelSchemas {
+		majors = append(majors, m)
+	}
+	sort.Ints(majors)
+	best := majors[0]
+	for _, m := range majors {
+		if m <= major {
+			best = m
+		}
+	}
+	return best
+}
+
+// objectSchema returns the schema of the saved objects of the type, empty
+// when the type has none.
+func objectSchema(objectType string, major int) string {
+	attributes, ok := attributeSchemas(schemaMajor(major))[objectType]
+	if !ok {
+		return ""
+	}
+	return `{"type":"object","required":["id","type","attributes"],"properties":{
+		"id":{"type":"string"},"type":{"type":"string"},
+		"attributes":` + attributes + `,"references":` + referencesSchema + `}}`
+}
+
+// validateSchemas reports the objects of the payload which do not match the
+// schema of their type for the kibana major version, each finding naming the
+// path of the offending value.
+func validateSchemas(payload []byte, major int) []lintFinding {
+	var findings []lintFinding
+	for _, object := range gjson.GetBytes(payload, "objects").Array() {
+		schema := objectSchema(object.Get("type").String(), major)
+		if schema == "" {
+			continue
+		}
+		for _, violation := range validateValue(object, gjson.Parse(schema), "") {
+			findings = append(findings, newFinding(object, severityError, "schema", "%v", violation))
+		}
+	}
+	return findings
+}
+
+func schemaPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// validateValue checks the value against the schema and returns the
+// violations, e.g. "attributes.panelsJSON[3].gridData.w must be integer".
+func validateValue(value, schema gjson.Result, path string) []string {
+	name := path
+	if name == "" {
+		name = "object"
+	}
+	if types := schema.Get("type"); types.Exists() {
+		var expected []string
+		for _, t := range types.Array() {
+			expected = append(expected, t.String())
+		}
+		if !hasSchemaType(value, expected) {
+			return []string{fmt.Sprintf("%v must be %v", name, strings.Join(expected, " or "))}
+		}
+	}
+	if enum := schema.Get("enum"); enum.Exists() {
+		found := false
+		var allowed []string
+		for _, e := range enum.Array() {
+			found = found || e.Raw == value.Raw
+			allowed = append(allowed, e.Raw)
+		}
+		if !found {
+			return []string{fmt.Sprintf("%v must be one of %v", name, strings.Join(allowed, ", "))}
+		}
+	}
+	if value.Type == gjson.Number {
+		if minimum := schema.Get("minimum"); minimum.Exists() && value.Num < minimum.Num {
+			return []string{fmt.Sprintf("%v must be at least %v", name, minimum.Raw)}
+		}
+		if maximum := schema.Get("maximum"); maximum.Exists() && value.Num > maximum.Num {
+			return []string{fmt.Sprintf("%v must be at most %v", name, maximum.Raw)}
+		}
+	}
+
+	var violations []string
+	if value.IsObject() {
+		fields := value.Map()
+		for _, key := range schema.Get("required").Array() {
+			if _, ok := fields[key.String()]; !ok {
+				violations = append(violations, fmt.Sprintf("%v is required", schemaPath(path, key.String())))
+			}
+		}
+		schema.Get("properties").ForEach(func(key, property gjson.Result) bool {
+			if field, ok := fields[key.String()]; ok {
+				violations = append(violations, validateValue(field, property, schemaPath(path, key.String()))...)
+			}
+			return true
+		})
+	}
+	if items := schema.Get("items"); items.Exists() && value.IsArray() {
+		for i, item := range value.Array() {
+			violations = append(violations, validateValue(item, items, fmt.Sprintf("%v[%d]", path, i))...)
+		}
+	}
+	if schema.Get("contentMediaType").String() == "application/json" && value.Type == gjson.String {
+		if !gjson.Valid(value.String()) {
+			return append(violations, fmt.Sprintf("%v must be valid json", name))
+		}
+		if content := schema.Get("contentSchema"); content.Exists() {
+			violations = append(violations, validateValue(gjson.Parse(value.String()), content, path)...)
+		}
+	}
+	return violations
+}
+
+func hasSchemaType(value gjson.Result, types []string) bool {
+	for _, t := range types {
+		switch {
+		case t == "string" && value.Type == gjson.String,
+			t == "number" && value.Type == gjson.Number,
+			t == "integer" && value.Type == gjson.Number && value.Num == math.Trunc(value.Num),
+			t == "boolean" && (value.Type == gjson.True || value.Type == gjson.False),
+			t == "null" && value.Type == gjson.Null,
+			t == "object" && value.IsObject(),
+			t == "array" && value.IsArray():
+			return true
+		}
+	}
+	return false
+}
+
+// checkSchemas validates the payload against the schemas of the kibana major
+// version targeted by the client.
+func (c *client) checkSchemas(payload []byte) ([]lintFinding, error) {
+	major, err := c.major()
+	if err != nil {
+		return nil, err
+	}
+	return validateSchemas(payload, major), nil
+}
@@ -0,0 +1,79 @@
+package kibctl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestValidateValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		schema string
+		want   []string
+	}{
+		{"valid", `{"x":0,"y":2,"w":24,"h":15}`, gridDataSchema, nil},
+		{"wrong type", `"x"`, `{"type":"object"}`, []string{"object must be object"}},
+		{"type union", `3`, `{"type":["string","integer"]}`, nil},
+		{"not an integer", `{"x":0.5,"y":0,"w":24,"h":15}`, gridDataSchema, []string{"x must be integer"}},
+		{"required", `{"x":0,"y":0}`, gridDataSchema, []string{"w is required", "h is required"}},
+		{"minimum", `{"x":0,"y":0,"w":0,"h":15}`, gridDataSchema, []string{"w must be at least 1"}},
+		{"maximum", `{"x":0,"y":0,"w":49,"h":15}`, gridDataSchema, []string{"w must be at most 48"}},
+		{"enum", `"area"`, `{"enum":["line","bar"]}`, []string{"object must be one of \"line\", \"bar\""}},
+		{"items", `[{"name":"a","type":"index-pattern","id":"1"},{"name":"b","type":"index-pattern"}]`,
+			referencesSchema, []string{"[1].id is required"}},
+		{"invalid json content", `{"searchSourceJSON":"{"}`, searchSourceSchema,
+			[]string{"searchSourceJSON must be valid json"}},
+		{"json content schema", `{"searchSourceJSON":"[]"}`, searchSourceSchema,
+			[]string{"searchSourceJSON must be object"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := validateValue(gjson.Parse(test.value), gjson.Parse(test.schema), "")
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("validateValue() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckSchemasDetectsVersion(t *testing.T) {
+	payload := []byte(`{"objects":[{"id":"ip1","type":"index-pattern","attributes":{"title":"logs-*"},"references":[]}]}`)
+	tests := []struct {
+		name     string
+		status   int
+		version  string
+		findings int
+		fails    bool
+	}{
+		{"kibana 7 data view without fields", http.StatusOK, "7.17.9", 1, false},
+		{"kibana 8 data view without fields", http.StatusOK, "8.11.0", 0, false},
+		{"status unavailable", http.StatusServiceUnavailable, "", 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/status" {
+					t.Errorf("unexpected request %v", r.URL)
+				}
+				w.WriteHeader(test.status)
+				w.Write([]byte(`{"version":{"number":"` + test.version + `"}}`))
+			}))
+			defer server.Close()
+			c := newTestClient(server)
+			c.Compat = "auto"
+
+			findings, err := c.checkSchemas(payload)
+			if (err != nil) != test.fails {
+				t.Fatalf("checkSchemas() error = %v, want failure %v", err, test.fails)
+			}
+			if len(findings) != test.findings {
+				t.Errorf("checkSchemas() = %v, want %d findings", findings, test.findings)
+			}
+		})
+	}
+}
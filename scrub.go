@@ -0,0 +1,171 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"github.com/urfave/cli"
+)
+
+// scrubRules describes how an export is anonymised.
+// Index-pattern and query rules rewrite the matching parts of titles and query
+// strings, filter rules replace the value of filters on matching field names.
+type scrubRules struct {
+	IndexPatterns []scrubRule `json:"indexPatterns"`
+	Queries       []scrubRule `json:"queries"`
+	Filters       []scrubRule `json:"filters"`
+}
+
+type scrubRule struct {
+	Match   string `json:"match"`
+	Replace string `json:"replace"`
+
+	re *regexp.Regexp
+}
+
+func loadScrubRules(file string) (*scrubRules, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read rules file")
+	}
+	var rules scrubRules
+	if err := json.Unmarshal(content, &rules); err != nil {
+		return nil, errors.Wrap(err, "could not parse rules file")
+	}
+	for _, list := range [][]scrubRule{rules.IndexPatterns, rules.Queries, rules.Filters} {
+		for i := range list {
+			list[i].re, err = regexp.Compile(list[i].Match)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid rule %v", list[i].Match)
+			}
+		}
+	}
+	return &rules, nil
+}
+
+func rewrite(rules []scrubRule, value string) string {
+	for _, rule := range rules {
+		value = rule.re.ReplaceAllString(value, rule.Replace)
+	}
+	return value
+}
+
+// escapePath escapes a json key so it can be used as a single gjson/sjson path component.
+func escapePath(key string) string {
+	for _, char := range []string{`\`, ".", "*", "?", "#", "|", "@"} {
+		key = strings.Replace(key, char, `\`+char, -1)
+	}
+	return key
+}
+
+func scrubExport(export []byte, rules *scrubRules) ([]byte, error) {
+	var err error
+	for i, object := range gjson.GetBytes(export, "objects").Array() {
+		prefix := fmt.Sprintf("objects.%d.attributes", i)
+
+		if object.Get("type").String() == "index-pattern" {
+			title := object.Get("attributes.title").String()
+			export, err = sjson.SetBytes(export, prefix+".title", rewrite(rules.IndexPatterns, title))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if visState := object.Get("attributes.visState"); visState.Exists() {
+			state := visState.String()
+			if index := gjson.Get(state, "params.index_pattern"); index.Type == gjson.String {
+				state, err = sjson.Set(state, "params.index_pattern", rewrite(rules.IndexPatterns, index.String()))
+				if err != nil {
+					return nil, err
+				}
+			}
+			export, err = sjson.SetBytes(export, prefix+".visState", state)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if source := object.Get("attributes.kibanaSavedObjectMeta.searchSourceJSON"); source.Exists() {
+			scrubbed, err := scrubSearchSource(source.String(), rules)
+			if err != nil {
+				return nil, err
+			}
+			export, err = sjson.SetBytes(export, prefix+".kibanaSavedObjectMeta.searchSourceJSON", scrubbed)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return export, nil
+}
+
+func scrubSearchSource(source string, rules *scrubRules) (string, error) {
+	var err error
+	if query := gjson.Get(source, "query.query"); query.Type == gjson.String {
+		source, err = sjson.Set(source, "query.query", rewrite(rules.Queries, query.String()))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	for i, filter := range gjson.Get(source, "filter").Array() {
+		field := filter.Get("meta.key").String()
+		for _, rule := range rules.Filters {
+			if !rule.re.MatchString(field) {
+				continue
+			}
+			prefix := fmt.Sprintf("filter.%d", i)
+			for _, path := range []string{"meta.value", "meta.params.query"} {
+				if filter.Get(path).Exists() {
+					source, err = sjson.Set(source, prefix+"."+path, rule.Replace)
+					if err != nil {
+						return "", err
+					}
+				}
+			}
+			path := "query.match_phrase." + escapePath(field)
+			if phrase := filter.Get(path); phrase.Exists() {
+				if phrase.IsObject() {
+					path += ".query"
+				}
+				source, err = sjson.Set(source, prefix+"."+path, rule.Replace)
+				if err != nil {
+					return "", err
+				}
+			}
+			break
+		}
+	}
+	return source, nil
+}
+
+func scrub(c *cli.Context) error {
+	file := c.Args().First()
+	if file == "" {
+		return cli.NewExitError("export file missing", 1)
+	}
+	if c.String("rules") == "" {
+		return cli.NewExitError("rules file missing", 1)
+	}
+	rules, err := loadScrubRules(c.String("rules"))
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	export, err := ioutil.ReadFile(file)
+	if err != nil {
+		return cli.NewExitError(errors.Wrap(err, "could not read export file"), 2)
+	}
+	scrubbed, err := scrubExport(export, rules)
+	if err != nil {
+		return cli.NewExitError(errors.Wrap(err, "could not scrub export"), 2)
+	}
+	os.Stdout.Write(scrubbed)
+	return nil
+}
@@ -0,0 +1,134 @@
+package kibctl
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+)
+
+// credentials are the secrets fetched from the credential source of a context.
+type credentials struct {
+	Username string
+	Password string
+	APIKey   string
+}
+
+// fetchCredentials retrieves the credentials from the source of a context,
+// vault://PATH reading the secret from vault and aws-sm://NAME from aws
+// secrets manager.
+func fetchCredentials(source string) (credentials, error) {
+	var secret []byte
+	var err error
+	switch {
+	case strings.HasPrefix(source, "vault://"):
+		secret, err = vaultSecret(strings.TrimPrefix(source, "vault://"))
+	case strings.HasPrefix(source, "aws-sm://"):
+		secret, err = awsSecret(strings.TrimPrefix(source, "aws-sm://"))
+	default:
+		return credentials{}, errors.Errorf("unsupported credential source %v, vault:// or aws-sm:// expected.\n", source)
+	}
+	if err != nil {
+		return credentials{}, err
+	}
+	return parseCredentials(secret)
+}
+
+// parseCredentials reads the username, password and apiKey fields of a json
+// secret, a plain text secret being the password.
+func parseCredentials(secret []byte) (credentials, error) {
+	secret = bytes.TrimSpace(secret)
+	if len(secret) == 0 {
+		return credentials{}, errors.New("empty secret.\n")
+	}
+	if !gjson.ValidBytes(secret) || !gjson.ParseBytes(secret).IsObject() {
+		return credentials{Password: string(secret)}, nil
+	}
+	fields := gjson.ParseBytes(secret)
+	creds := credentials{
+		Username: fields.Get("username").String(),
+		Password: fields.Get("password").String(),
+		APIKey:   fields.Get("apiKey").String(),
+	}
+	if creds.Password == "" && creds.APIKey == "" {
+		return credentials{}, errors.New("secret defines neither password nor apiKey.\n")
+	}
+	return creds, nil
+}
+
+// vaultSecret reads the secret at the path from the vault server of
+// VAULT_ADDR, authenticated by VAULT_TOKEN or the token left by vault login.
+// Both kv version 1 and 2 secrets are supported.
+func vaultSecret(path string) ([]byte, error) {
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return nil, errors.New("VAULT_ADDR not defined.\n")
+	}
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			content, _ := ioutil.ReadFile(filepath.Join(home, ".vault-token"))
+			token = strings.TrimSpace(string(content))
+		}
+	}
+	if token == "" {
+		return nil, errors.New("VAULT_TOKEN not defined and no ~/.vault-token found.\n")
+	}
+
+	req, err := http.NewRequest("GET", strings.TrimSuffix(addr, "/")+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not reach vault")
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to read vault secret %v. Status:%v %v.\n", path, resp.StatusCode, strings.Join(gjsonStrings(gjson.GetBytes(body, "errors")), ", "))
+	}
+	data := gjson.GetBytes(body, "data")
+	if nested := data.Get("data"); nested.IsObject() {
+		// kv version 2 nests the secret along with its metadata
+		data = nested
+	}
+	if !data.IsObject() {
+		return nil, errors.Errorf("vault secret %v holds no data.\n", path)
+	}
+	return []byte(data.Raw), nil
+}
+
+// awsSecret reads the string of the aws secrets manager secret through the
+// aws cli, which resolves the region and credentials from the environment.
+func awsSecret(name string) ([]byte, error) {
+	var out, stderr bytes.Buffer
+	cmd := exec.Command("aws", "secretsmanager", "get-secret-value", "--secret-id", name, "--query", "SecretString", "--output", "text")
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Errorf("failed to read aws secret %v: %v %v.\n", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return out.Bytes(), nil
+}
+
+func gjsonStrings(result gjson.Result) []string {
+	var values []string
+	for _, value := range result.Array() {
+		values = append(values, value.String())
+	}
+	return values
+}
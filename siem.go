@@ -0,0 +1,177 @@
+package kibctl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+const detectionRulesAPI = "/api/detection_engine/rules"
+
+// uploadNDJSON posts the ndjson content as the file of a multipart form, the
+// way the import apis expect it.
+func (c *client) uploadNDJSON(path, filename string, content []byte) ([]byte, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(content); err != nil {
+		return nil, err
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := c.newRequest("POST", path, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	resp, details, err := c.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.apiError(fmt.Sprintf("upload %v", filename), resp, details)
+	}
+	return details, nil
+}
+
+// ndjsonLines returns the json documents of ndjson content, skipping the
+// export details summary line.
+func ndjsonLines(content []byte) []gjson.Result {
+	var lines []gjson.Result
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		doc := gjson.Parse(string(line))
+		if doc.Get("exported_count").Exists() || doc.Get("exported_rules_count").Exists() || doc.Get("exported_exception_list_count").Exists() {
+			continue
+		}
+		lines = append(lines, doc)
+	}
+	return lines
+}
+
+// exportDetectionRules exports the detection rules with the given rule ids, all
+// of them when none is given, along with their exception lists and items.
+func (c *client) exportDetectionRules(ruleIDs []string) ([]byte, error) {
+	var payload interface{}
+	if len(ruleIDs) > 0 {
+		var objects []map[string]string
+		for _, id := range ruleIDs {
+			objects = append(objects, map[string]string{"rule_id": id})
+		}
+		payload = map[string]interface{}{"objects": objects}
+	}
+	return c.jsonRequest("POST", detectionRulesAPI+"/_export?exclude_export_details=true", payload)
+}
+
+// importResult is the summary returned by the ndjson import apis.
+type importResult struct {
+	Success      bool `json:"success"`
+	SuccessCount int  `json:"success_count"`
+	Errors       []struct {
+		ID     string `json:"id"`
+		RuleID string `json:"rule_id"`
+		ListID string `json:"list_id"`
+		Error  struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	} `json:"errors"`
+	ExceptionsSuccessCount int `json:"exceptions_success_count"`
+}
+
+func (r importResult) err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	var message bytes.Buffer
+	for _, e := range r.Errors {
+		id := e.RuleID + e.ListID
+		if id == "" {
+			id = e.ID
+		}
+		fmt.Fprintf(&message, "  %v: %v\n", id, e.Error.Message)
+	}
+	return errors.Errorf("%d object(s) failed to import:\n%v", len(r.Errors), message.String())
+}
+
+func (c *client) importDetectionRules(content []byte) (importResult, error) {
+	body, err := c.uploadNDJSON(detectionRulesAPI+"/_import?overwrite=true&overwrite_exceptions=true", "rules.ndjson", content)
+	if err != nil {
+		return importResult{}, err
+	}
+	var result importResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		return importResult{}, errors.Wrap(err, "could not parse import result")
+	}
+	return result, nil
+}
+
+func siemRuleExport(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	client := newClient()
+	export, err := client.exportDetectionRules(c.Args())
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if c.Bool("no-exceptions") {
+		export = stripExceptionLists(export)
+	} else {
+		export, err = client.linkExceptionLists(export)
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+	}
+	os.Stdout.Write(export)
+	return nil
+}
+
+func siemRuleImport(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	file := c.Args().First()
+	if file == "" {
+		return cli.NewExitError("rules ndjson file missing", 1)
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return cli.NewExitError(errors.Wrap(err, "could not read rules file"), 2)
+	}
+	result, err := newClient().importDetectionRules(content)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if err := result.err(); err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if quiet {
+		for _, line := range ndjsonLines(content) {
+			if id := line.Get("rule_id").String(); id != "" {
+				printIDs(id)
+			}
+		}
+		return nil
+	}
+	fmt.Fprintf(os.Stdout, "imported %d rule(s) and %d exception list object(s)\n", result.SuccessCount, result.ExceptionsSuccessCount)
+	return nil
+}
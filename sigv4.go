@@ -0,0 +1,202 @@
+package kibctl
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// sigv4Service is the service name the Amazon OpenSearch Service domains,
+// and their dashboards endpoint, are signed for.
+const sigv4Service = "es"
+
+type awsCredentials struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	SessionToken    string `json:"SessionToken"`
+}
+
+// loadAWSCredentials resolves the credentials of the environment variables,
+// or else of the aws cli which follows the rest of the credential chain:
+// profiles, sso, web identity and instance roles.
+func loadAWSCredentials() (*awsCredentials, error) {
+	if id := os.Getenv("AWS_ACCESS_KEY_ID"); id != "" {
+		return &awsCredentials{
+			AccessKeyID:     id,
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+	var out, stderr bytes.Buffer
+	cmd := exec.Command("aws", "configure", "export-credentials", "--format", "process")
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Errorf("could not resolve aws credentials: %v %v.\n", err, strings.TrimSpace(stderr.String()))
+	}
+	var creds awsCredentials
+	if err := json.Unmarshal(out.Bytes(), &creds); err != nil {
+		return nil, errors.Wrap(err, "could not parse aws credentials")
+	}
+	if creds.AccessKeyID == "" {
+		return nil, errors.New("no aws credentials found.\n")
+	}
+	return &creds, nil
+}
+
+// signRequest signs the request with aws signature version 4 for the region,
+// the credentials being resolved once per client.
+func (c *client) signRequest(req *http.Request) error {
+	if err := c.loadAWSCredentials(); err != nil {
+		return err
+	}
+	var payload []byte
+	if req.Body != nil {
+		var err error
+		if payload, err = ioutil.ReadAll(req.Body); err != nil {
+			return err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(payload))
+	}
+	sigv4Sign(req, payload, c.awsCredentials, c.AWSRegion, sigv4Service, time.Now().UTC())
+	return nil
+}
+
+// loadAWSCredentials resolves the ambient aws credentials of the client once.
+func (c *client) loadAWSCredentials() error {
+	if c.awsCredentials != nil {
+		return nil
+	}
+	creds, err := loadAWSCredentials()
+	if err != nil {
+		return err
+	}
+	c.awsCredentials = creds
+	return nil
+}
+
+// signingIdentity identifies the credentials the requests are signed with,
+// empty unless the client signs them.
+func (c *client) signingIdentity() (string, error) {
+	if c.Auth != "sigv4" {
+		return "", nil
+	}
+	if err := c.loadAWSCredentials(); err != nil {
+		return "", err
+	}
+	return "sigv4 " + c.awsCredentials.AccessKeyID + " " + c.AWSRegion, nil
+}
+
+func sigv4Sign(req *http.Request, payload []byte, creds *awsCredentials, region, service string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	payloadHash := sha256Hex(payload)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for _, name := range []string{"Content-Type", "X-Amz-Date", "X-Amz-Content-Sha256", "X-Amz-Security-Token"} {
+		if value := req.Header.Get(name); value != "" {
+			headers[strings.ToLower(name)] = strings.TrimSpace(value)
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsEscape(req.URL.EscapedPath(), false),
+		canonicalQuery(req),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := strings.Join([]string{now.Format("20060102"), region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{now.Format("20060102"), region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%v/%v, SignedHeaders=%v, Signature=%v",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalQuery sorts the query parameters by name and value, escaped the
+// way aws expects them.
+func canonicalQuery(req *http.Request) string {
+	var params [][2]string
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			params = append(params, [2]string{awsEscape(name, true), awsEscape(value, true)})
+		}
+	}
+	sort.Slice(params, func(i, j int) bool {
+		if params[i][0] != params[j][0] {
+			return params[i][0] < params[j][0]
+		}
+		return params[i][1] < params[j][1]
+	})
+	pairs := make([]string, len(params))
+	for i, param := range params {
+		pairs[i] = param[0] + "=" + param[1]
+	}
+	return strings.Join(pairs, "&")
+}
+
+// awsEscape percent-encodes everything but the unreserved characters, and the
+// slashes unless asked to.
+func awsEscape(s string, encodeSlash bool) string {
+	var escaped strings.Builder
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '_', b == '.', b == '~':
+			escaped.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			escaped.WriteByte(b)
+		default:
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
+
+func sha256Hex(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, content string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(content))
+	return mac.Sum(nil)
+}
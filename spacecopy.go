@@ -0,0 +1,210 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+// copyResult is the outcome of the copy of an object to another space.
+type copyResult struct {
+	Type          string `json:"type"`
+	ID            string `json:"id"`
+	DestinationID string `json:"destinationId"`
+}
+
+// copyError is an object the copy could not write to the target space.
+type copyError struct {
+	Type  string `json:"type"`
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Error struct {
+		// Type is conflict, ambiguous_conflict, missing_references or unsupported_type
+		Type          string `json:"type"`
+		DestinationID string `json:"destinationId"`
+	} `json:"error"`
+}
+
+type copyStatus struct {
+	Success        bool         `json:"success"`
+	SuccessCount   int          `json:"successCount"`
+	SuccessResults []copyResult `json:"successResults"`
+	Errors         []copyError  `json:"errors"`
+}
+
+type copyOptions struct {
+	IncludeReferences bool
+	CreateNewCopies   bool
+	Overwrite         bool
+}
+
+// copyObjects copies the objects of the client space to the target space,
+// along with the objects they refer to when included. The objects conflicting
+// with existing ones are reported as errors, to be overwritten by a retry.
+func (c *client) copyObjects(target string, objects []dependency, options copyOptions) (copyStatus, error) {
+	body := map[string]interface{}{
+		"spaces":            []string{target},
+		"objects":           copyObjectList(objects),
+		"includeReferences": options.IncludeReferences,
+		"overwrite":         false,
+		"createNewCopies":   options.CreateNewCopies,
+	}
+	return c.copyRequest("/api/spaces/_copy_saved_objects", target, body)
+}
+
+// retryCopy copies again the objects which conflicted with existing ones in
+// the target space, overwriting them. The objects conflicting with several
+// objects of the target space cannot be resolved and are left as errors.
+func (c *client) retryCopy(target string, objects []dependency, status copyStatus, options copyOptions) (copyStatus, error) {
+	var retries []map[string]interface{}
+	var unresolved []copyError
+	for _, e := range status.Errors {
+		switch e.Error.Type {
+		case "conflict":
+			retry := map[string]interface{}{"type": e.Type, "id": e.ID, "overwrite": true}
+			if e.Error.DestinationID != "" {
+				retry["destinationId"] = e.Error.DestinationID
+			}
+			retries = append(retries, retry)
+		default:
+			unresolved = append(unresolved, e)
+		}
+	}
+	if len(retries) == 0 {
+		return status, nil
+	}
+	c.Logger.Printf("overwriting %d conflicting object(s) in space %v\n", len(retries), target)
+	retried, err := c.copyRequest("/api/spaces/_resolve_copy_saved_objects_errors", target, map[string]interface{}{
+		"objects":           copyObjectList(objects),
+		"includeReferences": options.IncludeReferences,
+		"createNewCopies":   options.CreateNewCopies,
+		"retries":           map[string]interface{}{target: retries},
+	})
+	if err != nil {
+		return status, err
+	}
+	retried.SuccessResults = append(status.SuccessResults, retried.SuccessResults...)
+	retried.Errors = append(retried.Errors, unresolved...)
+	retried.Success = len(retried.Errors) == 0
+	return retried, nil
+}
+
+func copyObjectList(objects []dependency) []map[string]string {
+	list := make([]map[string]string, len(objects))
+	for i, o := range objects {
+		list[i] = map[string]string{"type": o.Type, "id": o.ID}
+	}
+	return list
+}
+
+func (c *client) copyRequest(path, target string, body interface{}) (copyStatus, error) {
+	if c.Flavor == "opensearch" {
+		return copyStatus{}, errors.Errorf("spaces are not supported with opensearch dashboards.\n")
+	}
+	response, err := c.jsonRequest("POST", path, body)
+	if err != nil {
+		return copyStatus{}, err
+	}
+	var result map[string]copyStatus
+	if err := json.Unmarshal(response, &result); err != nil {
+		return copyStatus{}, errors.Wrap(err, "could not parse the copy results")
+	}
+	status, ok := result[target]
+	if !ok {
+		return copyStatus{}, errors.Errorf("no copy result for space %v.\n", target)
+	}
+	return status, nil
+}
+
+// copyTargets resolves the TYPE:NAME arguments to the objects to copy, the
+// dashboards being looked up by name and the other objects by id.
+func (c *client) copyTargets(args []string) ([]dependency, error) {
+	var objects []dependency
+	for _, arg := range args {
+		parts := strings.SplitN(arg, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errors.Errorf("invalid object %v, TYPE:NAME expected.\n", arg)
+		}
+		if parts[0] != "dashboard" {
+			objects = append(objects, dependency{Type: parts[0], ID: parts[1]})
+			continue
+		}
+		found, err := c.findDashboard(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, dependency{Type: "dashboard", ID: found.ID})
+	}
+	return objects, nil
+}
+
+func spaceCopy(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	target := c.String("to")
+	if target == "" {
+		return cli.NewExitError("--to missing", 1)
+	}
+	if !c.Args().Present() {
+		return cli.NewExitError("objects to copy missing, e.g. dashboard:NAME", 1)
+	}
+	options := copyOptions{
+		IncludeReferences: c.BoolT("include-references"),
+		CreateNewCopies:   c.Bool("create-new-copies"),
+		Overwrite:         c.Bool("overwrite"),
+	}
+	if options.CreateNewCopies && options.Overwrite {
+		return cli.NewExitError("--overwrite and --create-new-copies are mutually exclusive", 1)
+	}
+
+	client := newClient()
+	if from := c.String("from"); from != "" {
+		client = client.inSpace(from)
+	}
+	objects, err := client.copyTargets(c.Args())
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	status, err := client.copyObjects(target, objects, options)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if options.Overwrite && !status.Success {
+		if status, err = client.retryCopy(target, objects, status, options); err != nil {
+			return cli.NewExitError(err, 2)
+		}
+	}
+
+	if !quiet {
+		os.Stdout.WriteString(stdout.header(fmt.Sprintf("%-15v %-40v %-40v %v", "TYPE", "ID", "DESTINATION", "STATUS")) + "\n")
+	}
+	for _, r := range status.SuccessResults {
+		destination := r.DestinationID
+		if destination == "" {
+			destination = r.ID
+		}
+		if quiet {
+			printIDs(destination)
+			continue
+		}
+		fmt.Fprintf(os.Stdout, "%-15v %-40v %v %v\n", r.Type, r.ID, stdout.paint(cyan, fmt.Sprintf("%-40v", destination)), stdout.paint(green, "copied"))
+	}
+	for _, e := range status.Errors {
+		fmt.Fprintf(os.Stderr, "%-15v %-40v %-40v %v\n", e.Type, e.ID, "", stdout.paint(red, strings.Replace(e.Error.Type, "_", " ", -1)))
+	}
+	if len(status.Errors) > 0 {
+		hint := ""
+		for _, e := range status.Errors {
+			if e.Error.Type == "conflict" {
+				hint = ", use --overwrite or --create-new-copies to resolve the conflicts"
+			}
+		}
+		return cli.NewExitError(fmt.Sprintf("%d object(s) not copied to space %v%v", len(status.Errors), target, hint), 2)
+	}
+	return nil
+}
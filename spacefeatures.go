@@ -0,0 +1,110 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"github.com/urfave/cli"
+)
+
+// kibanaFeatures returns the ids of the features of kibana, the ones the
+// spaces can disable.
+func (c *client) kibanaFeatures() ([]string, error) {
+	body, err := c.jsonRequest("GET", "/api/features", nil)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, id := range gjson.GetBytes(body, "#.id").Array() {
+		ids = append(ids, id.String())
+	}
+	return ids, nil
+}
+
+// setDisabledFeatures sets the disabled features of the space, keeping its
+// other attributes, and reports whether they changed.
+func (c *client) setDisabledFeatures(id string, features []string) (bool, error) {
+	path := "/api/spaces/space/" + url.PathEscape(id)
+	current, err := c.jsonRequest("GET", path, nil)
+	if err != nil {
+		return false, err
+	}
+	var disabled []string
+	for _, feature := range gjson.GetBytes(current, "disabledFeatures").Array() {
+		disabled = append(disabled, feature.String())
+	}
+	sort.Strings(disabled)
+	if strings.Join(disabled, ",") == strings.Join(features, ",") {
+		return false, nil
+	}
+	updated, err := sjson.SetBytes(current, "disabledFeatures", features)
+	if err != nil {
+		return false, err
+	}
+	_, err = c.jsonRequest("PUT", path, json.RawMessage(updated))
+	return err == nil, err
+}
+
+func spaceFeaturesSet(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	id := c.Args().First()
+	if id == "" && !c.Bool("all-spaces") {
+		return cli.NewExitError("space id or --all-spaces missing", 1)
+	}
+	if !c.IsSet("disable") {
+		return cli.NewExitError("--disable missing, empty to enable every feature", 1)
+	}
+	features := []string{}
+	for _, feature := range strings.Split(c.String("disable"), ",") {
+		if feature = strings.TrimSpace(feature); feature != "" && !contains(features, feature) {
+			features = append(features, feature)
+		}
+	}
+	sort.Strings(features)
+
+	client := newClient()
+	known, err := client.kibanaFeatures()
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	for _, feature := range features {
+		if !contains(known, feature) {
+			return cli.NewExitError(fmt.Sprintf("unknown feature %v, one of %v expected", feature, strings.Join(known, ", ")), 1)
+		}
+	}
+	spaces := []string{id}
+	if c.Bool("all-spaces") {
+		all, err := client.listSpaces()
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		spaces = nil
+		for _, s := range all {
+			spaces = append(spaces, s.ID)
+		}
+	}
+	for _, space := range spaces {
+		changed, err := client.setDisabledFeatures(space, features)
+		if err != nil {
+			return cli.NewExitError(errors.Wrapf(err, "could not set the features of space %v", space), 2)
+		}
+		if quiet {
+			continue
+		}
+		status := "unchanged"
+		if changed {
+			status = stdout.paint(green, "updated")
+		}
+		fmt.Fprintf(os.Stdout, "%v %v\n", stdout.paint(cyan, fmt.Sprintf("%-30v", space)), status)
+	}
+	return nil
+}
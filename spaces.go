@@ -0,0 +1,66 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+type kibanaSpace struct {
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	Description      string   `json:"description,omitempty"`
+	Color            string   `json:"color,omitempty"`
+	Initials         string   `json:"initials,omitempty"`
+	DisabledFeatures []string `json:"disabledFeatures"`
+}
+
+// inSpace returns a copy of the client targeting the given space.
+func (c *client) inSpace(id string) *client {
+	scoped := *c
+	scoped.Space = id
+	return &scoped
+}
+
+func (c *client) listSpaces() ([]kibanaSpace, error) {
+	req, err := c.newRequest("GET", "/api/spaces/space", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, body, err := c.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.apiError("list spaces", resp, body)
+	}
+
+	var spaces []kibanaSpace
+	if err := json.Unmarshal(body, &spaces); err != nil {
+		return nil, errors.Wrap(err, "could not parse spaces")
+	}
+	return spaces, nil
+}
+
+// createSpace creates the space, failing when it already exists.
+func (c *client) createSpace(space kibanaSpace) error {
+	if c.Flavor == "opensearch" {
+		return errors.Errorf("spaces are not supported with opensearch dashboards.\n")
+	}
+	if space.DisabledFeatures == nil {
+		space.DisabledFeatures = []string{}
+	}
+	_, err := c.jsonRequest("POST", "/api/spaces/space", space)
+	if apiErr, ok := err.(*apiError); ok && apiErr.StatusCode == http.StatusConflict {
+		return errors.Errorf("space %v already exists.\n", space.ID)
+	}
+	return err
+}
+
+// deleteSpace deletes the space along with all its saved objects.
+func (c *client) deleteSpace(id string) error {
+	_, err := c.jsonRequest("DELETE", "/api/spaces/space/"+url.PathEscape(id), nil)
+	return err
+}
@@ -0,0 +1,269 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"github.com/urfave/cli"
+)
+
+// panelGroup is a group of panels split out of a dashboard.
+type panelGroup struct {
+	Name   string
+	Panels []map[string]interface{}
+}
+
+// groupPanels groups the panels, in reading order, by the key of each panel,
+// the groups keeping the order of their first panel.
+func groupPanels(panels []map[string]interface{}, key func(panel map[string]interface{}) string) []*panelGroup {
+	sort.SliceStable(panels, func(i, j int) bool {
+		rowI, colI := panelPosition(panels[i])
+		rowJ, colJ := panelPosition(panels[j])
+		if rowI != rowJ {
+			return rowI < rowJ
+		}
+		return colI < colJ
+	})
+	var groups []*panelGroup
+	byName := make(map[string]*panelGroup)
+	for _, panel := range panels {
+		name := key(panel)
+		group, ok := byName[name]
+		if !ok {
+			group = &panelGroup{Name: name}
+			byName[name] = group
+			groups = append(groups, group)
+		}
+		group.Panels = append(group.Panels, panel)
+	}
+	return groups
+}
+
+// rowKey returns a key function grouping the panels, given in reading order,
+// by horizontal band: a band ends where no panel of it extends further down.
+func rowKey() func(panel map[string]interface{}) string {
+	row, bottom := 0, 0.0
+	return func(panel map[string]interface{}) string {
+		y, _ := panelPosition(panel)
+		if row == 0 || y >= bottom {
+			row++
+		}
+		if grid, ok := panel["gridData"].(map[string]interface{}); ok && y+number(grid["h"]) > bottom {
+			bottom = y + number(grid["h"])
+		}
+		return fmt.Sprintf("row %d", row)
+	}
+}
+
+// panelObject returns the type and id of the saved object the panel displays.
+func panelObject(panel map[string]interface{}, refs map[string]gjson.Result) (string, string) {
+	if ref, ok := refs[fmt.Sprint(panel["panelRefName"])]; ok {
+		return ref.Get("type").String(), ref.Get("id").String()
+	}
+	objectType, _ := panel["type"].(string)
+	id, _ := panel["id"].(string)
+	return objectType, id
+}
+
+// splitDashboard splits the dashboard of the export into a dashboard per group
+// of panels, titled after the group. The dashboard itself becomes an index of
+// markdown links to the parts, so that the links to it keep working.
+func splitDashboard(export []byte, dashboard gjson.Result, groups []*panelGroup) ([]byte, error) {
+	id, title := dashboard.Get("id").String(), dashboard.Get("attributes.title").String()
+	panelRefs := make(map[string]bool)
+	refs := make(map[string]gjson.Result)
+	for _, ref := range dashboard.Get("references").Array() {
+		refs[ref.Get("name").String()] = ref
+	}
+	for _, group := range groups {
+		for _, panel := range group.Panels {
+			panelRefs[fmt.Sprint(panel["panelRefName"])] = true
+		}
+	}
+	// the data views of the filters and controls apply to every part
+	var shared []gjson.Result
+	for _, ref := range dashboard.Get("references").Array() {
+		if !panelRefs[ref.Get("name").String()] {
+			shared = append(shared, ref)
+		}
+	}
+
+	var objects []string
+	for _, object := range gjson.GetBytes(export, "objects").Array() {
+		if object.Get("type").String() != "dashboard" || object.Get("id").String() != id {
+			objects = append(objects, object.Raw)
+		}
+	}
+	withPanels := func(partID, partTitle string, panels []map[string]interface{}, references []gjson.Result) error {
+		panelsJSON, err := encodeJSON(panels)
+		if err != nil {
+			return err
+		}
+		raw := "[]"
+		for _, ref := range references {
+			if raw, err = sjson.SetRaw(raw, "-1", ref.Raw); err != nil {
+				return err
+			}
+		}
+		part := dashboard.Raw
+		for _, update := range []struct {
+			path  string
+			value interface{}
+		}{
+			{"id", partID},
+			{"attributes.title", partTitle},
+			{"attributes.panelsJSON", panelsJSON},
+		} {
+			if part, err = sjson.Set(part, update.path, update.value); err != nil {
+				return err
+			}
+		}
+		if part, err = sjson.SetRaw(part, "references", raw); err != nil {
+			return err
+		}
+		if part, err = sjson.Delete(part, "version"); err != nil {
+			return err
+		}
+		objects = append(objects, part)
+		return nil
+	}
+
+	var links []string
+	for i, group := range groups {
+		partID := fmt.Sprintf("%v-part-%d", id, i+1)
+		partTitle := fmt.Sprintf("%v - %v", title, group.Name)
+		top := -1.0
+		for _, panel := range group.Panels {
+			if y, _ := panelPosition(panel); top < 0 || y < top {
+				top = y
+			}
+		}
+		references := append([]gjson.Result{}, shared...)
+		for _, panel := range group.Panels {
+			if grid, ok := panel["gridData"].(map[string]interface{}); ok {
+				grid["y"] = int(number(grid["y"]) - top)
+			}
+			if ref, ok := refs[fmt.Sprint(panel["panelRefName"])]; ok {
+				references = append(references, ref)
+			}
+		}
+		if err := withPanels(partID, partTitle, group.Panels, references); err != nil {
+			return nil, err
+		}
+		// relative to the dashboards app, the links stay in the current space
+		links = append(links, fmt.Sprintf("- [%v](#/view/%v)", group.Name, partID))
+	}
+
+	indexID := id + "-index"
+	index, err := markdownVisualization(indexID, title+" index", "## "+title+"\n\n"+strings.Join(links, "\n"))
+	if err != nil {
+		return nil, err
+	}
+	objects = append(objects, index)
+	indexPanel := []map[string]interface{}{{
+		"panelIndex":       "1",
+		"gridData":         map[string]interface{}{"x": 0, "y": 0, "w": gridColumns, "h": 4 + len(links), "i": "1"},
+		"embeddableConfig": map[string]interface{}{"hidePanelTitles": true},
+		"panelRefName":     "panel_0",
+		"version":          "7.10.0",
+	}}
+	indexRef := gjson.Parse(fmt.Sprintf(`{"name":"panel_0","type":"visualization","id":%q}`, indexID))
+	if err := withPanels(id, title, indexPanel, append(shared, indexRef)); err != nil {
+		return nil, err
+	}
+
+	result := []byte(`{"objects":[]}`)
+	for _, object := range objects {
+		if result, err = sjson.SetRawBytes(result, "objects.-1", []byte(object)); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func splitDashboardCommand(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	name := c.Args().First()
+	if name == "" {
+		return cli.NewExitError("dashboard name missing", 1)
+	}
+	by := c.String("by")
+	if by != "tag" && by != "row" && by != "panel-title-prefix" {
+		return cli.NewExitError(fmt.Sprintf("unsupported --by %v, tag, row or panel-title-prefix expected", by), 1)
+	}
+
+	client := newClient()
+	found, err := client.findDashboard(name)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	export, err := client.exportDashboards(found.ID)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	dashboard := gjson.GetBytes(export, fmt.Sprintf(`objects.#(id==%q)`, found.ID))
+	if !dashboard.Exists() {
+		return cli.NewExitError(fmt.Sprintf("dashboard %v missing from its export", found.ID), 2)
+	}
+	var panels []map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(dashboard.Get("attributes.panelsJSON").String()))
+	decoder.UseNumber()
+	if err := decoder.Decode(&panels); err != nil {
+		return cli.NewExitError(errors.Wrapf(err, "could not parse the panels of dashboard %v", found.ID), 2)
+	}
+	refs := make(map[string]gjson.Result)
+	for _, ref := range dashboard.Get("references").Array() {
+		refs[ref.Get("name").String()] = ref
+	}
+	objects := exportObjects(export)
+
+	var key func(panel map[string]interface{}) string
+	switch by {
+	case "row":
+		key = rowKey()
+	case "tag":
+		tags, err := client.find([]string{"tag"}, nil)
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		tagNames := make(map[string]string)
+		for _, tag := range tags {
+			tagNames[tag.ID] = gjson.GetBytes(tag.Attributes, "name").String()
+		}
+		key = func(panel map[string]interface{}) string {
+			objectType, id := panelObject(panel, refs)
+			for _, tag := range objects[objectType+":"+id].Get(`references.#(type=="tag")#.id`).Array() {
+				if name, ok := tagNames[tag.String()]; ok {
+					return name
+				}
+			}
+			return "untagged"
+		}
+	case "panel-title-prefix":
+		labels := panelLabels(dashboard, objects)
+		separator := c.String("separator")
+		key = func(panel map[string]interface{}) string {
+			label := labels[fmt.Sprint(panel["panelIndex"])]
+			if i := strings.Index(label, separator); i > 0 {
+				return strings.TrimSpace(label[:i])
+			}
+			return "other"
+		}
+	}
+	groups := groupPanels(panels, key)
+	if len(groups) < 2 {
+		return cli.NewExitError(fmt.Sprintf("the panels of dashboard %v make a single group by %v, nothing to split", found.ID, by), 2)
+	}
+	split, err := splitDashboard(export, dashboard, groups)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	return writeExport(c, split)
+}
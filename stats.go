@@ -0,0 +1,116 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/urfave/cli"
+)
+
+type objectStat struct {
+	spaceObject
+	Size int
+}
+
+// objectStats sizes the objects by their json encoding, which is close to
+// what they take in the kibana index.
+func objectStats(objects []spaceObject) []objectStat {
+	stats := make([]objectStat, len(objects))
+	for i, o := range objects {
+		encoded, _ := json.Marshal(o.savedObject)
+		stats[i] = objectStat{spaceObject: o, Size: len(encoded)}
+	}
+	return stats
+}
+
+// countBy returns the object counts by key along with the sorted keys.
+func countBy(stats []objectStat, key func(objectStat) string) (map[string]int, []string) {
+	counts := make(map[string]int)
+	for _, s := range stats {
+		counts[key(s)]++
+	}
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return counts, keys
+}
+
+func writeStatObjects(title string, stats []objectStat, value func(objectStat) string) {
+	os.Stdout.WriteString("\n" + stdout.header(fmt.Sprintf("%-25v %-15v %-20v %-40v %v", title, "TYPE", "SPACE", "ID", "TITLE")) + "\n")
+	for _, s := range stats {
+		os.Stdout.WriteString(fmt.Sprintf("%-25v %-15v %-20v %-40v %v\n", value(s), s.Type, s.Space, s.ID, s.title()))
+	}
+}
+
+func stats(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	top := c.Int("top")
+	if top <= 0 {
+		return cli.NewExitError("--top must be positive", 1)
+	}
+	client := newClient()
+	spaces, err := client.listSpaces()
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	var objects []spaceObject
+	for _, s := range spaces {
+		client.Logger.Printf("scanning space %v\n", s.ID)
+		found, err := client.inSpace(s.ID).findObjects(savedObjectTypes, "")
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		for _, o := range found {
+			objects = append(objects, spaceObject{Space: s.ID, savedObject: o})
+		}
+	}
+	sized := objectStats(objects)
+
+	if quiet {
+		os.Stdout.WriteString(fmt.Sprintf("%d\n", len(sized)))
+		return nil
+	}
+	total := 0
+	for _, s := range sized {
+		total += s.Size
+	}
+	os.Stdout.WriteString(fmt.Sprintf("%d objects in %d spaces, %.1f MB\n", len(sized), len(spaces), float64(total)/(1<<20)))
+
+	for _, group := range []struct {
+		title string
+		key   func(objectStat) string
+	}{
+		{"TYPE", func(s objectStat) string { return s.Type }},
+		{"SPACE", func(s objectStat) string { return s.Space }},
+	} {
+		counts, keys := countBy(sized, group.key)
+		os.Stdout.WriteString("\n" + stdout.header(fmt.Sprintf("%-40v %v", group.title, "COUNT")) + "\n")
+		for _, key := range keys {
+			os.Stdout.WriteString(fmt.Sprintf("%-40v %d\n", key, counts[key]))
+		}
+	}
+
+	sort.SliceStable(sized, func(i, j int) bool { return sized[i].Size > sized[j].Size })
+	writeStatObjects("LARGEST", sized[:minInt(top, len(sized))], func(s objectStat) string {
+		return fmt.Sprintf("%.1f KB", float64(s.Size)/(1<<10))
+	})
+	// the updated_at dates are RFC 3339 timestamps in UTC, sorting as strings
+	sort.SliceStable(sized, func(i, j int) bool { return sized[i].UpdatedAt > sized[j].UpdatedAt })
+	writeStatObjects("UPDATED", sized[:minInt(top, len(sized))], func(s objectStat) string {
+		return s.UpdatedAt
+	})
+	return nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,148 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// placeholder matches the {{ .name }} placeholders of a payload template. The
+// mustache placeholders of kibana, e.g. {{context.reason}} in rule actions or
+// {{#context.alerts}} in url drilldowns, have no leading dot and are left as is.
+var placeholder = regexp.MustCompile(`\{\{\s*\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// loadValues reads the json values file a payload template is rendered with.
+func loadValues(file string) (map[string]interface{}, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read values file")
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(content, &values); err != nil {
+		return nil, errors.Wrap(err, "could not parse values file")
+	}
+	return values, nil
+}
+
+// renderTemplate expands the {{ .name }} placeholders of the payload with the
+// values, failing on placeholders without value so that nothing half rendered
+// gets imported. The values are json escaped: placeholders inside a string are
+// replaced by the text of the value, including in the json documents kibana
+// stores as strings, and placeholders outside of strings by the json value.
+func renderTemplate(payload []byte, values map[string]interface{}) ([]byte, error) {
+	missing := make(map[string]bool)
+	rendered, err := renderJSON(string(payload), values, missing)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not render template")
+	}
+	if len(missing) > 0 {
+		var names []string
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, errors.Errorf("could not render template, no value for %v.\n", strings.Join(names, ", "))
+	}
+	return []byte(rendered), nil
+}
+
+// renderJSON renders the placeholders of the json text, string literals
+// included.
+func renderJSON(text string, values map[string]interface{}, missing map[string]bool) (string, error) {
+	var out strings.Builder
+	for i := 0; i < len(text); {
+		start := strings.IndexByte(text[i:], '"')
+		if start < 0 {
+			outside, err := renderValues(text[i:], values, missing)
+			out.WriteString(outside)
+			return out.String(), err
+		}
+		start += i
+		outside, err := renderValues(text[i:start], values, missing)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(outside)
+		end := stringEnd(text, start)
+		literal, err := renderString(text[start:end], values, missing)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(literal)
+		i = end
+	}
+	return out.String(), nil
+}
+
+// stringEnd returns the end of the json string literal starting at start.
+func stringEnd(text string, start int) int {
+	for i := start + 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(text)
+}
+
+// renderString renders the placeholders of the json string literal, left
+// untouched when it has none.
+func renderString(literal string, values map[string]interface{}, missing map[string]bool) (string, error) {
+	if !placeholder.MatchString(literal) {
+		return literal, nil
+	}
+	var text string
+	if err := json.Unmarshal([]byte(literal), &text); err != nil {
+		return literal, nil
+	}
+	var rendered string
+	var err error
+	if trimmed := strings.TrimSpace(text); (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(text)) {
+		rendered, err = renderJSON(text, values, missing)
+	} else {
+		rendered = placeholder.ReplaceAllStringFunc(text, func(match string) string {
+			name := placeholder.FindStringSubmatch(match)[1]
+			value, ok := values[name]
+			if !ok {
+				missing[name] = true
+				return match
+			}
+			if s, ok := value.(string); ok {
+				return s
+			}
+			encoded, _ := encodeJSON(value)
+			return strings.TrimSpace(encoded)
+		})
+	}
+	if err != nil {
+		return "", err
+	}
+	encoded, err := encodeJSON(rendered)
+	return strings.TrimSpace(encoded), err
+}
+
+// renderValues replaces the placeholders outside of string literals with the
+// json encoding of their value.
+func renderValues(text string, values map[string]interface{}, missing map[string]bool) (string, error) {
+	var err error
+	rendered := placeholder.ReplaceAllStringFunc(text, func(match string) string {
+		name := placeholder.FindStringSubmatch(match)[1]
+		value, ok := values[name]
+		if !ok {
+			missing[name] = true
+			return match
+		}
+		encoded, encodeErr := encodeJSON(value)
+		if encodeErr != nil {
+			err = errors.Wrapf(encodeErr, "could not encode value %v", name)
+		}
+		return strings.TrimSpace(encoded)
+	})
+	return rendered, err
+}
@@ -0,0 +1,75 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	values := map[string]interface{}{
+		"index":     "logs-*",
+		"title":     `Team "A" <ops>`,
+		"threshold": 42.0,
+	}
+	tests := []struct {
+		name    string
+		payload string
+		want    string
+	}{
+		{
+			name:    "string value",
+			payload: `{"title":"{{ .index }} overview"}`,
+			want:    `{"title":"logs-* overview"}`,
+		},
+		{
+			name:    "escaped value",
+			payload: `{"title":"{{.title}}"}`,
+			want:    `{"title":"Team \"A\" <ops>"}`,
+		},
+		{
+			name:    "value outside of strings",
+			payload: `{"threshold":{{ .threshold }},"index":{{ .index }}}`,
+			want:    `{"threshold":42,"index":"logs-*"}`,
+		},
+		{
+			name:    "json attribute",
+			payload: `{"visState":"{\"title\":\"{{ .title }}\"}"}`,
+			want:    `{"visState":"{\"title\":\"Team \\\"A\\\" <ops>\"}"}`,
+		},
+		{
+			name:    "kibana mustache left as is",
+			payload: `{"message":"{{context.reason}} {{#context.alerts}}{{_id}}{{/context.alerts}} on {{ .index }}"}`,
+			want:    `{"message":"{{context.reason}} {{#context.alerts}}{{_id}}{{/context.alerts}} on logs-*"}`,
+		},
+		{
+			name:    "no placeholder",
+			payload: "{\"title\":\"caf\\u00e9\"}\n",
+			want:    "{\"title\":\"caf\\u00e9\"}\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := renderTemplate([]byte(test.payload), values)
+			if err != nil {
+				t.Fatalf("renderTemplate() error: %v", err)
+			}
+			if string(got) != test.want {
+				t.Errorf("renderTemplate() = %s, want %s", got, test.want)
+			}
+			if !json.Valid(got) {
+				t.Errorf("renderTemplate() rendered invalid json %s", got)
+			}
+		})
+	}
+}
+
+func TestRenderTemplateMissingValue(t *testing.T) {
+	_, err := renderTemplate([]byte(`{"a":"{{ .missing }}","b":{{ .other }}}`), map[string]interface{}{})
+	if err == nil {
+		t.Fatal("renderTemplate() rendered placeholders without value")
+	}
+	if !strings.Contains(err.Error(), "missing, other") {
+		t.Errorf("renderTemplate() error %q does not name the missing values", err)
+	}
+}
@@ -0,0 +1,145 @@
+package kibctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+type timeline struct {
+	SavedObjectID string `json:"savedObjectId"`
+	Title         string `json:"title"`
+	TimelineType  string `json:"timelineType"`
+	Status        string `json:"status"`
+}
+
+// listTimelines returns the timelines of the type, default or template.
+func (c *client) listTimelines(timelineType string) ([]timeline, error) {
+	var timelines []timeline
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("timeline_type", timelineType)
+		query.Set("page_size", "100")
+		query.Set("page_index", fmt.Sprint(page))
+		body, err := c.jsonRequest("GET", "/api/timelines?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			TotalCount int        `json:"totalCount"`
+			Timeline   []timeline `json:"timeline"`
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, errors.Wrap(err, "could not parse timelines")
+		}
+		timelines = append(timelines, result.Timeline...)
+		if len(result.Timeline) == 0 || len(timelines) >= result.TotalCount {
+			return timelines, nil
+		}
+	}
+}
+
+// exportTimelines exports the timelines with the saved object ids as ndjson.
+func (c *client) exportTimelines(ids []string) ([]byte, error) {
+	return c.jsonRequest("POST", "/api/timeline/_export?file_name=timelines.ndjson", map[string]interface{}{"ids": ids})
+}
+
+func (c *client) importTimelines(content []byte) (importResult, error) {
+	body, err := c.uploadNDJSON("/api/timeline/_import", "timelines.ndjson", content)
+	if err != nil {
+		return importResult{}, err
+	}
+	var result importResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		return importResult{}, errors.Wrap(err, "could not parse import result")
+	}
+	return result, nil
+}
+
+func timelineType(c *cli.Context) string {
+	if c.Bool("templates") {
+		return "template"
+	}
+	return "default"
+}
+
+func timelineList(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	timelines, err := newClient().listTimelines(timelineType(c))
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if quiet {
+		for _, t := range timelines {
+			printIDs(t.SavedObjectID)
+		}
+		return nil
+	}
+	os.Stdout.WriteString(stdout.header(fmt.Sprintf("%-40v %-10v %v", "ID", "STATUS", "TITLE")) + "\n")
+	for _, t := range timelines {
+		fmt.Fprintf(os.Stdout, "%v %-10v %v\n", stdout.paint(cyan, fmt.Sprintf("%-40v", t.SavedObjectID)), t.Status, t.Title)
+	}
+	return nil
+}
+
+func timelineExport(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	client := newClient()
+	ids := []string(c.Args())
+	if len(ids) == 0 {
+		timelines, err := client.listTimelines(timelineType(c))
+		if err != nil {
+			return cli.NewExitError(err, 2)
+		}
+		for _, t := range timelines {
+			ids = append(ids, t.SavedObjectID)
+		}
+		if len(ids) == 0 {
+			return cli.NewExitError("no timeline to export", 2)
+		}
+	}
+	export, err := client.exportTimelines(ids)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	os.Stdout.Write(export)
+	return nil
+}
+
+func timelineImport(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	file := c.Args().First()
+	if file == "" {
+		return cli.NewExitError("timelines ndjson file missing", 1)
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return cli.NewExitError(errors.Wrap(err, "could not read timelines file"), 2)
+	}
+	result, err := newClient().importTimelines(content)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if err := result.err(); err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	if quiet {
+		for _, line := range ndjsonLines(content) {
+			printIDs(line.Get("savedObjectId").String())
+		}
+		return nil
+	}
+	fmt.Fprintf(os.Stdout, "imported %d timeline(s)\n", result.SuccessCount)
+	return nil
+}
@@ -0,0 +1,139 @@
+package kibctl
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// timeOverrides replaces the time range and refresh interval restored by the
+// dashboards, empty values leave the stored settings untouched.
+type timeOverrides struct {
+	From            string
+	To              string
+	RefreshInterval *time.Duration
+}
+
+func (o timeOverrides) empty() bool {
+	return o.From == "" && o.To == "" && o.RefreshInterval == nil
+}
+
+func applyTimeOverrides(payload []byte, overrides timeOverrides) ([]byte, error) {
+	if overrides.empty() {
+		return payload, nil
+	}
+	values := make(map[string]interface{})
+	values["timeRestore"] = true
+	if overrides.From != "" {
+		values["timeFrom"] = overrides.From
+	}
+	if overrides.To != "" {
+		values["timeTo"] = overrides.To
+	}
+	if overrides.RefreshInterval != nil {
+		interval := *overrides.RefreshInterval
+		values["refreshInterval"] = map[string]interface{}{
+			"pause": interval == 0,
+			"value": int64(interval / time.Millisecond),
+		}
+	}
+
+	var err error
+	for i, object := range gjson.GetBytes(payload, "objects").Array() {
+		if object.Get("type").String() != "dashboard" {
+			continue
+		}
+		for key, value := range values {
+			payload, err = sjson.SetBytes(payload, fmt.Sprintf("objects.%d.attributes.%v", i, key), value)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return payload, nil
+}
+
+// applyOverlay merges the environment overlay, a json merge patch, into every
+// dashboard of the payload.
+func applyOverlay(payload []byte, file string) ([]byte, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read overlay file")
+	}
+	overlay, err := decodeJSON(string(content))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse overlay file")
+	}
+
+	for i, object := range gjson.GetBytes(payload, "objects").Array() {
+		if object.Get("type").String() != "dashboard" {
+			continue
+		}
+		decoded, err := decodeJSON(object.Raw)
+		if err != nil {
+			return nil, err
+		}
+		patched, err := encodeJSON(mergePatch(decoded, overlay))
+		if err != nil {
+			return nil, err
+		}
+		payload, err = sjson.SetRawBytes(payload, fmt.Sprintf("objects.%d", i), []byte(patched))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return payload, nil
+}
+
+// filterObjects removes the objects of the payload for which keep returns false.
+func filterObjects(payload []byte, keep func(object gjson.Result) bool) ([]byte, error) {
+	var removed []int
+	for i, object := range gjson.GetBytes(payload, "objects").Array() {
+		if !keep(object) {
+			removed = append(removed, i)
+		}
+	}
+	var err error
+	// delete from the end so the remaining indexes stay valid
+	for j := len(removed) - 1; j >= 0; j-- {
+		payload, err = sjson.DeleteBytes(payload, fmt.Sprintf("objects.%d", removed[j]))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return payload, nil
+}
+
+// skipTypes removes the objects of the given types from the payload, calling
+// onSkip for each of them.
+func skipTypes(payload []byte, types []string, onSkip func(object gjson.Result)) ([]byte, error) {
+	skipped := make(map[string]struct{})
+	for _, t := range types {
+		skipped[strings.TrimSpace(t)] = struct{}{}
+	}
+	return filterObjects(payload, func(object gjson.Result) bool {
+		_, skip := skipped[object.Get("type").String()]
+		if skip {
+			onSkip(object)
+		}
+		return !skip
+	})
+}
+
+// markManaged flags every object of the payload as managed, which kibana
+// shows as read-only in the UI.
+func markManaged(payload []byte) ([]byte, error) {
+	var err error
+	for i := range gjson.GetBytes(payload, "objects").Array() {
+		payload, err = sjson.SetBytes(payload, fmt.Sprintf("objects.%d.managed", i), true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return payload, nil
+}
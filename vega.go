@@ -0,0 +1,214 @@
+package kibctl
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+)
+
+const vegaSpecPath = "params.spec"
+
+func isVega(visState string) bool {
+	return gjson.Get(visState, "type").String() == "vega"
+}
+
+var vegaSchema = regexp.MustCompile(`(^|[{,\s])["']?\$schema["']?\s*:`)
+
+// validateVegaSpec checks the syntax of a Vega or Vega-Lite spec, written
+// either as json or as the hjson relaxed syntax accepted by Kibana.
+func validateVegaSpec(spec string) error {
+	p := &hjsonParser{src: []rune(spec)}
+	p.skip()
+	if p.peek() != '{' {
+		return errors.New("spec must be an object")
+	}
+	if err := p.value(); err != nil {
+		return err
+	}
+	p.skip()
+	if !p.eof() {
+		return p.errorf("unexpected content after the spec")
+	}
+	if !vegaSchema.MatchString(spec) {
+		return errors.New(`spec has no "$schema" field`)
+	}
+	return nil
+}
+
+// hjsonParser is a validating only parser of the hjson syntax.
+type hjsonParser struct {
+	src []rune
+	pos int
+}
+
+func (p *hjsonParser) eof() bool { return p.pos >= len(p.src) }
+
+func (p *hjsonParser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *hjsonParser) errorf(format string, v ...interface{}) error {
+	line := 1 + strings.Count(string(p.src[:p.pos]), "\n")
+	return errors.Errorf("line %d: "+format, append([]interface{}{line}, v...)...)
+}
+
+func (p *hjsonParser) rest() string {
+	return string(p.src[p.pos:])
+}
+
+// skip moves past whitespaces and comments.
+func (p *hjsonParser) skip() {
+	for !p.eof() {
+		rest := p.rest()
+		switch {
+		case strings.ContainsRune(" \t\r\n", p.peek()):
+			p.pos++
+		case strings.HasPrefix(rest, "#") || strings.HasPrefix(rest, "//"):
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				p.pos = len(p.src)
+				return
+			}
+			p.pos += 2 + len([]rune(rest[2:2+end])) + 2
+		default:
+			return
+		}
+	}
+}
+
+func (p *hjsonParser) value() error {
+	p.skip()
+	switch p.peek() {
+	case 0:
+		return p.errorf("unexpected end of spec")
+	case '{':
+		return p.object()
+	case '[':
+		return p.array()
+	case '"', '\'':
+		return p.quoted()
+	case '}', ']', ',', ':':
+		return p.errorf("unexpected %q", p.peek())
+	}
+	return p.quoteless()
+}
+
+var hjsonLiteral = regexp.MustCompile(`^(true|false|null|-?\d+(\.\d+)?([eE][+-]?\d+)?)[ \t]*([,\]}]|#|//|/\*|\r?\n|$)`)
+
+// quoteless consumes a literal, or a quoteless string which runs to the end of the line.
+func (p *hjsonParser) quoteless() error {
+	if match := hjsonLiteral.FindStringSubmatch(p.rest()); match != nil {
+		p.pos += len([]rune(match[1]))
+		return nil
+	}
+	for !p.eof() && p.peek() != '\n' {
+		p.pos++
+	}
+	return nil
+}
+
+func (p *hjsonParser) quoted() error {
+	if strings.HasPrefix(p.rest(), "'''") {
+		end := strings.Index(p.rest()[3:], "'''")
+		if end < 0 {
+			return p.errorf("unterminated multiline string")
+		}
+		p.pos += 3 + len([]rune(p.rest()[3:3+end])) + 3
+		return nil
+	}
+	quote := p.peek()
+	start := p.pos
+	for p.pos++; !p.eof() && p.peek() != quote; p.pos++ {
+		if p.peek() == '\n' {
+			p.pos = start
+			return p.errorf("unterminated string")
+		}
+		if p.peek() == '\\' {
+			p.pos++
+		}
+	}
+	if p.eof() {
+		p.pos = start
+		return p.errorf("unterminated string")
+	}
+	p.pos++
+	return nil
+}
+
+func (p *hjsonParser) object() error {
+	p.pos++
+	for {
+		p.skip()
+		switch p.peek() {
+		case 0:
+			return p.errorf("unclosed object")
+		case '}':
+			p.pos++
+			return nil
+		case ',':
+			p.pos++
+			continue
+		case '"', '\'':
+			if err := p.quoted(); err != nil {
+				return err
+			}
+		default:
+			start := p.pos
+			for !p.eof() && !strings.ContainsRune(":,{}[] \t\r\n", p.peek()) {
+				p.pos++
+			}
+			if p.pos == start {
+				return p.errorf("unexpected %q", p.peek())
+			}
+		}
+		p.skip()
+		if p.peek() != ':' {
+			return p.errorf("expected ':' after key")
+		}
+		p.pos++
+		if err := p.value(); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *hjsonParser) array() error {
+	p.pos++
+	for {
+		p.skip()
+		switch p.peek() {
+		case 0:
+			return p.errorf("unclosed array")
+		case ']':
+			p.pos++
+			return nil
+		case ',':
+			p.pos++
+			continue
+		}
+		if err := p.value(); err != nil {
+			return err
+		}
+	}
+}
+
+// lintVega validates the specs of vega visualisations.
+func lintVega(object gjson.Result) []lintFinding {
+	visState := object.Get("attributes.visState").String()
+	if object.Get("type").String() != "visualization" || !isVega(visState) {
+		return nil
+	}
+	if err := validateVegaSpec(gjson.Get(visState, vegaSpecPath).String()); err != nil {
+		return []lintFinding{newFinding(object, severityError, "vega-spec", "%v", err)}
+	}
+	return nil
+}
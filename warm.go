@@ -0,0 +1,200 @@
+package kibctl
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/urfave/cli"
+)
+
+// warmSearch is the search priming the caches of a data view of a dashboard,
+// aggregating every field the panels use over the time range. Skipped are the
+// fields the data view marks as not aggregatable, e.g. the text fields.
+type warmSearch struct {
+	DataView  string
+	Index     string
+	TimeField string
+	Aggs      map[string]interface{}
+	Skipped   []string
+}
+
+// nonAggregatable returns the fields the data view saved as not aggregatable.
+// The data views of kibana 8 save no field list, their fields being read
+// from the cluster.
+func nonAggregatable(dataView gjson.Result) map[string]bool {
+	fields := make(map[string]bool)
+	for _, field := range gjson.Parse(dataView.Get("attributes.fields").String()).Array() {
+		if aggregatable := field.Get("aggregatable"); aggregatable.Exists() && !aggregatable.Bool() {
+			fields[field.Get("name").String()] = true
+		}
+	}
+	return fields
+}
+
+// useAgg returns an aggregation of the field suiting the operation using it.
+func useAgg(use fieldUse) map[string]interface{} {
+	switch {
+	case contains(dateOperations, use.Operation):
+		return map[string]interface{}{"auto_date_histogram": map[string]interface{}{"field": use.Field, "buckets": 50}}
+	case contains(numberOperations, use.Operation):
+		return map[string]interface{}{"stats": map[string]interface{}{"field": use.Field}}
+	}
+	return map[string]interface{}{"terms": map[string]interface{}{"field": use.Field, "size": 10}}
+}
+
+// warmSearches builds a search per data view used by the objects of the
+// dashboard export.
+func warmSearches(export []byte) []*warmSearch {
+	dataViews := make(map[string]gjson.Result)
+	for _, object := range gjson.GetBytes(export, `objects.#(type=="index-pattern")#`).Array() {
+		dataViews[object.Get("id").String()] = object
+	}
+	searches := make(map[string]*warmSearch)
+	skip := make(map[string]map[string]bool)
+	search := func(id string) *warmSearch {
+		if s, ok := searches[id]; ok {
+			return s
+		}
+		dataView, ok := dataViews[id]
+		if !ok {
+			return nil
+		}
+		s := &warmSearch{
+			DataView:  id,
+			Index:     dataView.Get("attributes.title").String(),
+			TimeField: dataView.Get("attributes.timeFieldName").String(),
+			Aggs:      make(map[string]interface{}),
+		}
+		searches[id] = s
+		skip[id] = nonAggregatable(dataView)
+		return s
+	}
+
+	for _, object := range gjson.GetBytes(export, "objects").Array() {
+		if object.Get("type").String() == "index-pattern" {
+			continue
+		}
+		for _, ref := range object.Get(`references.#(type=="index-pattern")#.id`).Array() {
+			search(ref.String())
+		}
+		for _, use := range fieldUses(object) {
+			s := search(use.DataView)
+			if s == nil {
+				continue
+			}
+			if skip[use.DataView][use.Field] {
+				if !contains(s.Skipped, use.Field) {
+					s.Skipped = append(s.Skipped, use.Field)
+				}
+				continue
+			}
+			s.Aggs[fmt.Sprintf("%v-%d", use.Field, len(s.Aggs))] = useAgg(use)
+		}
+	}
+
+	var result []*warmSearch
+	for _, s := range searches {
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Index < result[j].Index })
+	return result
+}
+
+// searchBody returns the body of the search of the index over the time range
+// up to now, with the aggregations.
+func searchBody(timeField, from string, aggs map[string]interface{}) map[string]interface{} {
+	query := map[string]interface{}{"match_all": map[string]interface{}{}}
+	if timeField != "" {
+		query = map[string]interface{}{"range": map[string]interface{}{
+			timeField: map[string]string{"gte": from, "lte": "now"},
+		}}
+	}
+	body := map[string]interface{}{"size": 0, "track_total_hits": true, "query": query}
+	if len(aggs) > 0 {
+		body["aggs"] = aggs
+	}
+	return body
+}
+
+// search executes the search body through the search api of kibana, so that
+// it hits the same elasticsearch caches as the dashboards, and returns the
+// raw elasticsearch response.
+func (c *client) search(index string, body map[string]interface{}) (gjson.Result, error) {
+	strategy := "es"
+	if c.Flavor == "opensearch" {
+		strategy = "opensearch"
+	}
+	response, err := c.jsonRequest("POST", "/internal/search/"+strategy, map[string]interface{}{
+		"params": map[string]interface{}{"index": index, "body": body},
+	})
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	return gjson.GetBytes(response, "rawResponse"), nil
+}
+
+// runWarmSearch executes the search and returns the number of hits.
+func (c *client) runWarmSearch(s *warmSearch, from string) (int64, error) {
+	response, err := c.search(s.Index, searchBody(s.TimeField, from, s.Aggs))
+	if err != nil {
+		return 0, err
+	}
+	total := response.Get("hits.total")
+	if total.IsObject() {
+		total = total.Get("value")
+	}
+	return total.Int(), nil
+}
+
+func warmDashboard(c *cli.Context) error {
+	if err := checkGlobals(c); err != nil {
+		return err
+	}
+	name := c.Args().First()
+	if name == "" {
+		return cli.NewExitError("dashboard name missing", 1)
+	}
+	from := c.String("time-range")
+	if !strings.HasPrefix(from, "now-") {
+		return cli.NewExitError(fmt.Sprintf("invalid --time-range %v, e.g. now-1h expected", from), 1)
+	}
+
+	client := newClient()
+	export, err := client.export(name)
+	if err != nil {
+		return cli.NewExitError(err, 2)
+	}
+	searches := warmSearches(export)
+	if len(searches) == 0 {
+		return cli.NewExitError(fmt.Sprintf("dashboard %v uses no data view", name), 2)
+	}
+	if !quiet {
+		os.Stdout.WriteString(stdout.header(fmt.Sprintf("%-40v %-10v %-12v %v", "DATA VIEW", "FIELDS", "HITS", "DURATION")) + "\n")
+	}
+	// a failed search leaves the caches of the other data views to warm
+	failed := 0
+	for _, s := range searches {
+		if len(s.Skipped) > 0 {
+			client.Logger.Printf("skipped the fields of %v which are not aggregatable: %v\n", s.Index, strings.Join(s.Skipped, ", "))
+		}
+		start := time.Now()
+		hits, err := client.runWarmSearch(s, from)
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "error: could not search data view %v: %v\n", s.Index, strings.TrimSpace(err.Error()))
+			continue
+		}
+		if !quiet {
+			fmt.Fprintf(os.Stdout, "%v %-10v %-12v %v\n", stdout.paint(cyan, fmt.Sprintf("%-40v", s.Index)), len(s.Aggs), hits, time.Since(start).Round(time.Millisecond))
+		}
+	}
+	if failed > 0 {
+		return cli.NewExitError(errors.Errorf("%d of %d data view search(es) failed", failed, len(searches)), 2)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package kibctl
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestWarmSearches(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  string
+		aggs    []string
+		skipped []string
+	}{
+		{
+			name:   "no field list",
+			fields: `[]`,
+			aggs:   []string{"bytes-1", "host-0", "message-2"},
+		},
+		{
+			name:    "text field",
+			fields:  `[{"name":"host","type":"string","aggregatable":true},{"name":"bytes","type":"number","aggregatable":true},{"name":"message","type":"string","aggregatable":false}]`,
+			aggs:    []string{"bytes-1", "host-0"},
+			skipped: []string{"message"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fields := strings.Replace(test.fields, `"`, `\"`, -1)
+			export := []byte(`{"objects":[
+				{"type":"index-pattern","id":"logs","attributes":{"title":"logs-*","timeFieldName":"@timestamp","fields":"` + fields + `"}},
+				{"type":"visualization","id":"v1","attributes":{"visState":"{\"aggs\":[{\"type\":\"terms\",\"params\":{\"field\":\"host\"}},{\"type\":\"avg\",\"params\":{\"field\":\"bytes\"}}]}"},"references":[{"name":"kibanaSavedObjectMeta.searchSourceJSON.index","type":"index-pattern","id":"logs"}]},
+				{"type":"search","id":"s1","attributes":{"columns":["message"]},"references":[{"name":"kibanaSavedObjectMeta.searchSourceJSON.index","type":"index-pattern","id":"logs"}]}
+			]}`)
+			searches := warmSearches(export)
+			if len(searches) != 1 {
+				t.Fatalf("warmSearches() returned %d searches, want 1", len(searches))
+			}
+			var aggs []string
+			for name := range searches[0].Aggs {
+				aggs = append(aggs, name)
+			}
+			sort.Strings(aggs)
+			if strings.Join(aggs, ",") != strings.Join(test.aggs, ",") {
+				t.Errorf("aggregations = %v, want %v", aggs, test.aggs)
+			}
+			if strings.Join(searches[0].Skipped, ",") != strings.Join(test.skipped, ",") {
+				t.Errorf("skipped = %v, want %v", searches[0].Skipped, test.skipped)
+			}
+		})
+	}
+}